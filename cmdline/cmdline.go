@@ -0,0 +1,102 @@
+// Package cmdline parses Windows command lines, as reported for running
+// processes, without calling into Windows so the parsing can be tested on
+// any platform.
+package cmdline
+
+import (
+	"strings"
+)
+
+// Split splits a command line into arguments following the rules of the
+// Microsoft C runtime, which CommandLineToArgvW implements as well:
+//   - the program name ends at the first whitespace, unless quoted, and
+//     backslashes aren't special in it;
+//   - 2n backslashes followed by a quote produce n backslashes and the quote
+//     begins or ends a quoted part;
+//   - 2n+1 backslashes followed by a quote produce n backslashes and a
+//     literal quote;
+//   - two quotes within a quoted part produce a literal quote;
+//   - other backslashes are literal.
+func Split(cmdline string) []string {
+	var args []string
+
+	// The program name.
+	rest := strings.TrimLeft(cmdline, " \t")
+	if rest == "" {
+		return nil
+	}
+	if rest[0] == '"' {
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return []string{rest[1:]}
+		}
+		args = append(args, rest[1:end+1])
+		rest = rest[end+2:]
+	} else {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			return []string{rest}
+		}
+		args = append(args, rest[:end])
+		rest = rest[end:]
+	}
+
+	var arg strings.Builder
+	// inArg is set once the current argument started, so that an empty
+	// quoted argument is kept.
+	inArg, quoted := false, false
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		switch {
+		case c == '\\':
+			backslashes := 1
+			for i+backslashes < len(rest) && rest[i+backslashes] == '\\' {
+				backslashes++
+			}
+			i += backslashes - 1
+			if i+1 < len(rest) && rest[i+1] == '"' {
+				arg.WriteString(strings.Repeat(`\`, backslashes/2))
+				if backslashes%2 == 1 {
+					arg.WriteByte('"')
+					i++
+				}
+			} else {
+				arg.WriteString(strings.Repeat(`\`, backslashes))
+			}
+			inArg = true
+		case c == '"':
+			if quoted && i+1 < len(rest) && rest[i+1] == '"' {
+				arg.WriteByte('"')
+				i++
+			} else {
+				quoted = !quoted
+			}
+			inArg = true
+		case (c == ' ' || c == '\t') && !quoted:
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args
+}
+
+// Value returns the argument following the first occurrence of flag, which
+// is matched case-insensitively, e.g. the application pool of an IIS worker
+// process given its arguments and "-ap".
+func Value(args []string, flag string) (string, bool) {
+	for i, arg := range args {
+		if strings.EqualFold(arg, flag) && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,69 @@
+package cmdline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		cmdline  string
+		expected []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{`notepad.exe`, []string{"notepad.exe"}},
+		{`C:\Windows\notepad.exe a.txt`, []string{`C:\Windows\notepad.exe`, "a.txt"}},
+		{`"C:\Program Files\app.exe" --flag`, []string{`C:\Program Files\app.exe`, "--flag"}},
+		{`"C:\Program Files\app.exe"`, []string{`C:\Program Files\app.exe`}},
+		// Backslashes in the program name are literal.
+		{`"C:\dir\\"app.exe x`, []string{`C:\dir\\`, `app.exe`, "x"}},
+		{"app.exe  a\t\tb ", []string{"app.exe", "a", "b"}},
+		{`app.exe "a b" c`, []string{"app.exe", "a b", "c"}},
+		{`app.exe a"b c"d`, []string{"app.exe", "ab cd"}},
+		{`app.exe "" x`, []string{"app.exe", "", "x"}},
+		{`app.exe \\server\share`, []string{"app.exe", `\\server\share`}},
+		{`app.exe a\\\"b`, []string{"app.exe", `a\"b`}},
+		{`app.exe "a\\" b`, []string{"app.exe", `a\`, "b"}},
+		{`app.exe \"a\"`, []string{"app.exe", `"a"`}},
+		{`app.exe "a""b"`, []string{"app.exe", `a"b`}},
+		{`app.exe "unterminated arg`, []string{"app.exe", "unterminated arg"}},
+		{
+			`c:\windows\system32\inetsrv\w3wp.exe -ap "DefaultAppPool" -v "v4.0" -l "webengine4.dll" -a \\.\pipe\iisipm4c1f1c4c -h "C:\inetpub\temp\apppools\DefaultAppPool\DefaultAppPool.config" -w "" -m 0 -t 20 -ta 0`,
+			[]string{
+				`c:\windows\system32\inetsrv\w3wp.exe`,
+				"-ap", "DefaultAppPool",
+				"-v", "v4.0",
+				"-l", "webengine4.dll",
+				"-a", `\\.\pipe\iisipm4c1f1c4c`,
+				"-h", `C:\inetpub\temp\apppools\DefaultAppPool\DefaultAppPool.config`,
+				"-w", "",
+				"-m", "0",
+				"-t", "20",
+				"-ta", "0",
+			},
+		},
+	}
+	for _, tc := range cases {
+		if args := Split(tc.cmdline); !reflect.DeepEqual(args, tc.expected) {
+			t.Errorf("%s: expected %q, got %q", tc.cmdline, tc.expected, args)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	args := Split(`w3wp.exe -ap "My Pool" -v "v4.0" -w`)
+	if pool, ok := Value(args, "-ap"); !ok || pool != "My Pool" {
+		t.Errorf("expected pool %q, got %q (%v)", "My Pool", pool, ok)
+	}
+	if pool, ok := Value(args, "-AP"); !ok || pool != "My Pool" {
+		t.Errorf("expected flags to be matched case-insensitively, got %q (%v)", pool, ok)
+	}
+	// The flag is the last argument.
+	if _, ok := Value(args, "-w"); ok {
+		t.Error("expected no value for a trailing flag")
+	}
+	if _, ok := Value(args, "-h"); ok {
+		t.Error("expected no value for a missing flag")
+	}
+}
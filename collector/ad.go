@@ -8,11 +8,12 @@ import (
 
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A ADCollector is a Prometheus collector for WMI Win32_PerfRawData_DirectoryServices_DirectoryServices metrics
 type ADCollector struct {
+	logger log.Logger
+
 	AddressBookOperationsTotal                          *prometheus.Desc
 	AddressBookClientSessions                           *prometheus.Desc
 	ApproximateHighestDistinguishedNameTag              *prometheus.Desc
@@ -80,6 +81,7 @@ type ADCollector struct {
 func newADCollector() (Collector, error) {
 	const subsystem = "ad"
 	return &ADCollector{
+		logger: log.With(log.CollectorField, "ad"),
 		AddressBookOperationsTotal: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "address_book_operations_total"),
 			"",
@@ -452,8 +454,8 @@ func newADCollector() (Collector, error) {
 // Collect sends the metric values for each metric
 // to the provided prometheus Metric channel.
 func (c *ADCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
-	if desc, err := c.collect(ch); err != nil {
-		log.Error("failed collecting ad metrics:", desc, err)
+	if desc, err := c.collect(ctx, ch); err != nil {
+		c.logger.Error("failed collecting ad metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -611,10 +613,10 @@ type Win32_PerfRawData_DirectoryServices_DirectoryServices struct {
 	TransitivesuboperationsPersec                                    uint32
 }
 
-func (c *ADCollector) collect(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+func (c *ADCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_DirectoryServices_DirectoryServices
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := ctx.wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
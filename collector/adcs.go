@@ -11,6 +11,8 @@ import (
 )
 
 type adcsCollector struct {
+	logger log.Logger
+
 	RequestsPerSecond                            *prometheus.Desc
 	RequestProcessingTime                        *prometheus.Desc
 	RetrievalsPerSecond                          *prometheus.Desc
@@ -30,6 +32,7 @@ type adcsCollector struct {
 func adcsCollectorMethod() (Collector, error) {
 	const subsystem = "adcs"
 	return &adcsCollector{
+		logger: log.With(log.CollectorField, "adcs"),
 		RequestsPerSecond: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "requests_total"),
 			"Total certificate requests processed",
@@ -113,7 +116,7 @@ func adcsCollectorMethod() (Collector, error) {
 
 func (c *adcsCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collectADCSCounters(ctx, ch); err != nil {
-		log.Error("Failed collecting ADCS Metrics:", desc, err)
+		c.logger.Error("Failed collecting ADCS Metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -141,7 +144,7 @@ func (c *adcsCollector) collectADCSCounters(ctx *ScrapeContext, ch chan<- promet
 	if _, ok := ctx.perfObjects["Certification Authority"]; !ok {
 		return nil, errors.New("Perflib did not contain an entry for Certification Authority")
 	}
-	err := unmarshalObject(ctx.perfObjects["Certification Authority"], &dst)
+	err := unmarshalObject(ctx.perfObjects["Certification Authority"], &dst, c.logger)
 	if err != nil {
 		return nil, err
 	}
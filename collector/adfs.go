@@ -4,11 +4,14 @@
 package collector
 
 import (
+	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"math"
 )
 
 type adfsCollector struct {
+	logger log.Logger
+
 	adLoginConnectionFailures                          *prometheus.Desc
 	certificateAuthentications                         *prometheus.Desc
 	deviceAuthentications                              *prometheus.Desc
@@ -59,6 +62,7 @@ func newADFSCollector() (Collector, error) {
 	const subsystem = "adfs"
 
 	return &adfsCollector{
+		logger: log.With(log.CollectorField, "adfs"),
 		adLoginConnectionFailures: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "ad_login_connection_failures_total"),
 			"Total number of connection failures to an Active Directory domain controller",
@@ -368,7 +372,7 @@ type perflibADFS struct {
 
 func (c *adfsCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	var adfsData []perflibADFS
-	err := unmarshalObject(ctx.perfObjects["AD FS"], &adfsData)
+	err := unmarshalObject(ctx.perfObjects["AD FS"], &adfsData, c.logger)
 	if err != nil {
 		return err
 	}
@@ -10,6 +10,8 @@ import (
 
 // A CacheCollector is a Prometheus collector for Perflib Cache metrics
 type CacheCollector struct {
+	logger log.Logger
+
 	AsyncCopyReadsTotal         *prometheus.Desc
 	AsyncDataMapsTotal          *prometheus.Desc
 	AsyncFastReadsTotal         *prometheus.Desc
@@ -45,6 +47,7 @@ type CacheCollector struct {
 func newCacheCollector() (Collector, error) {
 	const subsystem = "cache"
 	return &CacheCollector{
+		logger: log.With(log.CollectorField, "cache"),
 		AsyncCopyReadsTotal: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "async_copy_reads_total"),
 			"(AsyncCopyReadsTotal)",
@@ -225,7 +228,7 @@ func newCacheCollector() (Collector, error) {
 // Collect implements the Collector interface
 func (c *CacheCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collect(ctx, ch); err != nil {
-		log.Error("failed collecting cache metrics:", desc, err)
+		c.logger.Error("failed collecting cache metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -267,7 +270,7 @@ type perflibCache struct {
 
 func (c *CacheCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []perflibCache // Single-instance class, array is required but will have single entry.
-	if err := unmarshalObject(ctx.perfObjects["Cache"], &dst); err != nil {
+	if err := unmarshalObject(ctx.perfObjects["Cache"], &dst, c.logger); err != nil {
 		return nil, err
 	}
 
@@ -1,6 +1,7 @@
 package collector
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strconv"
@@ -103,8 +104,40 @@ type Collector interface {
 	Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (err error)
 }
 
+// A Closer is a collector holding resources, such as background goroutines,
+// to release once it is no longer used.
+type Closer interface {
+	Close() error
+}
+
+// Close closes the collectors implementing Closer.
+func Close(collectors map[string]Collector) {
+	for name, c := range collectors {
+		if closer, ok := c.(Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.With(log.CollectorField, name).WithError(err).Warn("Couldn't close collector")
+			}
+		}
+	}
+}
+
 type ScrapeContext struct {
 	perfObjects map[string]*perflib.PerfObject
+	// wmi runs the WMI queries of the scrape, either on the local machine or on target.
+	wmi *wmiClient
+	// target is the remote host being probed, empty for the local machine.
+	target string
+	// ctx is cancelled once the scrape is abandoned, nil if it never is.
+	ctx context.Context
+}
+
+// context returns the context of the scrape, cancelled once the scrape is
+// abandoned.
+func (ctx *ScrapeContext) context() context.Context {
+	if ctx == nil || ctx.ctx == nil {
+		return context.Background()
+	}
+	return ctx.ctx
 }
 
 // PrepareScrapeContext creates a ScrapeContext to be used during a single scrape
@@ -115,7 +148,7 @@ func PrepareScrapeContext(collectors []string) (*ScrapeContext, error) {
 		return nil, err
 	}
 
-	return &ScrapeContext{objs}, nil
+	return &ScrapeContext{perfObjects: objs, wmi: defaultWMIClient}, nil
 }
 func boolToFloat(b bool) float64 {
 	if b {
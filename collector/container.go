@@ -11,6 +11,8 @@ import (
 
 // A ContainerMetricsCollector is a Prometheus collector for containers metrics
 type ContainerMetricsCollector struct {
+	logger log.Logger
+
 	// Presence
 	ContainerAvailable *prometheus.Desc
 
@@ -45,6 +47,7 @@ type ContainerMetricsCollector struct {
 func newContainerMetricsCollector() (Collector, error) {
 	const subsystem = "container"
 	return &ContainerMetricsCollector{
+		logger: log.With(log.CollectorField, "container"),
 		ContainerAvailable: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "available"),
 			"Available",
@@ -160,17 +163,17 @@ func newContainerMetricsCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *ContainerMetricsCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collect(ch); err != nil {
-		log.Error("failed collecting ContainerMetricsCollector metrics:", desc, err)
+		c.logger.Error("failed collecting ContainerMetricsCollector metrics:", desc, err)
 		return err
 	}
 	return nil
 }
 
 // containerClose closes the container resource
-func containerClose(c hcsshim.Container) {
+func containerClose(c hcsshim.Container, logger log.Logger) {
 	err := c.Close()
 	if err != nil {
-		log.Error(err)
+		logger.Error(err)
 	}
 }
 
@@ -179,7 +182,7 @@ func (c *ContainerMetricsCollector) collect(ch chan<- prometheus.Metric) (*prome
 	// Types Container is passed to get the containers compute systems only
 	containers, err := hcsshim.GetContainers(hcsshim.ComputeSystemQuery{Types: []string{"Container"}})
 	if err != nil {
-		log.Error("Err in Getting containers:", err)
+		c.logger.Error("Err in Getting containers:", err)
 		return nil, err
 	}
 
@@ -197,16 +200,16 @@ func (c *ContainerMetricsCollector) collect(ch chan<- prometheus.Metric) (*prome
 	for _, containerDetails := range containers {
 		container, err := hcsshim.OpenContainer(containerDetails.ID)
 		if container != nil {
-			defer containerClose(container)
+			defer containerClose(container, c.logger)
 		}
 		if err != nil {
-			log.Error("err in opening container: ", containerDetails.ID, err)
+			c.logger.Error("err in opening container: ", containerDetails.ID, err)
 			continue
 		}
 
 		cstats, err := container.Statistics()
 		if err != nil {
-			log.Error("err in fetching container Statistics: ", containerDetails.ID, err)
+			c.logger.Error("err in fetching container Statistics: ", containerDetails.ID, err)
 			continue
 		}
 		containerIdWithPrefix := getContainerIdWithPrefix(containerDetails)
@@ -255,7 +258,7 @@ func (c *ContainerMetricsCollector) collect(ch chan<- prometheus.Metric) (*prome
 		)
 
 		if len(cstats.Network) == 0 {
-			log.Info("No Network Stats for container: ", containerDetails.ID)
+			c.logger.Info("No Network Stats for container: ", containerDetails.ID)
 			continue
 		}
 
@@ -6,16 +6,21 @@ package collector
 import (
 	"strings"
 
+	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 type cpuCollectorBasic struct {
+	logger log.Logger
+
 	CStateSecondsTotal *prometheus.Desc
 	TimeTotal          *prometheus.Desc
 	InterruptsTotal    *prometheus.Desc
 	DPCsTotal          *prometheus.Desc
 }
 type cpuCollectorFull struct {
+	logger log.Logger
+
 	CStateSecondsTotal       *prometheus.Desc
 	TimeTotal                *prometheus.Desc
 	InterruptsTotal          *prometheus.Desc
@@ -45,6 +50,7 @@ func newCPUCollector() (Collector, error) {
 	// Value 6.05 was selected to split between Windows versions.
 	if version < 6.05 {
 		return &cpuCollectorBasic{
+			logger: log.With(log.CollectorField, "cpu"),
 			CStateSecondsTotal: prometheus.NewDesc(
 				prometheus.BuildFQName(Namespace, subsystem, "cstate_seconds_total"),
 				"Time spent in low-power idle state",
@@ -73,6 +79,7 @@ func newCPUCollector() (Collector, error) {
 	}
 
 	return &cpuCollectorFull{
+		logger: log.With(log.CollectorField, "cpu"),
 		CStateSecondsTotal: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "cstate_seconds_total"),
 			"Time spent in low-power idle state",
@@ -175,7 +182,7 @@ type perflibProcessor struct {
 
 func (c *cpuCollectorBasic) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	data := make([]perflibProcessor, 0)
-	err := unmarshalObject(ctx.perfObjects["Processor"], &data)
+	err := unmarshalObject(ctx.perfObjects["Processor"], &data, c.logger)
 	if err != nil {
 		return err
 	}
@@ -284,7 +291,7 @@ type perflibProcessorInformation struct {
 
 func (c *cpuCollectorFull) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	data := make([]perflibProcessorInformation, 0)
-	err := unmarshalObject(ctx.perfObjects["Processor Information"], &data)
+	err := unmarshalObject(ctx.perfObjects["Processor Information"], &data, c.logger)
 	if err != nil {
 		return err
 	}
@@ -10,7 +10,6 @@ import (
 
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // If you are adding additional labels to the metric, make sure that they get added in here as well. See below for explanation.
@@ -20,11 +19,14 @@ const (
 
 // A CpuInfoCollector is a Prometheus collector for a few WMI metrics in Win32_Processor
 type CpuInfoCollector struct {
+	logger log.Logger
+
 	CpuInfo *prometheus.Desc
 }
 
 func newCpuInfoCollector() (Collector, error) {
 	return &CpuInfoCollector{
+		logger: log.With(log.CollectorField, "cpu_info"),
 		CpuInfo: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, "", "cpu_info"),
 			"Labeled CPU information as provided provided by Win32_Processor",
@@ -54,19 +56,19 @@ type win32_Processor struct {
 // Collect sends the metric values for each metric
 // to the provided prometheus Metric channel.
 func (c *CpuInfoCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
-	if desc, err := c.collect(ch); err != nil {
-		log.Error("failed collecting cpu_info metrics:", desc, err)
+	if desc, err := c.collect(ctx, ch); err != nil {
+		c.logger.Error("failed collecting cpu_info metrics:", desc, err)
 		return err
 	}
 	return nil
 }
 
-func (c *CpuInfoCollector) collect(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+func (c *CpuInfoCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_Processor
 	// We use a static query here because the provided methods in wmi.go all issue a SELECT *;
 	// This results in the time consuming LoadPercentage field being read which seems to measure each CPU
 	// serially over a 1 second interval, so the scrape time is at least 1s * num_sockets
-	if err := wmi.Query(win32ProcessorQuery, &dst); err != nil {
+	if err := ctx.wmiQuery(win32ProcessorQuery, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
@@ -12,6 +12,8 @@ import (
 
 // A CSCollector is a Prometheus collector for WMI metrics
 type CSCollector struct {
+	logger log.Logger
+
 	PhysicalMemoryBytes *prometheus.Desc
 	LogicalProcessors   *prometheus.Desc
 	Hostname            *prometheus.Desc
@@ -22,6 +24,7 @@ func newCSCollector() (Collector, error) {
 	const subsystem = "cs"
 
 	return &CSCollector{
+		logger: log.With(log.CollectorField, "cs"),
 		LogicalProcessors: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "logical_processors"),
 			"ComputerSystem.NumberOfLogicalProcessors",
@@ -50,7 +53,7 @@ func newCSCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *CSCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collect(ch); err != nil {
-		log.Error("failed collecting cs metrics:", desc, err)
+		c.logger.Error("failed collecting cs metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -9,7 +9,6 @@ import (
 
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 const (
@@ -18,6 +17,8 @@ const (
 
 // A DiskDriveInfoCollector is a Prometheus collector for a few WMI metrics in Win32_DiskDrive
 type DiskDriveInfoCollector struct {
+	logger log.Logger
+
 	DiskInfo     *prometheus.Desc
 	Status       *prometheus.Desc
 	Size         *prometheus.Desc
@@ -29,6 +30,7 @@ func newDiskDriveInfoCollector() (Collector, error) {
 	const subsystem = "diskdrive"
 
 	return &DiskDriveInfoCollector{
+		logger: log.With(log.CollectorField, "diskdrive"),
 		DiskInfo: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "info"),
 			"General drive information",
@@ -128,17 +130,17 @@ var (
 
 // Collect sends the metric values for each metric to the provided prometheus Metric channel.
 func (c *DiskDriveInfoCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
-	if desc, err := c.collect(ch); err != nil {
-		log.Error("failed collecting disk_drive_info metrics:", desc, err)
+	if desc, err := c.collect(ctx, ch); err != nil {
+		c.logger.Error("failed collecting disk_drive_info metrics:", desc, err)
 		return err
 	}
 	return nil
 }
 
-func (c *DiskDriveInfoCollector) collect(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+func (c *DiskDriveInfoCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_DiskDrive
 
-	if err := wmi.Query(win32DiskQuery, &dst); err != nil {
+	if err := ctx.wmiQuery(win32DiskQuery, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
@@ -8,11 +8,12 @@ import (
 
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A DNSCollector is a Prometheus collector for WMI Win32_PerfRawData_DNS_DNS metrics
 type DNSCollector struct {
+	logger log.Logger
+
 	ZoneTransferRequestsReceived  *prometheus.Desc
 	ZoneTransferRequestsSent      *prometheus.Desc
 	ZoneTransferResponsesReceived *prometheus.Desc
@@ -41,6 +42,7 @@ type DNSCollector struct {
 func newDNSCollector() (Collector, error) {
 	const subsystem = "dns"
 	return &DNSCollector{
+		logger: log.With(log.CollectorField, "dns"),
 		ZoneTransferRequestsReceived: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "zone_transfer_requests_received_total"),
 			"Number of zone transfer requests (AXFR/IXFR) received by the master DNS server",
@@ -179,8 +181,8 @@ func newDNSCollector() (Collector, error) {
 // Collect sends the metric values for each metric
 // to the provided prometheus Metric channel.
 func (c *DNSCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
-	if desc, err := c.collect(ch); err != nil {
-		log.Error("failed collecting dns metrics:", desc, err)
+	if desc, err := c.collect(ctx, ch); err != nil {
+		c.logger.Error("failed collecting dns metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -232,10 +234,10 @@ type Win32_PerfRawData_DNS_DNS struct {
 	ZoneTransferSOARequestSent     uint32
 }
 
-func (c *DNSCollector) collect(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+func (c *DNSCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_DNS_DNS
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := ctx.wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
@@ -3,10 +3,11 @@ package collector
 import (
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 type FSRMQuotaCollector struct {
+	logger log.Logger
+
 	QuotasCount *prometheus.Desc
 	Path        *prometheus.Desc
 	PeakUsage   *prometheus.Desc
@@ -23,6 +24,7 @@ type FSRMQuotaCollector struct {
 func newFSRMQuotaCollector() (Collector, error) {
 	const subsystem = "fsrmquota"
 	return &FSRMQuotaCollector{
+		logger: log.With(log.CollectorField, "fsrmquota"),
 		QuotasCount: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "count"),
 			"Number of Quotas",
@@ -83,8 +85,8 @@ func newFSRMQuotaCollector() (Collector, error) {
 // Collect sends the metric values for each metric
 // to the provided prometheus Metric channel.
 func (c *FSRMQuotaCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
-	if desc, err := c.collect(ch); err != nil {
-		log.Error("failed collecting fsrmquota metrics:", desc, err)
+	if desc, err := c.collect(ctx, ch); err != nil {
+		c.logger.Error("failed collecting fsrmquota metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -107,13 +109,13 @@ type MSFT_FSRMQuota struct {
 	SoftLimit       bool
 }
 
-func (c *FSRMQuotaCollector) collect(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+func (c *FSRMQuotaCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []MSFT_FSRMQuota
-	q := queryAll(&dst)
+	q := queryAll(&dst, c.logger)
 
 	var count int
 
-	if err := wmi.QueryNamespace(q, &dst, "root/microsoft/windows/fsrm"); err != nil {
+	if err := ctx.wmiQueryNamespace(q, &dst, "root/microsoft/windows/fsrm"); err != nil {
 		return nil, err
 	}
 
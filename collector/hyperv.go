@@ -8,11 +8,12 @@ import (
 
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // HyperVCollector is a Prometheus collector for hyper-v
 type HyperVCollector struct {
+	logger log.Logger
+
 	// Win32_PerfRawData_VmmsVirtualMachineStats_HyperVVirtualMachineHealthSummary
 	HealthCritical *prometheus.Desc
 	HealthOk       *prometheus.Desc
@@ -130,6 +131,7 @@ type HyperVCollector struct {
 func newHyperVCollector() (Collector, error) {
 	buildSubsystemName := func(component string) string { return "hyperv_" + component }
 	return &HyperVCollector{
+		logger: log.With(log.CollectorField, "hyperv"),
 		HealthCritical: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, buildSubsystemName("health"), "critical"),
 			"This counter represents the number of virtual machines with critical health",
@@ -697,62 +699,62 @@ func newHyperVCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *HyperVCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collectVmHealth(ch); err != nil {
-		log.Error("failed collecting hyperV health status metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV health status metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectVmVid(ch); err != nil {
-		log.Error("failed collecting hyperV pages metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV pages metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectVmHv(ch); err != nil {
-		log.Error("failed collecting hyperV hv status metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV hv status metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectVmProcessor(ch); err != nil {
-		log.Error("failed collecting hyperV processor metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV processor metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectHostLPUsage(ch); err != nil {
-		log.Error("failed collecting hyperV host logical processors metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV host logical processors metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectHostCpuUsage(ch); err != nil {
-		log.Error("failed collecting hyperV host CPU metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV host CPU metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectVmCpuUsage(ch); err != nil {
-		log.Error("failed collecting hyperV VM CPU metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV VM CPU metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectVmSwitch(ch); err != nil {
-		log.Error("failed collecting hyperV switch metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV switch metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectVmEthernet(ch); err != nil {
-		log.Error("failed collecting hyperV ethernet metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV ethernet metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectVmStorage(ch); err != nil {
-		log.Error("failed collecting hyperV virtual storage metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV virtual storage metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectVmNetwork(ch); err != nil {
-		log.Error("failed collecting hyperV virtual network metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV virtual network metrics:", desc, err)
 		return err
 	}
 
 	if desc, err := c.collectVmMemory(ch); err != nil {
-		log.Error("failed collecting hyperV virtual memory metrics:", desc, err)
+		c.logger.Error("failed collecting hyperV virtual memory metrics:", desc, err)
 		return err
 	}
 
@@ -767,8 +769,8 @@ type Win32_PerfRawData_VmmsVirtualMachineStats_HyperVVirtualMachineHealthSummary
 
 func (c *HyperVCollector) collectVmHealth(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_VmmsVirtualMachineStats_HyperVVirtualMachineHealthSummary
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -800,8 +802,8 @@ type Win32_PerfRawData_VidPerfProvider_HyperVVMVidPartition struct {
 
 func (c *HyperVCollector) collectVmVid(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_VidPerfProvider_HyperVVMVidPartition
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -864,8 +866,8 @@ type Win32_PerfRawData_HvStats_HyperVHypervisorRootPartition struct {
 
 func (c *HyperVCollector) collectVmHv(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_HvStats_HyperVHypervisorRootPartition
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1002,8 +1004,8 @@ type Win32_PerfRawData_HvStats_HyperVHypervisor struct {
 
 func (c *HyperVCollector) collectVmProcessor(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_HvStats_HyperVHypervisor
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1036,8 +1038,8 @@ type Win32_PerfRawData_HvStats_HyperVHypervisorLogicalProcessor struct {
 
 func (c *HyperVCollector) collectHostLPUsage(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_HvStats_HyperVHypervisorLogicalProcessor
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1048,7 +1050,7 @@ func (c *HyperVCollector) collectHostLPUsage(ch chan<- prometheus.Metric) (*prom
 		// The name format is Hv LP <core id>
 		parts := strings.Split(obj.Name, " ")
 		if len(parts) != 3 {
-			log.Warnf("Unexpected format of Name in collectHostLPUsage: %q", obj.Name)
+			c.logger.Warnf("Unexpected format of Name in collectHostLPUsage: %q", obj.Name)
 			continue
 		}
 		coreId := parts[2]
@@ -1090,8 +1092,8 @@ type Win32_PerfRawData_HvStats_HyperVHypervisorRootVirtualProcessor struct {
 
 func (c *HyperVCollector) collectHostCpuUsage(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_HvStats_HyperVHypervisorRootVirtualProcessor
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1102,7 +1104,7 @@ func (c *HyperVCollector) collectHostCpuUsage(ch chan<- prometheus.Metric) (*pro
 		// The name format is Root VP <core id>
 		parts := strings.Split(obj.Name, " ")
 		if len(parts) != 3 {
-			log.Warnf("Unexpected format of Name in collectHostCpuUsage: %q", obj.Name)
+			c.logger.Warnf("Unexpected format of Name in collectHostCpuUsage: %q", obj.Name)
 			continue
 		}
 		coreId := parts[2]
@@ -1151,8 +1153,8 @@ type Win32_PerfRawData_HvStats_HyperVHypervisorVirtualProcessor struct {
 
 func (c *HyperVCollector) collectVmCpuUsage(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_HvStats_HyperVHypervisorVirtualProcessor
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1163,12 +1165,12 @@ func (c *HyperVCollector) collectVmCpuUsage(ch chan<- prometheus.Metric) (*prome
 		// The name format is <VM Name>:Hv VP <vcore id>
 		parts := strings.Split(obj.Name, ":")
 		if len(parts) != 2 {
-			log.Warnf("Unexpected format of Name in collectVmCpuUsage: %q, expected %q. Skipping.", obj.Name, "<VM Name>:Hv VP <vcore id>")
+			c.logger.Warnf("Unexpected format of Name in collectVmCpuUsage: %q, expected %q. Skipping.", obj.Name, "<VM Name>:Hv VP <vcore id>")
 			continue
 		}
 		coreParts := strings.Split(parts[1], " ")
 		if len(coreParts) != 3 {
-			log.Warnf("Unexpected format of core identifier in collectVmCpuUsage: %q, expected %q. Skipping.", parts[1], "Hv VP <vcore id>")
+			c.logger.Warnf("Unexpected format of core identifier in collectVmCpuUsage: %q, expected %q. Skipping.", parts[1], "Hv VP <vcore id>")
 			continue
 		}
 		vmName := parts[0]
@@ -1238,8 +1240,8 @@ type Win32_PerfRawData_NvspSwitchStats_HyperVVirtualSwitch struct {
 
 func (c *HyperVCollector) collectVmSwitch(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_NvspSwitchStats_HyperVVirtualSwitch
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1403,8 +1405,8 @@ type Win32_PerfRawData_EthernetPerfProvider_HyperVLegacyNetworkAdapter struct {
 
 func (c *HyperVCollector) collectVmEthernet(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_EthernetPerfProvider_HyperVLegacyNetworkAdapter
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1473,8 +1475,8 @@ type Win32_PerfRawData_Counters_HyperVVirtualStorageDevice struct {
 
 func (c *HyperVCollector) collectVmStorage(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_Counters_HyperVVirtualStorageDevice
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1542,8 +1544,8 @@ type Win32_PerfRawData_NvspNicStats_HyperVVirtualNetworkAdapter struct {
 
 func (c *HyperVCollector) collectVmNetwork(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_NvspNicStats_HyperVVirtualNetworkAdapter
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1615,8 +1617,8 @@ type Win32_PerfRawData_BalancerStats_HyperVDynamicMemoryVM struct {
 
 func (c *HyperVCollector) collectVmMemory(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_PerfRawData_BalancerStats_HyperVDynamicMemoryVM
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
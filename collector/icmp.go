@@ -0,0 +1,290 @@
+//go:build windows
+// +build windows
+
+package collector
+
+import (
+	"github.com/prometheus-community/windows_exporter/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// An ICMPCollector is a Prometheus collector for WMI Win32_PerfRawData_Tcpip_ICMP and Win32_PerfRawData_Tcpip_ICMPv6 metrics
+type ICMPCollector struct {
+	logger log.Logger
+
+	MessagesTotal               *prometheus.Desc
+	MessagesReceivedTotal       *prometheus.Desc
+	MessagesReceivedErrorsTotal *prometheus.Desc
+	MessagesSentTotal           *prometheus.Desc
+	MessagesOutboundErrorsTotal *prometheus.Desc
+	MessagesReceivedByTypeTotal *prometheus.Desc
+	MessagesSentByTypeTotal     *prometheus.Desc
+}
+
+// newICMPCollector ...
+func newICMPCollector() (Collector, error) {
+	const subsystem = "icmp"
+
+	return &ICMPCollector{
+		logger: log.With(log.CollectorField, "icmp"),
+		MessagesTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "messages_total"),
+			"ICMP messages received or sent (ICMP.Messages)",
+			[]string{"af"},
+			nil,
+		),
+		MessagesReceivedTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "messages_received_total"),
+			"ICMP messages received, including those received in error (ICMP.MessagesReceived)",
+			[]string{"af"},
+			nil,
+		),
+		MessagesReceivedErrorsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "messages_received_errors_total"),
+			"ICMP messages received with errors, such as bad checksums or lengths (ICMP.MessagesReceivedErrors)",
+			[]string{"af"},
+			nil,
+		),
+		MessagesSentTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "messages_sent_total"),
+			"ICMP messages attempted to send, including those sent in error (ICMP.MessagesSent)",
+			[]string{"af"},
+			nil,
+		),
+		MessagesOutboundErrorsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "messages_outbound_errors_total"),
+			"ICMP messages not sent due to problems within ICMP, such as lack of buffers (ICMP.MessagesOutboundErrors)",
+			[]string{"af"},
+			nil,
+		),
+		MessagesReceivedByTypeTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "messages_received_by_type_total"),
+			"ICMP messages received by message type",
+			[]string{"af", "type"},
+			nil,
+		),
+		MessagesSentByTypeTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "messages_sent_by_type_total"),
+			"ICMP messages sent by message type",
+			[]string{"af", "type"},
+			nil,
+		),
+	}, nil
+}
+
+// Collect sends the metric values for each metric
+// to the provided prometheus Metric channel.
+func (c *ICMPCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
+	if desc, err := c.collect(ctx, ch); err != nil {
+		c.logger.Error("failed collecting icmp metrics:", desc, err)
+		return err
+	}
+	return nil
+}
+
+// Win32_PerfRawData_Tcpip_ICMP docs
+// - https://learn.microsoft.com/en-us/previous-versions/aa394333(v=vs.85)
+type icmp struct {
+	MessagesPersec               float64 `perflib:"Messages/sec"`
+	MessagesReceivedPersec       float64 `perflib:"Messages Received/sec"`
+	MessagesReceivedErrors       float64 `perflib:"Messages Received Errors"`
+	MessagesSentPersec           float64 `perflib:"Messages Sent/sec"`
+	MessagesOutboundErrors       float64 `perflib:"Messages Outbound Errors"`
+	ReceivedDestUnreachable      float64 `perflib:"Received Dest. Unreachable"`
+	ReceivedTimeExceeded         float64 `perflib:"Received Time Exceeded"`
+	ReceivedParameterProblem     float64 `perflib:"Received Parameter Problem"`
+	ReceivedSourceQuench         float64 `perflib:"Received Source Quench"`
+	ReceivedRedirectPersec       float64 `perflib:"Received Redirect/sec"`
+	ReceivedEchoPersec           float64 `perflib:"Received Echo/sec"`
+	ReceivedEchoReplyPersec      float64 `perflib:"Received Echo Reply/sec"`
+	ReceivedTimestampPersec      float64 `perflib:"Received Timestamp/sec"`
+	ReceivedTimestampReplyPersec float64 `perflib:"Received Timestamp Reply/sec"`
+	ReceivedAddressMask          float64 `perflib:"Received Address Mask"`
+	ReceivedAddressMaskReply     float64 `perflib:"Received Address Mask Reply"`
+	SentDestinationUnreachable   float64 `perflib:"Sent Destination Unreachable"`
+	SentTimeExceeded             float64 `perflib:"Sent Time Exceeded"`
+	SentParameterProblem         float64 `perflib:"Sent Parameter Problem"`
+	SentSourceQuench             float64 `perflib:"Sent Source Quench"`
+	SentRedirectPersec           float64 `perflib:"Sent Redirect/sec"`
+	SentEchoPersec               float64 `perflib:"Sent Echo/sec"`
+	SentEchoReplyPersec          float64 `perflib:"Sent Echo Reply/sec"`
+	SentTimestampPersec          float64 `perflib:"Sent Timestamp/sec"`
+	SentTimestampReplyPersec     float64 `perflib:"Sent Timestamp Reply/sec"`
+	SentAddressMask              float64 `perflib:"Sent Address Mask"`
+	SentAddressMaskReply         float64 `perflib:"Sent Address Mask Reply"`
+}
+
+// Win32_PerfRawData_Tcpip_ICMPv6 has the message types of ICMPv6, which
+// differ from those of ICMP.
+type icmpv6 struct {
+	MessagesPersec                 float64 `perflib:"Messages/sec"`
+	MessagesReceivedPersec         float64 `perflib:"Messages Received/sec"`
+	MessagesReceivedErrors         float64 `perflib:"Messages Received Errors"`
+	MessagesSentPersec             float64 `perflib:"Messages Sent/sec"`
+	MessagesOutboundErrors         float64 `perflib:"Messages Outbound Errors"`
+	ReceivedDestinationUnreachable float64 `perflib:"Received Destination Unreachable"`
+	ReceivedPacketTooBig           float64 `perflib:"Received Packet Too Big"`
+	ReceivedTimeExceeded           float64 `perflib:"Received Time Exceeded"`
+	ReceivedParameterProblem       float64 `perflib:"Received Parameter Problem"`
+	ReceivedEchoPersec             float64 `perflib:"Received Echo/sec"`
+	ReceivedEchoReplyPersec        float64 `perflib:"Received Echo Reply/sec"`
+	ReceivedMembershipQuery        float64 `perflib:"Received Membership Query"`
+	ReceivedMembershipReport       float64 `perflib:"Received Membership Report"`
+	ReceivedMembershipReduction    float64 `perflib:"Received Membership Reduction"`
+	ReceivedRouterSolicit          float64 `perflib:"Received Router Solicit"`
+	ReceivedRouterAdvert           float64 `perflib:"Received Router Advert"`
+	ReceivedNeighborSolicit        float64 `perflib:"Received Neighbor Solicit"`
+	ReceivedNeighborAdvert         float64 `perflib:"Received Neighbor Advert"`
+	ReceivedRedirectPersec         float64 `perflib:"Received Redirect/sec"`
+	SentDestinationUnreachable     float64 `perflib:"Sent Destination Unreachable"`
+	SentPacketTooBig               float64 `perflib:"Sent Packet Too Big"`
+	SentTimeExceeded               float64 `perflib:"Sent Time Exceeded"`
+	SentParameterProblem           float64 `perflib:"Sent Parameter Problem"`
+	SentEchoPersec                 float64 `perflib:"Sent Echo/sec"`
+	SentEchoReplyPersec            float64 `perflib:"Sent Echo Reply/sec"`
+	SentMembershipQuery            float64 `perflib:"Sent Membership Query"`
+	SentMembershipReport           float64 `perflib:"Sent Membership Report"`
+	SentMembershipReduction        float64 `perflib:"Sent Membership Reduction"`
+	SentRouterSolicit              float64 `perflib:"Sent Router Solicit"`
+	SentRouterAdvert               float64 `perflib:"Sent Router Advert"`
+	SentNeighborSolicit            float64 `perflib:"Sent Neighbor Solicit"`
+	SentNeighborAdvert             float64 `perflib:"Sent Neighbor Advert"`
+	SentRedirectPersec             float64 `perflib:"Sent Redirect/sec"`
+}
+
+type icmpTypeCount struct {
+	messageType string
+	received    float64
+	sent        float64
+}
+
+// icmpMessages are the counters of ICMP or ICMPv6, with the message types of
+// the family.
+type icmpMessages struct {
+	total          float64
+	received       float64
+	receivedErrors float64
+	sent           float64
+	outboundErrors float64
+	types          []icmpTypeCount
+}
+
+func (m icmp) messages() icmpMessages {
+	return icmpMessages{
+		total:          m.MessagesPersec,
+		received:       m.MessagesReceivedPersec,
+		receivedErrors: m.MessagesReceivedErrors,
+		sent:           m.MessagesSentPersec,
+		outboundErrors: m.MessagesOutboundErrors,
+		types: []icmpTypeCount{
+			{"destination_unreachable", m.ReceivedDestUnreachable, m.SentDestinationUnreachable},
+			{"time_exceeded", m.ReceivedTimeExceeded, m.SentTimeExceeded},
+			{"parameter_problem", m.ReceivedParameterProblem, m.SentParameterProblem},
+			{"source_quench", m.ReceivedSourceQuench, m.SentSourceQuench},
+			{"redirect", m.ReceivedRedirectPersec, m.SentRedirectPersec},
+			{"echo", m.ReceivedEchoPersec, m.SentEchoPersec},
+			{"echo_reply", m.ReceivedEchoReplyPersec, m.SentEchoReplyPersec},
+			{"timestamp", m.ReceivedTimestampPersec, m.SentTimestampPersec},
+			{"timestamp_reply", m.ReceivedTimestampReplyPersec, m.SentTimestampReplyPersec},
+			{"address_mask", m.ReceivedAddressMask, m.SentAddressMask},
+			{"address_mask_reply", m.ReceivedAddressMaskReply, m.SentAddressMaskReply},
+		},
+	}
+}
+
+func (m icmpv6) messages() icmpMessages {
+	return icmpMessages{
+		total:          m.MessagesPersec,
+		received:       m.MessagesReceivedPersec,
+		receivedErrors: m.MessagesReceivedErrors,
+		sent:           m.MessagesSentPersec,
+		outboundErrors: m.MessagesOutboundErrors,
+		types: []icmpTypeCount{
+			{"destination_unreachable", m.ReceivedDestinationUnreachable, m.SentDestinationUnreachable},
+			{"packet_too_big", m.ReceivedPacketTooBig, m.SentPacketTooBig},
+			{"time_exceeded", m.ReceivedTimeExceeded, m.SentTimeExceeded},
+			{"parameter_problem", m.ReceivedParameterProblem, m.SentParameterProblem},
+			{"echo", m.ReceivedEchoPersec, m.SentEchoPersec},
+			{"echo_reply", m.ReceivedEchoReplyPersec, m.SentEchoReplyPersec},
+			{"membership_query", m.ReceivedMembershipQuery, m.SentMembershipQuery},
+			{"membership_report", m.ReceivedMembershipReport, m.SentMembershipReport},
+			{"membership_reduction", m.ReceivedMembershipReduction, m.SentMembershipReduction},
+			{"router_solicit", m.ReceivedRouterSolicit, m.SentRouterSolicit},
+			{"router_advert", m.ReceivedRouterAdvert, m.SentRouterAdvert},
+			{"neighbor_solicit", m.ReceivedNeighborSolicit, m.SentNeighborSolicit},
+			{"neighbor_advert", m.ReceivedNeighborAdvert, m.SentNeighborAdvert},
+			{"redirect", m.ReceivedRedirectPersec, m.SentRedirectPersec},
+		},
+	}
+}
+
+func writeICMPCounters(metrics icmpMessages, af string, c *ICMPCollector, ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(
+		c.MessagesTotal,
+		prometheus.CounterValue,
+		metrics.total,
+		af,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.MessagesReceivedTotal,
+		prometheus.CounterValue,
+		metrics.received,
+		af,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.MessagesReceivedErrorsTotal,
+		prometheus.CounterValue,
+		metrics.receivedErrors,
+		af,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.MessagesSentTotal,
+		prometheus.CounterValue,
+		metrics.sent,
+		af,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.MessagesOutboundErrorsTotal,
+		prometheus.CounterValue,
+		metrics.outboundErrors,
+		af,
+	)
+	for _, t := range metrics.types {
+		ch <- prometheus.MustNewConstMetric(
+			c.MessagesReceivedByTypeTotal,
+			prometheus.CounterValue,
+			t.received,
+			af,
+			t.messageType,
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.MessagesSentByTypeTotal,
+			prometheus.CounterValue,
+			t.sent,
+			af,
+			t.messageType,
+		)
+	}
+}
+
+func (c *ICMPCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+	// ICMP counters
+	var dst []icmp
+	if err := unmarshalObject(ctx.perfObjects["ICMP"], &dst, c.logger); err != nil {
+		return nil, err
+	}
+	if len(dst) != 0 {
+		writeICMPCounters(dst[0].messages(), "ipv4", c, ch)
+	}
+
+	// ICMPv6 counters
+	var dstv6 []icmpv6
+	if err := unmarshalObject(ctx.perfObjects["ICMPv6"], &dstv6, c.logger); err != nil {
+		return nil, err
+	}
+	if len(dstv6) != 0 {
+		writeICMPCounters(dstv6[0].messages(), "ipv6", c, ch)
+	}
+
+	return nil, nil
+}
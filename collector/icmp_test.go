@@ -0,0 +1,9 @@
+package collector
+
+import (
+	"testing"
+)
+
+func BenchmarkICMPCollector(b *testing.B) {
+	benchmarkCollector(b, "icmp", newICMPCollector)
+}
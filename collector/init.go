@@ -1,6 +1,9 @@
 package collector
 
-import "github.com/alecthomas/kingpin/v2"
+import (
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus-community/windows_exporter/log"
+)
 
 // collectorInit represents the required initialisation config for a collector.
 type collectorInit struct {
@@ -139,6 +142,14 @@ var collectors = []collectorInit{
 		builder:         newHyperVCollector,
 		perfCounterFunc: nil,
 	},
+	{
+		name:    "icmp",
+		flags:   nil,
+		builder: newICMPCollector,
+		perfCounterFunc: func() []string {
+			return []string{"ICMP", "ICMPv6"}
+		},
+	},
 	{
 		name:    "iis",
 		flags:   newIISCollectorFlags,
@@ -152,6 +163,14 @@ var collectors = []collectorInit{
 			}
 		},
 	},
+	{
+		name:    "ip",
+		flags:   nil,
+		builder: newIPCollector,
+		perfCounterFunc: func() []string {
+			return []string{"IPv4", "IPv6"}
+		},
+	},
 	{
 		name:    "logical_disk",
 		flags:   newLogicalDiskCollectorFlags,
@@ -302,6 +321,12 @@ var collectors = []collectorInit{
 		builder:         newScheduledTask,
 		perfCounterFunc: nil,
 	},
+	{
+		name:            "script",
+		flags:           newScriptCollectorFlags,
+		builder:         newScriptCollector,
+		perfCounterFunc: nil,
+	},
 	{
 		name:            "service",
 		flags:           newServiceCollectorFlags,
@@ -332,7 +357,7 @@ var collectors = []collectorInit{
 	},
 	{
 		name:    "tcp",
-		flags:   nil,
+		flags:   newTCPCollectorFlags,
 		builder: newTCPCollector,
 		perfCounterFunc: func() []string {
 			return []string{"TCPv4"}
@@ -370,6 +395,14 @@ var collectors = []collectorInit{
 			return []string{"Windows Time Service"}
 		},
 	},
+	{
+		name:    "udp",
+		flags:   nil,
+		builder: newUDPCollector,
+		perfCounterFunc: func() []string {
+			return []string{"UDPv4", "UDPv6"}
+		},
+	},
 	{
 		name:            "vmware",
 		flags:           nil,
@@ -386,15 +419,20 @@ var collectors = []collectorInit{
 
 // RegisterCollectorsFlags To be called by the exporter for collector initialisation before running app.Parse
 func RegisterCollectorsFlags(app *kingpin.Application) {
+	newWMIFlags(app)
+	names := make([]string, 0, len(collectors))
 	for _, v := range collectors {
 		if v.flags != nil {
 			v.flags(app)
 		}
+		names = append(names, v.name)
 	}
+	log.AddCollectorFlags(app, names)
 }
 
 // RegisterCollectors To be called by the exporter for collector initialisation
 func RegisterCollectors() {
+	defaultWMIClient.configure(*wmiQueryTimeout, *wmiBreakerThreshold, *wmiBreakerBackoff)
 	for _, v := range collectors {
 		var perfCounterNames []string
 
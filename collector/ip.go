@@ -0,0 +1,303 @@
+//go:build windows
+// +build windows
+
+package collector
+
+import (
+	"github.com/prometheus-community/windows_exporter/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// An IPCollector is a Prometheus collector for WMI Win32_PerfRawData_Tcpip_IPv{4,6} metrics
+type IPCollector struct {
+	logger log.Logger
+
+	DatagramsTotal                        *prometheus.Desc
+	DatagramsReceivedTotal                *prometheus.Desc
+	DatagramsReceivedHeaderErrorsTotal    *prometheus.Desc
+	DatagramsReceivedAddressErrorsTotal   *prometheus.Desc
+	DatagramsForwardedTotal               *prometheus.Desc
+	DatagramsReceivedUnknownProtocolTotal *prometheus.Desc
+	DatagramsReceivedDiscardedTotal       *prometheus.Desc
+	DatagramsReceivedDeliveredTotal       *prometheus.Desc
+	DatagramsSentTotal                    *prometheus.Desc
+	DatagramsOutboundDiscardedTotal       *prometheus.Desc
+	DatagramsOutboundNoRouteTotal         *prometheus.Desc
+	FragmentsReceivedTotal                *prometheus.Desc
+	FragmentsReassembledTotal             *prometheus.Desc
+	FragmentReassemblyFailuresTotal       *prometheus.Desc
+	FragmentedDatagramsTotal              *prometheus.Desc
+	FragmentationFailuresTotal            *prometheus.Desc
+	FragmentsCreatedTotal                 *prometheus.Desc
+}
+
+// newIPCollector ...
+func newIPCollector() (Collector, error) {
+	const subsystem = "ip"
+
+	return &IPCollector{
+		logger: log.With(log.CollectorField, "ip"),
+		DatagramsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_total"),
+			"Datagrams received from or sent to the interfaces (IP.Datagrams)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsReceivedTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_received_total"),
+			"Datagrams received from the interfaces, including those received in error (IP.DatagramsReceived)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsReceivedHeaderErrorsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_received_header_errors_total"),
+			"Datagrams received discarded due to errors in their headers (IP.DatagramsReceivedHeaderErrors)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsReceivedAddressErrorsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_received_address_errors_total"),
+			"Datagrams received discarded because their destination address was not valid (IP.DatagramsReceivedAddressErrors)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsForwardedTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_forwarded_total"),
+			"Datagrams forwarded to their final destination (IP.DatagramsForwarded)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsReceivedUnknownProtocolTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_received_unknown_protocol_total"),
+			"Datagrams received successfully but discarded because of an unknown or unsupported protocol (IP.DatagramsReceivedUnknownProtocol)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsReceivedDiscardedTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_received_discarded_total"),
+			"Datagrams received without errors but discarded, e.g. for lack of buffer space (IP.DatagramsReceivedDiscarded)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsReceivedDeliveredTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_received_delivered_total"),
+			"Datagrams received successfully delivered to the IP user protocols, including ICMP (IP.DatagramsReceivedDelivered)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsSentTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_sent_total"),
+			"Datagrams supplied to IP for transmission by the local IP user protocols, including ICMP (IP.DatagramsSent)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsOutboundDiscardedTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_outbound_discarded_total"),
+			"Datagrams to send without errors but discarded, e.g. for lack of buffer space (IP.DatagramsOutboundDiscarded)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsOutboundNoRouteTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_outbound_no_route_total"),
+			"Datagrams discarded because no route could be found to their destination (IP.DatagramsOutboundNoRoute)",
+			[]string{"af"},
+			nil,
+		),
+		FragmentsReceivedTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "fragments_received_total"),
+			"Fragments received that need to be reassembled (IP.FragmentsReceived)",
+			[]string{"af"},
+			nil,
+		),
+		FragmentsReassembledTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "fragments_reassembled_total"),
+			"Datagrams successfully reassembled (IP.FragmentsReassembled)",
+			[]string{"af"},
+			nil,
+		),
+		FragmentReassemblyFailuresTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "fragment_reassembly_failures_total"),
+			"Failures detected by the reassembly algorithm, such as timeouts (IP.FragmentReassemblyFailures)",
+			[]string{"af"},
+			nil,
+		),
+		FragmentedDatagramsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "fragmented_datagrams_total"),
+			"Datagrams successfully fragmented (IP.FragmentedDatagrams)",
+			[]string{"af"},
+			nil,
+		),
+		FragmentationFailuresTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "fragmentation_failures_total"),
+			"Datagrams discarded because they needed to be fragmented but could not be, e.g. because of the Don't Fragment flag (IP.FragmentationFailures)",
+			[]string{"af"},
+			nil,
+		),
+		FragmentsCreatedTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "fragments_created_total"),
+			"Fragments generated by fragmentation (IP.FragmentsCreated)",
+			[]string{"af"},
+			nil,
+		),
+	}, nil
+}
+
+// Collect sends the metric values for each metric
+// to the provided prometheus Metric channel.
+func (c *IPCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
+	if desc, err := c.collect(ctx, ch); err != nil {
+		c.logger.Error("failed collecting ip metrics:", desc, err)
+		return err
+	}
+	return nil
+}
+
+// Win32_PerfRawData_Tcpip_IPv4 docs
+// - https://learn.microsoft.com/en-us/previous-versions/aa394337(v=vs.85)
+// The IPv6 performance object uses the same fields.
+type ip struct {
+	DatagramsPersec                  float64 `perflib:"Datagrams/sec"`
+	DatagramsReceivedPersec          float64 `perflib:"Datagrams Received/sec"`
+	DatagramsReceivedHeaderErrors    float64 `perflib:"Datagrams Received Header Errors"`
+	DatagramsReceivedAddressErrors   float64 `perflib:"Datagrams Received Address Errors"`
+	DatagramsForwardedPersec         float64 `perflib:"Datagrams Forwarded/sec"`
+	DatagramsReceivedUnknownProtocol float64 `perflib:"Datagrams Received Unknown Protocol"`
+	DatagramsReceivedDiscarded       float64 `perflib:"Datagrams Received Discarded"`
+	DatagramsReceivedDeliveredPersec float64 `perflib:"Datagrams Received Delivered/sec"`
+	DatagramsSentPersec              float64 `perflib:"Datagrams Sent/sec"`
+	DatagramsOutboundDiscarded       float64 `perflib:"Datagrams Outbound Discarded"`
+	DatagramsOutboundNoRoute         float64 `perflib:"Datagrams Outbound No Route"`
+	FragmentsReceivedPersec          float64 `perflib:"Fragments Received/sec"`
+	FragmentsReassembledPersec       float64 `perflib:"Fragments Re-assembled/sec"`
+	FragmentReassemblyFailures       float64 `perflib:"Fragment Re-assembly Failures"`
+	FragmentedDatagramsPersec        float64 `perflib:"Fragmented Datagrams/sec"`
+	FragmentationFailures            float64 `perflib:"Fragmentation Failures"`
+	FragmentsCreatedPersec           float64 `perflib:"Fragments Created/sec"`
+}
+
+func writeIPCounters(metrics ip, labels []string, c *IPCollector, ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsReceivedTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsReceivedPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsReceivedHeaderErrorsTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsReceivedHeaderErrors,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsReceivedAddressErrorsTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsReceivedAddressErrors,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsForwardedTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsForwardedPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsReceivedUnknownProtocolTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsReceivedUnknownProtocol,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsReceivedDiscardedTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsReceivedDiscarded,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsReceivedDeliveredTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsReceivedDeliveredPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsSentTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsSentPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsOutboundDiscardedTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsOutboundDiscarded,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsOutboundNoRouteTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsOutboundNoRoute,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.FragmentsReceivedTotal,
+		prometheus.CounterValue,
+		metrics.FragmentsReceivedPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.FragmentsReassembledTotal,
+		prometheus.CounterValue,
+		metrics.FragmentsReassembledPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.FragmentReassemblyFailuresTotal,
+		prometheus.CounterValue,
+		metrics.FragmentReassemblyFailures,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.FragmentedDatagramsTotal,
+		prometheus.CounterValue,
+		metrics.FragmentedDatagramsPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.FragmentationFailuresTotal,
+		prometheus.CounterValue,
+		metrics.FragmentationFailures,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.FragmentsCreatedTotal,
+		prometheus.CounterValue,
+		metrics.FragmentsCreatedPersec,
+		labels...,
+	)
+}
+
+func (c *IPCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+	var dst []ip
+
+	// IPv4 counters
+	if err := unmarshalObject(ctx.perfObjects["IPv4"], &dst, c.logger); err != nil {
+		return nil, err
+	}
+	if len(dst) != 0 {
+		writeIPCounters(dst[0], []string{"ipv4"}, c, ch)
+	}
+
+	// IPv6 counters
+	if err := unmarshalObject(ctx.perfObjects["IPv6"], &dst, c.logger); err != nil {
+		return nil, err
+	}
+	if len(dst) != 0 {
+		writeIPCounters(dst[0], []string{"ipv6"}, c, ch)
+	}
+
+	return nil, nil
+}
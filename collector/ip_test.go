@@ -0,0 +1,9 @@
+package collector
+
+import (
+	"testing"
+)
+
+func BenchmarkIPCollector(b *testing.B) {
+	benchmarkCollector(b, "ip", newIPCollector)
+}
@@ -8,11 +8,12 @@ import (
 
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A LogonCollector is a Prometheus collector for WMI metrics
 type LogonCollector struct {
+	logger log.Logger
+
 	LogonType *prometheus.Desc
 }
 
@@ -21,6 +22,7 @@ func newLogonCollector() (Collector, error) {
 	const subsystem = "logon"
 
 	return &LogonCollector{
+		logger: log.With(log.CollectorField, "logon"),
 		LogonType: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "logon_type"),
 			"Number of active logon sessions (LogonSession.LogonType)",
@@ -33,8 +35,8 @@ func newLogonCollector() (Collector, error) {
 // Collect sends the metric values for each metric
 // to the provided prometheus Metric channel.
 func (c *LogonCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
-	if desc, err := c.collect(ch); err != nil {
-		log.Error("failed collecting user metrics:", desc, err)
+	if desc, err := c.collect(ctx, ch); err != nil {
+		c.logger.Error("failed collecting user metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -46,10 +48,10 @@ type Win32_LogonSession struct {
 	LogonType uint32
 }
 
-func (c *LogonCollector) collect(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+func (c *LogonCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []Win32_LogonSession
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := ctx.wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
@@ -13,6 +13,8 @@ import (
 
 // A MemoryCollector is a Prometheus collector for perflib Memory metrics
 type MemoryCollector struct {
+	logger log.Logger
+
 	AvailableBytes                  *prometheus.Desc
 	CacheBytes                      *prometheus.Desc
 	CacheBytesPeak                  *prometheus.Desc
@@ -52,6 +54,7 @@ func newMemoryCollector() (Collector, error) {
 	const subsystem = "memory"
 
 	return &MemoryCollector{
+		logger: log.With(log.CollectorField, "memory"),
 		AvailableBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "available_bytes"),
 			"The amount of physical memory immediately available for allocation to a process or for system use. It is equal to the sum of memory assigned to"+
@@ -263,7 +266,7 @@ func newMemoryCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *MemoryCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collect(ctx, ch); err != nil {
-		log.Error("failed collecting memory metrics:", desc, err)
+		c.logger.Error("failed collecting memory metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -308,7 +311,7 @@ type memory struct {
 
 func (c *MemoryCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []memory
-	if err := unmarshalObject(ctx.perfObjects["Memory"], &dst); err != nil {
+	if err := unmarshalObject(ctx.perfObjects["Memory"], &dst, c.logger); err != nil {
 		return nil, err
 	}
 
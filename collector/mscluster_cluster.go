@@ -1,12 +1,14 @@
 package collector
 
 import (
+	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A MSCluster_ClusterCollector is a Prometheus collector for WMI MSCluster_Cluster metrics
 type MSCluster_ClusterCollector struct {
+	logger log.Logger
+
 	AddEvictDelay                           *prometheus.Desc
 	AdminAccessPoint                        *prometheus.Desc
 	AutoAssignNodeSite                      *prometheus.Desc
@@ -89,6 +91,7 @@ type MSCluster_ClusterCollector struct {
 func newMSCluster_ClusterCollector() (Collector, error) {
 	const subsystem = "mscluster_cluster"
 	return &MSCluster_ClusterCollector{
+		logger: log.With(log.CollectorField, "mscluster_cluster"),
 		AddEvictDelay: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "add_evict_delay"),
 			"Provides access to the cluster's AddEvictDelay property, which is the number a seconds that a new node is delayed after an eviction of another node.",
@@ -642,8 +645,8 @@ type MSCluster_Cluster struct {
 // to the provided prometheus Metric channel.
 func (c *MSCluster_ClusterCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	var dst []MSCluster_Cluster
-	q := queryAll(&dst)
-	if err := wmi.QueryNamespace(q, &dst, "root/MSCluster"); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := ctx.wmiQueryNamespace(q, &dst, "root/MSCluster"); err != nil {
 		return err
 	}
 
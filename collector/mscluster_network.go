@@ -1,12 +1,14 @@
 package collector
 
 import (
+	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A MSCluster_NetworkCollector is a Prometheus collector for WMI MSCluster_Network metrics
 type MSCluster_NetworkCollector struct {
+	logger log.Logger
+
 	Characteristics *prometheus.Desc
 	Flags           *prometheus.Desc
 	Metric          *prometheus.Desc
@@ -17,6 +19,7 @@ type MSCluster_NetworkCollector struct {
 func newMSCluster_NetworkCollector() (Collector, error) {
 	const subsystem = "mscluster_network"
 	return &MSCluster_NetworkCollector{
+		logger: log.With(log.CollectorField, "mscluster_network"),
 		Characteristics: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "characteristics"),
 			"Provides the characteristics of the network.",
@@ -66,8 +69,8 @@ type MSCluster_Network struct {
 // to the provided prometheus Metric channel.
 func (c *MSCluster_NetworkCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	var dst []MSCluster_Network
-	q := queryAll(&dst)
-	if err := wmi.QueryNamespace(q, &dst, "root/MSCluster"); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := ctx.wmiQueryNamespace(q, &dst, "root/MSCluster"); err != nil {
 		return err
 	}
 
@@ -1,12 +1,14 @@
 package collector
 
 import (
+	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A MSCluster_NodeCollector is a Prometheus collector for WMI MSCluster_Node metrics
 type MSCluster_NodeCollector struct {
+	logger log.Logger
+
 	BuildNumber           *prometheus.Desc
 	Characteristics       *prometheus.Desc
 	DetectedCloudPlatform *prometheus.Desc
@@ -26,6 +28,7 @@ type MSCluster_NodeCollector struct {
 func newMSCluster_NodeCollector() (Collector, error) {
 	const subsystem = "mscluster_node"
 	return &MSCluster_NodeCollector{
+		logger: log.With(log.CollectorField, "mscluster_node"),
 		BuildNumber: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "build_number"),
 			"Provides access to the node's BuildNumber property.",
@@ -138,8 +141,8 @@ type MSCluster_Node struct {
 // to the provided prometheus Metric channel.
 func (c *MSCluster_NodeCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	var dst []MSCluster_Node
-	q := queryAll(&dst)
-	if err := wmi.QueryNamespace(q, &dst, "root/MSCluster"); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := ctx.wmiQueryNamespace(q, &dst, "root/MSCluster"); err != nil {
 		return err
 	}
 
@@ -1,12 +1,14 @@
 package collector
 
 import (
+	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A MSCluster_ResourceCollector is a Prometheus collector for WMI MSCluster_Resource metrics
 type MSCluster_ResourceCollector struct {
+	logger log.Logger
+
 	Characteristics        *prometheus.Desc
 	DeadlockTimeout        *prometheus.Desc
 	EmbeddedFailureAction  *prometheus.Desc
@@ -28,6 +30,7 @@ type MSCluster_ResourceCollector struct {
 func newMSCluster_ResourceCollector() (Collector, error) {
 	const subsystem = "mscluster_resource"
 	return &MSCluster_ResourceCollector{
+		logger: log.With(log.CollectorField, "mscluster_resource"),
 		Characteristics: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "characteristics"),
 			"Provides the characteristics of the object.",
@@ -156,8 +159,8 @@ type MSCluster_Resource struct {
 // to the provided prometheus Metric channel.
 func (c *MSCluster_ResourceCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	var dst []MSCluster_Resource
-	q := queryAll(&dst)
-	if err := wmi.QueryNamespace(q, &dst, "root/MSCluster"); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := ctx.wmiQueryNamespace(q, &dst, "root/MSCluster"); err != nil {
 		return err
 	}
 
@@ -1,12 +1,14 @@
 package collector
 
 import (
+	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A MSCluster_ResourceGroupCollector is a Prometheus collector for WMI MSCluster_ResourceGroup metrics
 type MSCluster_ResourceGroupCollector struct {
+	logger log.Logger
+
 	AutoFailbackType    *prometheus.Desc
 	Characteristics     *prometheus.Desc
 	ColdStartSetting    *prometheus.Desc
@@ -27,6 +29,7 @@ type MSCluster_ResourceGroupCollector struct {
 func newMSCluster_ResourceGroupCollector() (Collector, error) {
 	const subsystem = "mscluster_resourcegroup"
 	return &MSCluster_ResourceGroupCollector{
+		logger: log.With(log.CollectorField, "mscluster_resourcegroup"),
 		AutoFailbackType: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "auto_failback_type"),
 			"Provides access to the group's AutoFailbackType property.",
@@ -132,8 +135,8 @@ type MSCluster_ResourceGroup struct {
 // to the provided prometheus Metric channel.
 func (c *MSCluster_ResourceGroupCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	var dst []MSCluster_ResourceGroup
-	q := queryAll(&dst)
-	if err := wmi.QueryNamespace(q, &dst, "root/MSCluster"); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := ctx.wmiQueryNamespace(q, &dst, "root/MSCluster"); err != nil {
 		return err
 	}
 
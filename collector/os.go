@@ -19,6 +19,8 @@ import (
 
 // A OSCollector is a Prometheus collector for WMI metrics
 type OSCollector struct {
+	logger log.Logger
+
 	OSInformation           *prometheus.Desc
 	PhysicalMemoryFreeBytes *prometheus.Desc
 	PagingFreeBytes         *prometheus.Desc
@@ -45,6 +47,7 @@ func newOSCollector() (Collector, error) {
 	const subsystem = "os"
 
 	return &OSCollector{
+		logger: log.With(log.CollectorField, "os"),
 		OSInformation: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "info"),
 			"OperatingSystem.Caption, OperatingSystem.Version",
@@ -130,7 +133,7 @@ func newOSCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *OSCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collect(ctx, ch); err != nil {
-		log.Error("failed collecting os metrics:", desc, err)
+		c.logger.Error("failed collecting os metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -200,7 +203,7 @@ func (c *OSCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (
 		file, err := os.Stat(fileString)
 		// For unknown reasons, Windows doesn't always create a page file. Continue collection rather than aborting.
 		if err != nil {
-			log.Debugf("Failed to read page file (reason: %s): %s\n", err, fileString)
+			c.logger.Debugf("Failed to read page file (reason: %s): %s\n", err, fileString)
 		} else {
 			fsipf += float64(file.Size())
 		}
@@ -212,7 +215,7 @@ func (c *OSCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (
 	}
 
 	var pfc = make([]pagingFileCounter, 0)
-	if err := unmarshalObject(ctx.perfObjects["Paging File"], &pfc); err != nil {
+	if err := unmarshalObject(ctx.perfObjects["Paging File"], &pfc, c.logger); err != nil {
 		return nil, err
 	}
 
@@ -271,7 +274,7 @@ func (c *OSCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (
 			fsipf,
 		)
 	} else {
-		log.Debugln("Could not find HKLM:\\SYSTEM\\CurrentControlSet\\Control\\Session Manager\\Memory Management key. windows_os_paging_free_bytes and windows_os_paging_limit_bytes will be omitted.")
+		c.logger.Debugln("Could not find HKLM:\\SYSTEM\\CurrentControlSet\\Control\\Session Manager\\Memory Management key. windows_os_paging_free_bytes and windows_os_paging_limit_bytes will be omitted.")
 	}
 	ch <- prometheus.MustNewConstMetric(
 		c.VirtualMemoryFreeBytes,
@@ -0,0 +1,220 @@
+//go:build windows
+// +build windows
+
+package collector
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+const defaultWMINamespace = `root\cimv2`
+
+// remoteCollectors lists the collectors relying solely on WMI, which can
+// therefore be scraped from a remote host.
+var remoteCollectors = map[string]bool{
+	"ad":                      true,
+	"cpu_info":                true,
+	"diskdrive":               true,
+	"dns":                     true,
+	"fsrmquota":               true,
+	"logon":                   true,
+	"mscluster_cluster":       true,
+	"mscluster_network":       true,
+	"mscluster_node":          true,
+	"mscluster_resource":      true,
+	"mscluster_resourcegroup": true,
+	"msmq":                    true,
+	"service":                 true,
+	"thermalzone":             true,
+}
+
+// SupportsRemote reports whether the named collector can scrape a remote host.
+func SupportsRemote(name string) bool {
+	return remoteCollectors[name]
+}
+
+// remoteWMIQuerier runs WMI queries on a remote host, connecting with the
+// given credentials.
+type remoteWMIQuerier struct {
+	services wmiQuerier
+	host     string
+	username string
+	password string
+}
+
+// Query implements wmiQuerier. Namespaces are passed by callers the same way
+// as for wmi.QueryNamespace, as the second connect server argument.
+func (q *remoteWMIQuerier) Query(query string, dst interface{}, connectServerArgs ...interface{}) error {
+	namespace := defaultWMINamespace
+	if len(connectServerArgs) > 1 {
+		if ns, ok := connectServerArgs[1].(string); ok && ns != "" {
+			namespace = ns
+		}
+	}
+	return q.services.Query(query, dst, q.host, namespace, q.username, q.password)
+}
+
+// Close implements io.Closer, closing the services if they hold resources.
+func (q *remoteWMIQuerier) Close() error {
+	if closer, ok := q.services.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+const (
+	// maxRemoteWMIClients is the number of probed hosts whose clients are
+	// kept, beyond which the least recently used are closed.
+	maxRemoteWMIClients = 100
+	// remoteWMIClientIdleTimeout is the time after which the client of a host
+	// no longer probed is closed.
+	remoteWMIClientIdleTimeout = 10 * time.Minute
+)
+
+type remoteWMIClientKey struct {
+	host     string
+	username string
+	password string
+}
+
+type remoteWMIClientEntry struct {
+	client   *wmiClient
+	lastUsed time.Time
+	// refs is the number of probes using the client, which is only closed
+	// once they are done.
+	refs    int
+	evicted bool
+}
+
+// remoteWMIClientCache keeps the clients of probed hosts, so that connections
+// and circuit breaker state survive between probes. The least recently used
+// and idle clients are evicted and closed, as the targets come from the
+// callers of the /probe endpoint.
+type remoteWMIClientCache struct {
+	mu          sync.Mutex
+	max         int
+	idleTimeout time.Duration
+	clients     map[remoteWMIClientKey]*remoteWMIClientEntry
+	newClient   func(key remoteWMIClientKey) (*wmiClient, error)
+	now         func() time.Time
+}
+
+func newRemoteWMIClientCache(max int, idleTimeout time.Duration, newClient func(key remoteWMIClientKey) (*wmiClient, error)) *remoteWMIClientCache {
+	return &remoteWMIClientCache{
+		max:         max,
+		idleTimeout: idleTimeout,
+		clients:     map[remoteWMIClientKey]*remoteWMIClientEntry{},
+		newClient:   newClient,
+		now:         time.Now,
+	}
+}
+
+// get returns the client for the key, creating it on first use, and a
+// function releasing it once the probe is done.
+func (c *remoteWMIClientCache) get(key remoteWMIClientKey) (*wmiClient, func(), error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	e, ok := c.clients[key]
+	if !ok {
+		client, err := c.newClient(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		e = &remoteWMIClientEntry{client: client}
+		c.clients[key] = e
+	}
+	e.refs++
+	e.lastUsed = now
+	c.evict(now)
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			e.refs--
+			e.lastUsed = c.now()
+			if e.evicted && e.refs == 0 {
+				e.client.Close() //nolint:errcheck
+			}
+		})
+	}
+	return e.client, release, nil
+}
+
+// evict removes the idle clients, and the least recently used ones beyond
+// the maximum. The clients still in use are closed once released.
+func (c *remoteWMIClientCache) evict(now time.Time) {
+	for key, e := range c.clients {
+		if e.refs == 0 && now.Sub(e.lastUsed) > c.idleTimeout {
+			c.remove(key, e)
+		}
+	}
+	for len(c.clients) > c.max {
+		var oldestKey remoteWMIClientKey
+		var oldest *remoteWMIClientEntry
+		for key, e := range c.clients {
+			if oldest == nil || e.lastUsed.Before(oldest.lastUsed) {
+				oldestKey, oldest = key, e
+			}
+		}
+		c.remove(oldestKey, oldest)
+	}
+}
+
+func (c *remoteWMIClientCache) remove(key remoteWMIClientKey, e *remoteWMIClientEntry) {
+	delete(c.clients, key)
+	e.evicted = true
+	if e.refs == 0 {
+		e.client.Close() //nolint:errcheck
+	}
+}
+
+var remoteWMIClients = newRemoteWMIClientCache(maxRemoteWMIClients, remoteWMIClientIdleTimeout, func(key remoteWMIClientKey) (*wmiClient, error) {
+	// Each host gets its own SWbemServices, which serializes its queries on
+	// a dedicated goroutine. A hung host thus doesn't block the others.
+	services, err := wmi.InitializeSWbemServices(&wmi.Client{AllowMissingFields: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SWbemServices for %s: %w", key.host, err)
+	}
+
+	return newWMIClient(
+		&remoteWMIQuerier{services: services, host: key.host, username: key.username, password: key.password},
+		defaultWMIClient.timeout,
+		defaultWMIClient.breakerThreshold,
+		defaultWMIClient.breakerBackoff,
+	), nil
+})
+
+// NewRemotePrometheus returns a new Prometheus scraping the given collectors
+// from a remote host over WMI, within the given timeout. The returned function
+// must be called once the Prometheus is no longer used.
+func NewRemotePrometheus(timeout time.Duration, cs map[string]Collector, host, username, password string) (*Prometheus, func(), error) {
+	for name := range cs {
+		if !SupportsRemote(name) {
+			return nil, nil, fmt.Errorf("collector %s does not support remote targets", name)
+		}
+	}
+
+	c, release, err := remoteWMIClients.get(remoteWMIClientKey{host: host, username: username, password: password})
+	if err != nil {
+		return nil, nil, err
+	}
+	return newRemotePrometheus(timeout, cs, host, c), release, nil
+}
+
+func newRemotePrometheus(timeout time.Duration, cs map[string]Collector, host string, c *wmiClient) *Prometheus {
+	return &Prometheus{
+		maxScrapeDuration: timeout,
+		collectors:        cs,
+		wmi:               c,
+		target:            host,
+	}
+}
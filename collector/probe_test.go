@@ -0,0 +1,172 @@
+package collector
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// fakeSWbemServices records the connect server arguments of the queries and
+// answers them with canned thermal zone data.
+type fakeSWbemServices struct {
+	connectServerArgs [][]interface{}
+}
+
+func (f *fakeSWbemServices) Query(query string, dst interface{}, connectServerArgs ...interface{}) error {
+	f.connectServerArgs = append(f.connectServerArgs, connectServerArgs)
+	if zones, ok := dst.(*[]Win32_PerfRawData_Counters_ThermalZoneInformation); ok {
+		*zones = append(*zones, Win32_PerfRawData_Counters_ThermalZoneInformation{
+			Name:                     `\_TZ.THRM`,
+			HighPrecisionTemperature: 3231,
+			PercentPassiveLimit:      100,
+		})
+	}
+	return nil
+}
+
+func TestRemoteWMIQuerierNamespace(t *testing.T) {
+	services := &fakeSWbemServices{}
+	q := &remoteWMIQuerier{services: services, host: "appliance01", username: "monitor", password: "secret"}
+
+	var dst []Win32_PerfRawData_Counters_ThermalZoneInformation
+	if err := q.Query("SELECT * FROM Win32_PerfRawData_Counters_ThermalZoneInformation", &dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Query("SELECT * FROM MSCluster_Node", &dst, nil, "root/MSCluster"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]interface{}{
+		{"appliance01", defaultWMINamespace, "monitor", "secret"},
+		{"appliance01", "root/MSCluster", "monitor", "secret"},
+	}
+	if !reflect.DeepEqual(services.connectServerArgs, expected) {
+		t.Errorf("unexpected connect server arguments %v, expected %v", services.connectServerArgs, expected)
+	}
+}
+
+func TestRemotePrometheus(t *testing.T) {
+	services := &fakeSWbemServices{}
+	client := newWMIClient(&remoteWMIQuerier{services: services, host: "appliance01"}, time.Second, 0, time.Minute)
+
+	c, err := newThermalZoneCollector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newRemotePrometheus(time.Second, map[string]Collector{"thermalzone": c}, "appliance01", client)
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(p)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]float64{}
+	for _, mf := range families {
+		for _, m := range mf.Metric {
+			switch mf.GetType() {
+			case dto.MetricType_GAUGE:
+				values[mf.GetName()] = m.GetGauge().GetValue()
+			}
+		}
+	}
+
+	if _, ok := values["windows_exporter_perflib_snapshot_duration_seconds"]; ok {
+		t.Error("remote scrapes must not take a perflib snapshot")
+	}
+	if v := values["windows_exporter_collector_success"]; v != 1 {
+		t.Errorf("expected collector to succeed, got %v", v)
+	}
+	if v := values["windows_thermalzone_percent_passive_limit"]; v != 100 {
+		t.Errorf("unexpected windows_thermalzone_percent_passive_limit %v", v)
+	}
+	if len(services.connectServerArgs) != 1 || services.connectServerArgs[0][0] != "appliance01" {
+		t.Errorf("expected a single query against the target, got %v", services.connectServerArgs)
+	}
+}
+
+func wmiClientClosed(c *wmiClient) bool {
+	select {
+	case <-c.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestRemoteWMIClientCache(t *testing.T) {
+	var created []*wmiClient
+	cache := newRemoteWMIClientCache(2, time.Minute, func(key remoteWMIClientKey) (*wmiClient, error) {
+		c := newWMIClient(&remoteWMIQuerier{services: &fakeSWbemServices{}, host: key.host}, time.Second, 0, time.Minute)
+		created = append(created, c)
+		return c, nil
+	})
+	now := time.Unix(0, 0)
+	cache.now = func() time.Time { return now }
+
+	// Probing many targets keeps the most recently used ones only.
+	for i := 0; i < 5; i++ {
+		now = now.Add(time.Second)
+		_, release, err := cache.get(remoteWMIClientKey{host: fmt.Sprintf("host%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+		if len(cache.clients) > 2 {
+			t.Fatalf("expected at most 2 clients, got %d", len(cache.clients))
+		}
+	}
+	for i, c := range created {
+		if closed := wmiClientClosed(c); closed != (i < 3) {
+			t.Errorf("expected client %d to be closed: %v, got %v", i, i < 3, closed)
+		}
+	}
+
+	// A cached client is reused.
+	c, release, err := cache.get(remoteWMIClientKey{host: "host4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != created[4] || len(created) != 5 {
+		t.Error("expected the client of host4 to be reused")
+	}
+
+	// A client evicted while in use is closed once released.
+	now = now.Add(time.Second)
+	for _, host := range []string{"host5", "host6"} {
+		_, r, err := cache.get(remoteWMIClientKey{host: host})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r()
+	}
+	if _, ok := cache.clients[remoteWMIClientKey{host: "host4"}]; ok {
+		t.Error("expected the client of host4 to be evicted")
+	}
+	if wmiClientClosed(c) {
+		t.Error("expected the client of host4 to stay open while in use")
+	}
+	release()
+	if !wmiClientClosed(c) {
+		t.Error("expected the client of host4 to be closed once released")
+	}
+
+	// Idle clients are closed.
+	now = now.Add(2 * time.Minute)
+	_, release, err = cache.get(remoteWMIClientKey{host: "host7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if len(cache.clients) != 1 {
+		t.Errorf("expected the idle clients to be evicted, got %d clients", len(cache.clients))
+	}
+	if c := created[len(created)-2]; !wmiClientClosed(c) {
+		t.Error("expected the idle client of host6 to be closed")
+	}
+}
@@ -9,11 +9,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus-community/windows_exporter/config"
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 const (
@@ -22,6 +24,17 @@ const (
 
 	FlagProcessExclude = "collector.process.exclude"
 	FlagProcessInclude = "collector.process.include"
+
+	FlagProcessIISSource = "collector.process.iis-source"
+	FlagProcessCmdline   = "collector.process.cmdline"
+	FlagProcessOwner     = "collector.process.owner"
+
+	FlagProcessAggregate  = "collector.process.aggregate"
+	FlagProcessGroupsFile = "collector.process.groups-file"
+
+	FlagProcessConnections = "collector.process.connections"
+	FlagProcessHandleTypes = "collector.process.handle-types"
+	FlagProcessJobs        = "collector.process.jobs"
 )
 
 var (
@@ -33,9 +46,22 @@ var (
 
 	processIncludeSet bool
 	processExcludeSet bool
+
+	processIISSource *string
+	processCmdline   *bool
+	processOwner     *bool
+
+	processAggregateByName *bool
+	processGroupsFile      *string
+
+	processConnections *bool
+	processHandleTypes *bool
+	processJobs        *bool
 )
 
 type processCollector struct {
+	logger log.Logger
+
 	StartTime         *prometheus.Desc
 	CPUTimeTotal      *prometheus.Desc
 	HandleCount       *prometheus.Desc
@@ -51,9 +77,38 @@ type processCollector struct {
 	WorkingSetPrivate *prometheus.Desc
 	WorkingSetPeak    *prometheus.Desc
 	WorkingSet        *prometheus.Desc
+	Info              *prometheus.Desc
+	TCPConnections    *prometheus.Desc
+	UDPEndpoints      *prometheus.Desc
+	ListeningPortInfo *prometheus.Desc
+	HandlesByType     *prometheus.Desc
+	InJob             *prometheus.Desc
+	ContainerInfo     *prometheus.Desc
 
 	processIncludePattern *regexp.Regexp
 	processExcludePattern *regexp.Regexp
+
+	iisSource string
+	cmdline   bool
+	owner     bool
+	// details caches the command lines and owners read for the info metric
+	// and the groups.
+	details *processDetailsCache
+
+	// The processes are reported by name rather than by process ID if
+	// aggregate is set.
+	aggregate   bool
+	byNameDescs processAggregateDescs
+
+	groups           []config.ProcessGroup
+	groupDescs       processAggregateDescs
+	groupsUseCmdline bool
+	groupsUseOwner   bool
+
+	connections bool
+	// handleTypes is set if the handles are counted by type.
+	handleTypes *processHandleCounter
+	jobs        bool
 }
 
 // newProcessCollectorFlags ...
@@ -74,6 +129,46 @@ func newProcessCollectorFlags(app *kingpin.Application) {
 		return nil
 	}).String()
 
+	processIISSource = app.Flag(
+		FlagProcessIISSource,
+		"Source of the application pools of the IIS worker processes, appended to their names: 'wmi' reads them from the IIS WMI provider, 'native' from the command lines of the processes, which requires Windows 8.1 or Windows Server 2012 R2 or later.",
+	).Default("wmi").Enum("native", "wmi")
+
+	processCmdline = app.Flag(
+		FlagProcessCmdline,
+		"Report the command line of the processes in the cmdline label of windows_process_info.",
+	).Default("false").Bool()
+
+	processOwner = app.Flag(
+		FlagProcessOwner,
+		"Report the account running the processes in the owner label of windows_process_info.",
+	).Default("false").Bool()
+
+	processAggregateByName = app.Flag(
+		FlagProcessAggregate,
+		"Aggregate the metrics of the processes by name, without the process_id and creating_process_id labels.",
+	).Default("false").Bool()
+
+	processGroupsFile = app.Flag(
+		FlagProcessGroupsFile,
+		"YAML file defining groups of processes, whose aggregated metrics are reported by group.",
+	).Default("").String()
+
+	processConnections = app.Flag(
+		FlagProcessConnections,
+		"Report the TCP connections, the UDP endpoints and the listening ports of the processes. Not supported with --collector.process.aggregate.",
+	).Default("false").Bool()
+
+	processHandleTypes = app.Flag(
+		FlagProcessHandleTypes,
+		"Report the handles of the processes by type. Warning: reads the handles of the whole system, possibly hundreds of thousands, on every scrape. Not supported with --collector.process.aggregate.",
+	).Default("false").Bool()
+
+	processJobs = app.Flag(
+		FlagProcessJobs,
+		"Report whether the processes run in a job object, and their container. Not supported with --collector.process.aggregate.",
+	).Default("false").Bool()
+
 	processOldInclude = app.Flag(
 		FlagProcessOldInclude,
 		"DEPRECATED: Use --collector.process.include",
@@ -87,10 +182,11 @@ func newProcessCollectorFlags(app *kingpin.Application) {
 // NewProcessCollector ...
 func newProcessCollector() (Collector, error) {
 	const subsystem = "process"
+	logger := log.With(log.CollectorField, "process")
 
 	if *processOldExclude != "" {
 		if !processExcludeSet {
-			log.Warnln("msg", "--collector.process.blacklist is DEPRECATED and will be removed in a future release, use --collector.process.exclude")
+			logger.Warnln("msg", "--collector.process.blacklist is DEPRECATED and will be removed in a future release, use --collector.process.exclude")
 			*processExclude = *processOldExclude
 		} else {
 			return nil, errors.New("--collector.process.blacklist and --collector.process.exclude are mutually exclusive")
@@ -98,18 +194,38 @@ func newProcessCollector() (Collector, error) {
 	}
 	if *processOldInclude != "" {
 		if !processIncludeSet {
-			log.Warnln("msg", "--collector.process.whitelist is DEPRECATED and will be removed in a future release, use --collector.process.include")
+			logger.Warnln("msg", "--collector.process.whitelist is DEPRECATED and will be removed in a future release, use --collector.process.include")
 			*processInclude = *processOldInclude
 		} else {
 			return nil, errors.New("--collector.process.whitelist and --collector.process.include are mutually exclusive")
 		}
 	}
 
-	if *processInclude == ".*" && *processExclude == "" {
-		log.Warn("No filters specified for process collector. This will generate a very large number of metrics!")
+	if *processAggregateByName && (*processCmdline || *processOwner || *processConnections || *processHandleTypes || *processJobs) {
+		return nil, fmt.Errorf("--%s can't be combined with --%s, --%s, --%s, --%s or --%s", FlagProcessAggregate, FlagProcessCmdline, FlagProcessOwner, FlagProcessConnections, FlagProcessHandleTypes, FlagProcessJobs)
+	}
+
+	if *processInclude == ".*" && *processExclude == "" && !*processAggregateByName {
+		logger.Warn("No filters specified for process collector. This will generate a very large number of metrics!")
+	}
+
+	var groups *config.ProcessGroupConfig
+	if *processGroupsFile == "" {
+		groups = &config.ProcessGroupConfig{}
+	} else {
+		var err error
+		if groups, err = config.LoadProcessGroupConfig(*processGroupsFile); err != nil {
+			return nil, fmt.Errorf("failed to load process groups file %s: %w", *processGroupsFile, err)
+		}
+	}
+
+	var handleTypes *processHandleCounter
+	if *processHandleTypes {
+		handleTypes = &processHandleCounter{}
 	}
 
 	return &processCollector{
+		logger: logger,
 		StartTime: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "start_time"),
 			"Time of process start.",
@@ -200,8 +316,63 @@ func newProcessCollector() (Collector, error) {
 			[]string{"process", "process_id", "creating_process_id"},
 			nil,
 		),
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "info"),
+			"A metric with a constant '1' value labeled with the command line and the owner of the process, if enabled.",
+			[]string{"process", "process_id", "creating_process_id", "cmdline", "owner"},
+			nil,
+		),
+		TCPConnections: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "tcp_connections"),
+			"Number of TCP connections of the process by address family and state.",
+			[]string{"process", "process_id", "creating_process_id", "family", "state"},
+			nil,
+		),
+		UDPEndpoints: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "udp_endpoints"),
+			"Number of UDP endpoints of the process by address family.",
+			[]string{"process", "process_id", "creating_process_id", "family"},
+			nil,
+		),
+		ListeningPortInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "listening_port_info"),
+			"A metric with a constant '1' value labeled with a port the process listens on.",
+			[]string{"process", "process_id", "creating_process_id", "protocol", "family", "address", "port"},
+			nil,
+		),
+		HandlesByType: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "handles_by_type"),
+			"Number of handles the process has open by type of object.",
+			[]string{"process", "process_id", "creating_process_id", "type"},
+			nil,
+		),
+		InJob: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "in_job"),
+			"Whether the process runs in a job object (1) or not (0).",
+			[]string{"process", "process_id", "creating_process_id"},
+			nil,
+		),
+		ContainerInfo: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "container_info"),
+			"A metric with a constant '1' value labeled with the container the process runs in.",
+			[]string{"process", "process_id", "creating_process_id", "container_id"},
+			nil,
+		),
 		processIncludePattern: regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *processInclude)),
 		processExcludePattern: regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *processExclude)),
+		iisSource:             *processIISSource,
+		cmdline:               *processCmdline,
+		owner:                 *processOwner,
+		details:               newProcessDetailsCache(processCommandLine, newProcessOwners().owner),
+		aggregate:             *processAggregateByName,
+		byNameDescs:           newProcessAggregateDescs("process_name", "process"),
+		groups:                groups.Groups,
+		groupDescs:            newProcessAggregateDescs("process_group", "group"),
+		groupsUseCmdline:      groups.UsesCmdline(),
+		groupsUseOwner:        groups.UsesUser(),
+		connections:           *processConnections,
+		handleTypes:           handleTypes,
+		jobs:                  *processJobs,
 	}, nil
 }
 
@@ -244,20 +415,38 @@ type WorkerProcess struct {
 
 func (c *processCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	data := make([]perflibProcess, 0)
-	err := unmarshalObject(ctx.perfObjects["Process"], &data)
+	err := unmarshalObject(ctx.perfObjects["Process"], &data, c.logger)
 	if err != nil {
 		return err
 	}
 
 	var dst_wp []WorkerProcess
-	q_wp := queryAll(&dst_wp)
-	if err := wmi.QueryNamespace(q_wp, &dst_wp, "root\\WebAdministration"); err != nil {
-		log.Debugf("Could not query WebAdministration namespace for IIS worker processes: %v. Skipping", err)
+	if c.iisSource == "wmi" {
+		q_wp := queryAll(&dst_wp, c.logger)
+		if err := wmiQueryNamespace(q_wp, &dst_wp, "root\\WebAdministration"); err != nil {
+			c.logger.Debugf("Could not query WebAdministration namespace for IIS worker processes: %v. Skipping", err)
+		}
+	} else if dst_wp, err = nativeWorkerProcesses(); err != nil {
+		c.logger.Debugf("Could not list IIS worker processes: %v", err)
 	}
 
+	var extended *processExtended
+	if c.connections || c.handleTypes != nil || c.jobs {
+		extended = c.readExtended()
+	}
+
+	defer c.details.prune(time.Now())
+
+	byName := map[string]*processAggregate{}
+	groups := make([]processAggregate, len(c.groups))
 	for _, process := range data {
-		if process.Name == "_Total" ||
-			c.processExcludePattern.MatchString(process.Name) ||
+		if process.Name == "_Total" {
+			continue
+		}
+		if len(c.groups) > 0 {
+			c.addToGroup(groups, process)
+		}
+		if c.processExcludePattern.MatchString(process.Name) ||
 			!c.processIncludePattern.MatchString(process.Name) {
 			continue
 		}
@@ -273,6 +462,14 @@ func (c *processCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metr
 			}
 		}
 
+		if c.aggregate {
+			if byName[processName] == nil {
+				byName[processName] = &processAggregate{}
+			}
+			byName[processName].add(process)
+			continue
+		}
+
 		ch <- prometheus.MustNewConstMetric(
 			c.StartTime,
 			prometheus.GaugeValue,
@@ -471,7 +668,138 @@ func (c *processCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metr
 			pid,
 			cpid,
 		)
+
+		if c.cmdline || c.owner {
+			c.collectInfo(ch, processName, pid, cpid, process)
+		}
+		if extended != nil {
+			c.collectExtended(ch, extended, strings.Split(process.Name, "#")[0], processName, pid, cpid, uint32(process.IDProcess))
+		}
+	}
+
+	for processName, a := range byName {
+		c.byNameDescs.send(ch, processName, a)
+	}
+	for i := range c.groups {
+		c.groupDescs.send(ch, c.groups[i].Name, &groups[i])
 	}
 
 	return nil
 }
+
+// addToGroup adds the process to the first group it matches, if any.
+func (c *processCollector) addToGroup(groups []processAggregate, process perflibProcess) {
+	image := strings.Split(process.Name, "#")[0]
+	details := c.details.get(process, c.groupsUseCmdline, c.groupsUseOwner)
+	for i := range c.groups {
+		if c.groups[i].Match(image, details.cmdline, details.owner) {
+			groups[i].add(process)
+			return
+		}
+	}
+}
+
+// collectInfo sends the command line and the owner of the process, as far as
+// they are enabled and can be read, e.g. not for protected processes.
+func (c *processCollector) collectInfo(ch chan<- prometheus.Metric, processName, pid, cpid string, process perflibProcess) {
+	details := c.details.get(process, c.cmdline, c.owner)
+	if c.cmdline && details.cmdlineErr != nil {
+		c.logger.Debugf("Could not read the command line of process %s (%s): %v", processName, pid, details.cmdlineErr)
+	}
+	if c.owner && details.ownerErr != nil {
+		c.logger.Debugf("Could not read the owner of process %s (%s): %v", processName, pid, details.ownerErr)
+	}
+
+	var cmd, owner string
+	if c.cmdline {
+		cmd = details.cmdline
+	}
+	if c.owner {
+		owner = details.owner
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.Info,
+		prometheus.GaugeValue,
+		1.0,
+		processName,
+		pid,
+		cpid,
+		cmd,
+		owner,
+	)
+}
+
+// processDetailsExpiry is the time after which the details of processes no
+// longer seen are forgotten.
+const processDetailsExpiry = 5 * time.Minute
+
+// processKey identifies a process, whose ID may be reused once it exited.
+type processKey struct {
+	pid       uint32
+	startTime float64
+}
+
+// processDetails are the command line and the owner of a process, which
+// don't change while it runs, along with the errors reading them.
+type processDetails struct {
+	cmdline, owner         string
+	cmdlineErr, ownerErr   error
+	cmdlineRead, ownerRead bool
+	seen                   time.Time
+}
+
+// processDetailsCache reads the details of the processes once, rather than
+// on every scrape.
+type processDetailsCache struct {
+	mu        sync.Mutex
+	processes map[processKey]*processDetails
+
+	readCmdline func(pid uint32) (string, error)
+	readOwner   func(pid uint32) (string, error)
+	now         func() time.Time
+}
+
+func newProcessDetailsCache(readCmdline, readOwner func(pid uint32) (string, error)) *processDetailsCache {
+	return &processDetailsCache{
+		processes:   map[processKey]*processDetails{},
+		readCmdline: readCmdline,
+		readOwner:   readOwner,
+		now:         time.Now,
+	}
+}
+
+// get returns the details of the process, reading the command line and the
+// owner if requested and not read yet.
+func (c *processDetailsCache) get(process perflibProcess, cmdline, owner bool) processDetails {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := processKey{uint32(process.IDProcess), process.ElapsedTime}
+	d, ok := c.processes[key]
+	if !ok {
+		d = &processDetails{}
+		c.processes[key] = d
+	}
+	d.seen = c.now()
+	if cmdline && !d.cmdlineRead {
+		d.cmdline, d.cmdlineErr = c.readCmdline(key.pid)
+		d.cmdlineRead = true
+	}
+	if owner && !d.ownerRead {
+		d.owner, d.ownerErr = c.readOwner(key.pid)
+		d.ownerRead = true
+	}
+	return *d
+}
+
+// prune forgets the processes not seen for processDetailsExpiry.
+func (c *processDetailsCache) prune(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, d := range c.processes {
+		if now.Sub(d.seen) > processDetailsExpiry {
+			delete(c.processes, key)
+		}
+	}
+}
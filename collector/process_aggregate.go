@@ -0,0 +1,181 @@
+//go:build windows
+// +build windows
+
+package collector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// processAggregateDescs are the metrics of the processes aggregated by name
+// or by group, labeled with label instead of the process IDs.
+type processAggregateDescs struct {
+	NumProcs          *prometheus.Desc
+	StartTime         *prometheus.Desc
+	CPUTimeTotal      *prometheus.Desc
+	HandleCount       *prometheus.Desc
+	IOBytesTotal      *prometheus.Desc
+	IOOperationsTotal *prometheus.Desc
+	PageFaultsTotal   *prometheus.Desc
+	PageFileBytes     *prometheus.Desc
+	PoolBytes         *prometheus.Desc
+	PrivateBytes      *prometheus.Desc
+	ThreadCount       *prometheus.Desc
+	VirtualBytes      *prometheus.Desc
+	WorkingSetPrivate *prometheus.Desc
+	WorkingSet        *prometheus.Desc
+}
+
+func newProcessAggregateDescs(subsystem, label string) processAggregateDescs {
+	return processAggregateDescs{
+		NumProcs: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "num_procs"),
+			"Number of processes.",
+			[]string{label},
+			nil,
+		),
+		StartTime: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "start_time"),
+			"Time of start of the oldest process.",
+			[]string{label},
+			nil,
+		),
+		CPUTimeTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "cpu_time_total"),
+			"Returns elapsed time that all of the threads of the processes used the processor to execute instructions by mode (privileged, user).",
+			[]string{label, "mode"},
+			nil,
+		),
+		HandleCount: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "handles"),
+			"Total number of handles the processes have open.",
+			[]string{label},
+			nil,
+		),
+		IOBytesTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "io_bytes_total"),
+			"Bytes issued to I/O operations in different modes (read, write, other).",
+			[]string{label, "mode"},
+			nil,
+		),
+		IOOperationsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "io_operations_total"),
+			"I/O operations issued in different modes (read, write, other).",
+			[]string{label, "mode"},
+			nil,
+		),
+		PageFaultsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "page_faults_total"),
+			"Page faults by the threads executing in the processes.",
+			[]string{label},
+			nil,
+		),
+		PageFileBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "page_file_bytes"),
+			"Current number of bytes the processes have used in the paging file(s).",
+			[]string{label},
+			nil,
+		),
+		PoolBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "pool_bytes"),
+			"Pool Bytes is the last observed number of bytes in the paged or nonpaged pool.",
+			[]string{label, "pool"},
+			nil,
+		),
+		PrivateBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "private_bytes"),
+			"Current number of bytes the processes have allocated that cannot be shared with other processes.",
+			[]string{label},
+			nil,
+		),
+		ThreadCount: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "threads"),
+			"Number of threads currently active in the processes.",
+			[]string{label},
+			nil,
+		),
+		VirtualBytes: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "virtual_bytes"),
+			"Current size, in bytes, of the virtual address space that the processes are using.",
+			[]string{label},
+			nil,
+		),
+		WorkingSetPrivate: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "working_set_private_bytes"),
+			"Size of the working sets, in bytes, that is use for the processes only and not shared nor shareable by other processes.",
+			[]string{label},
+			nil,
+		),
+		WorkingSet: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "working_set_bytes"),
+			"Number of bytes in the working sets of the processes.",
+			[]string{label},
+			nil,
+		),
+	}
+}
+
+// processAggregate sums the counters of processes. The peak and priority
+// counters aren't summed, as their sum is meaningless.
+type processAggregate struct {
+	numProcs float64
+	// startTime is the start time of the oldest process.
+	startTime float64
+	sum       perflibProcess
+}
+
+func (a *processAggregate) add(p perflibProcess) {
+	if a.numProcs == 0 || p.ElapsedTime < a.startTime {
+		a.startTime = p.ElapsedTime
+	}
+	a.numProcs++
+
+	a.sum.PercentPrivilegedTime += p.PercentPrivilegedTime
+	a.sum.PercentUserTime += p.PercentUserTime
+	a.sum.HandleCount += p.HandleCount
+	a.sum.IOOtherBytesPerSec += p.IOOtherBytesPerSec
+	a.sum.IOOtherOperationsPerSec += p.IOOtherOperationsPerSec
+	a.sum.IOReadBytesPerSec += p.IOReadBytesPerSec
+	a.sum.IOReadOperationsPerSec += p.IOReadOperationsPerSec
+	a.sum.IOWriteBytesPerSec += p.IOWriteBytesPerSec
+	a.sum.IOWriteOperationsPerSec += p.IOWriteOperationsPerSec
+	a.sum.PageFaultsPerSec += p.PageFaultsPerSec
+	a.sum.PageFileBytes += p.PageFileBytes
+	a.sum.PoolNonpagedBytes += p.PoolNonpagedBytes
+	a.sum.PoolPagedBytes += p.PoolPagedBytes
+	a.sum.PrivateBytes += p.PrivateBytes
+	a.sum.ThreadCount += p.ThreadCount
+	a.sum.VirtualBytes += p.VirtualBytes
+	a.sum.WorkingSetPrivate += p.WorkingSetPrivate
+	a.sum.WorkingSet += p.WorkingSet
+}
+
+// send sends the metrics of the aggregate, the start time only if it has
+// processes.
+func (d *processAggregateDescs) send(ch chan<- prometheus.Metric, label string, a *processAggregate) {
+	ch <- prometheus.MustNewConstMetric(d.NumProcs, prometheus.GaugeValue, a.numProcs, label)
+	if a.numProcs > 0 {
+		ch <- prometheus.MustNewConstMetric(d.StartTime, prometheus.GaugeValue, a.startTime, label)
+	}
+
+	ch <- prometheus.MustNewConstMetric(d.CPUTimeTotal, prometheus.CounterValue, a.sum.PercentPrivilegedTime, label, "privileged")
+	ch <- prometheus.MustNewConstMetric(d.CPUTimeTotal, prometheus.CounterValue, a.sum.PercentUserTime, label, "user")
+	ch <- prometheus.MustNewConstMetric(d.HandleCount, prometheus.GaugeValue, a.sum.HandleCount, label)
+
+	ch <- prometheus.MustNewConstMetric(d.IOBytesTotal, prometheus.CounterValue, a.sum.IOOtherBytesPerSec, label, "other")
+	ch <- prometheus.MustNewConstMetric(d.IOOperationsTotal, prometheus.CounterValue, a.sum.IOOtherOperationsPerSec, label, "other")
+	ch <- prometheus.MustNewConstMetric(d.IOBytesTotal, prometheus.CounterValue, a.sum.IOReadBytesPerSec, label, "read")
+	ch <- prometheus.MustNewConstMetric(d.IOOperationsTotal, prometheus.CounterValue, a.sum.IOReadOperationsPerSec, label, "read")
+	ch <- prometheus.MustNewConstMetric(d.IOBytesTotal, prometheus.CounterValue, a.sum.IOWriteBytesPerSec, label, "write")
+	ch <- prometheus.MustNewConstMetric(d.IOOperationsTotal, prometheus.CounterValue, a.sum.IOWriteOperationsPerSec, label, "write")
+
+	ch <- prometheus.MustNewConstMetric(d.PageFaultsTotal, prometheus.CounterValue, a.sum.PageFaultsPerSec, label)
+	ch <- prometheus.MustNewConstMetric(d.PageFileBytes, prometheus.GaugeValue, a.sum.PageFileBytes, label)
+	ch <- prometheus.MustNewConstMetric(d.PoolBytes, prometheus.GaugeValue, a.sum.PoolNonpagedBytes, label, "nonpaged")
+	ch <- prometheus.MustNewConstMetric(d.PoolBytes, prometheus.GaugeValue, a.sum.PoolPagedBytes, label, "paged")
+	ch <- prometheus.MustNewConstMetric(d.PrivateBytes, prometheus.GaugeValue, a.sum.PrivateBytes, label)
+	ch <- prometheus.MustNewConstMetric(d.ThreadCount, prometheus.GaugeValue, a.sum.ThreadCount, label)
+	ch <- prometheus.MustNewConstMetric(d.VirtualBytes, prometheus.GaugeValue, a.sum.VirtualBytes, label)
+	ch <- prometheus.MustNewConstMetric(d.WorkingSetPrivate, prometheus.GaugeValue, a.sum.WorkingSetPrivate, label)
+	ch <- prometheus.MustNewConstMetric(d.WorkingSet, prometheus.GaugeValue, a.sum.WorkingSet, label)
+}
@@ -0,0 +1,314 @@
+//go:build windows
+// +build windows
+
+package collector
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/Microsoft/hcsshim"
+	"github.com/prometheus-community/windows_exporter/headers/iphlpapi"
+	"github.com/prometheus-community/windows_exporter/headers/ntdll"
+	"github.com/prometheus-community/windows_exporter/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/windows"
+)
+
+var procIsProcessInJob = windows.NewLazySystemDLL("kernel32.dll").NewProc("IsProcessInJob")
+
+type processSocketKey struct {
+	family string
+	state  string
+}
+
+type processListener struct {
+	protocol string
+	family   string
+	address  string
+	port     string
+}
+
+// processSockets counts the sockets of the processes, by process ID.
+type processSockets struct {
+	tcp       map[uint32]map[processSocketKey]float64
+	udp       map[uint32]map[string]float64
+	listeners map[uint32]map[processListener]bool
+}
+
+func newProcessSockets() *processSockets {
+	return &processSockets{
+		tcp:       map[uint32]map[processSocketKey]float64{},
+		udp:       map[uint32]map[string]float64{},
+		listeners: map[uint32]map[processListener]bool{},
+	}
+}
+
+func (s *processSockets) addListener(pid uint32, l processListener) {
+	if s.listeners[pid] == nil {
+		s.listeners[pid] = map[processListener]bool{}
+	}
+	s.listeners[pid][l] = true
+}
+
+func (s *processSockets) addTCP(family string, connections []iphlpapi.TCPConnection) {
+	for _, conn := range connections {
+		if s.tcp[conn.PID] == nil {
+			s.tcp[conn.PID] = map[processSocketKey]float64{}
+		}
+		state, ok := iphlpapi.TCPStates[conn.State]
+		if !ok {
+			state = "unknown"
+		}
+		s.tcp[conn.PID][processSocketKey{family, state}]++
+		if conn.State == iphlpapi.MIB_TCP_STATE_LISTEN {
+			s.addListener(conn.PID, processListener{"tcp", family, conn.LocalAddr.String(), strconv.Itoa(int(conn.LocalPort))})
+		}
+	}
+}
+
+// addUDP adds the UDP endpoints, which are all listening.
+func (s *processSockets) addUDP(family string, endpoints []iphlpapi.UDPEndpoint) {
+	for _, endpoint := range endpoints {
+		if s.udp[endpoint.PID] == nil {
+			s.udp[endpoint.PID] = map[string]float64{}
+		}
+		s.udp[endpoint.PID][family]++
+		s.addListener(endpoint.PID, processListener{"udp", family, endpoint.LocalAddr.String(), strconv.Itoa(int(endpoint.LocalPort))})
+	}
+}
+
+// readProcessSockets reads the TCP connections and the UDP endpoints of both
+// families.
+func readProcessSockets() (*processSockets, error) {
+	s := newProcessSockets()
+	for _, family := range []struct {
+		af   uint32
+		name string
+	}{{windows.AF_INET, "ipv4"}, {windows.AF_INET6, "ipv6"}} {
+		connections, err := iphlpapi.GetTCPConnections(family.af)
+		if err != nil {
+			return nil, err
+		}
+		s.addTCP(family.name, connections)
+
+		endpoints, err := iphlpapi.GetUDPEndpoints(family.af)
+		if err != nil {
+			return nil, err
+		}
+		s.addUDP(family.name, endpoints)
+	}
+	return s, nil
+}
+
+// processHandleCounter counts the handles of the processes by type, caching
+// the names of the types, which are only read again for unknown types.
+type processHandleCounter struct {
+	mu    sync.Mutex
+	names map[uint16]string
+}
+
+// count returns the number of handles of the processes, by process ID and
+// type.
+func (t *processHandleCounter) count(logger log.Logger) (map[uint32]map[string]float64, error) {
+	handles, err := ntdll.Handles()
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	counts := map[uint32]map[string]float64{}
+	for _, h := range handles {
+		name, ok := t.names[h.TypeIndex]
+		if !ok {
+			if names, err := ntdll.ObjectTypeNames(); err != nil {
+				logger.Debugf("Could not read the object types: %v", err)
+			} else {
+				t.names = names
+			}
+			if name, ok = t.names[h.TypeIndex]; !ok {
+				name = "unknown"
+				// Don't read the types again for the other handles.
+				if t.names == nil {
+					t.names = map[uint16]string{}
+				}
+				t.names[h.TypeIndex] = name
+			}
+		}
+		if counts[h.ProcessID] == nil {
+			counts[h.ProcessID] = map[string]float64{}
+		}
+		counts[h.ProcessID][name]++
+	}
+	return counts, nil
+}
+
+type processContainer struct {
+	id    string
+	image string
+}
+
+// readProcessContainers returns the containers of the processes by process
+// ID, along with the image of the processes.
+func readProcessContainers() (map[uint32]processContainer, error) {
+	containers, err := hcsshim.GetContainers(hcsshim.ComputeSystemQuery{Types: []string{"Container"}})
+	if err != nil {
+		return nil, err
+	}
+
+	processes := map[uint32]processContainer{}
+	for _, details := range containers {
+		container, err := hcsshim.OpenContainer(details.ID)
+		if err != nil {
+			continue
+		}
+		items, err := container.ProcessList()
+		container.Close() //nolint:errcheck
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			processes[item.ProcessId] = processContainer{getContainerIdWithPrefix(details), item.ImageName}
+		}
+	}
+	return processes, nil
+}
+
+// processInJob returns whether the process runs in a job object.
+func processInJob(pid uint32) (bool, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return false, err
+	}
+	defer windows.CloseHandle(h) //nolint:errcheck
+
+	var inJob int32
+	if r1, _, err := procIsProcessInJob.Call(uintptr(h), 0, uintptr(unsafe.Pointer(&inJob))); r1 == 0 {
+		return false, err
+	}
+	return inJob != 0, nil
+}
+
+// processExtended is the data read once per scrape for all the processes.
+type processExtended struct {
+	sockets     *processSockets
+	handles     map[uint32]map[string]float64
+	containers  map[uint32]processContainer
+	readHandles bool
+	readJobs    bool
+}
+
+// readExtended reads the data enabled by the flags, logging the errors.
+func (c *processCollector) readExtended() *processExtended {
+	var e processExtended
+	var err error
+	if c.connections {
+		if e.sockets, err = readProcessSockets(); err != nil {
+			c.logger.Warnf("Could not read the connections of the processes: %v", err)
+		}
+	}
+	if c.handleTypes != nil {
+		if e.handles, err = c.handleTypes.count(c.logger); err != nil {
+			c.logger.Warnf("Could not read the handles of the processes: %v", err)
+		}
+		e.readHandles = err == nil
+	}
+	if c.jobs {
+		// The containers may not be available, e.g. without the Containers
+		// feature.
+		if e.containers, err = readProcessContainers(); err != nil {
+			c.logger.Debugf("Could not list the containers: %v", err)
+		}
+		e.readJobs = true
+	}
+	return &e
+}
+
+// collectExtended sends the extended metrics of the process, given its image
+// name, without the application pool of IIS worker processes.
+func (c *processCollector) collectExtended(ch chan<- prometheus.Metric, e *processExtended, image, processName, pid, cpid string, processID uint32) {
+	if e.sockets != nil {
+		for key, count := range e.sockets.tcp[processID] {
+			ch <- prometheus.MustNewConstMetric(
+				c.TCPConnections,
+				prometheus.GaugeValue,
+				count,
+				processName,
+				pid,
+				cpid,
+				key.family,
+				key.state,
+			)
+		}
+		for family, count := range e.sockets.udp[processID] {
+			ch <- prometheus.MustNewConstMetric(
+				c.UDPEndpoints,
+				prometheus.GaugeValue,
+				count,
+				processName,
+				pid,
+				cpid,
+				family,
+			)
+		}
+		for l := range e.sockets.listeners[processID] {
+			ch <- prometheus.MustNewConstMetric(
+				c.ListeningPortInfo,
+				prometheus.GaugeValue,
+				1.0,
+				processName,
+				pid,
+				cpid,
+				l.protocol,
+				l.family,
+				l.address,
+				l.port,
+			)
+		}
+	}
+
+	if e.readHandles {
+		for handleType, count := range e.handles[processID] {
+			ch <- prometheus.MustNewConstMetric(
+				c.HandlesByType,
+				prometheus.GaugeValue,
+				count,
+				processName,
+				pid,
+				cpid,
+				handleType,
+			)
+		}
+	}
+
+	if e.readJobs {
+		if inJob, err := processInJob(processID); err != nil {
+			c.logger.Debugf("Could not read the job of process %s (%s): %v", processName, pid, err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(
+				c.InJob,
+				prometheus.GaugeValue,
+				boolToFloat(inJob),
+				processName,
+				pid,
+				cpid,
+			)
+		}
+		// The processes of Hyper-V isolated containers run in their own
+		// virtual machine, so their IDs may match unrelated processes.
+		if container, ok := e.containers[processID]; ok && strings.EqualFold(strings.TrimSuffix(strings.ToLower(container.image), ".exe"), image) {
+			ch <- prometheus.MustNewConstMetric(
+				c.ContainerInfo,
+				prometheus.GaugeValue,
+				1.0,
+				processName,
+				pid,
+				cpid,
+				container.id,
+			)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+//go:build windows
+// +build windows
+
+package collector
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/prometheus-community/windows_exporter/cmdline"
+	"golang.org/x/sys/windows"
+)
+
+// nativeWorkerProcesses lists the IIS worker processes with a Toolhelp
+// snapshot, reading their application pool from their command line, given
+// as `w3wp.exe -ap "<pool>"`. Unlike the WebAdministration WMI namespace, it
+// doesn't need the IIS WMI provider to be installed.
+func nativeWorkerProcesses() ([]WorkerProcess, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer windows.CloseHandle(snapshot) //nolint:errcheck
+
+	var workers []WorkerProcess
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		if !strings.EqualFold(windows.UTF16ToString(entry.ExeFile[:]), "w3wp.exe") {
+			continue
+		}
+		// The process may have exited since the snapshot.
+		cmd, cmdErr := processCommandLine(entry.ProcessID)
+		if cmdErr != nil {
+			continue
+		}
+		if pool, ok := cmdline.Value(cmdline.Split(cmd), "-ap"); ok {
+			workers = append(workers, WorkerProcess{AppPoolName: pool, ProcessId: uint64(entry.ProcessID)})
+		}
+	}
+	if !errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+		return workers, err
+	}
+	return workers, nil
+}
+
+// processCommandLine reads the command line of a process, which needs
+// Windows 8.1 or later.
+func processCommandLine(pid uint32) (string, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(h) //nolint:errcheck
+
+	// The UNICODE_STRING is followed by the command line it points to.
+	buf := make([]byte, 1024)
+	for {
+		var size uint32
+		err := windows.NtQueryInformationProcess(h, windows.ProcessCommandLineInformation, unsafe.Pointer(&buf[0]), uint32(len(buf)), &size)
+		if err == nil {
+			return (*windows.NTUnicodeString)(unsafe.Pointer(&buf[0])).String(), nil
+		}
+		if (err != windows.STATUS_INFO_LENGTH_MISMATCH && err != windows.STATUS_BUFFER_TOO_SMALL) || size <= uint32(len(buf)) {
+			return "", err
+		}
+		buf = make([]byte, size)
+	}
+}
+
+// processOwners resolves the owners of processes, caching the accounts of
+// their SIDs, whose lookup may query a domain controller.
+type processOwners struct {
+	mu       sync.Mutex
+	accounts map[string]string
+}
+
+func newProcessOwners() *processOwners {
+	return &processOwners{accounts: map[string]string{}}
+}
+
+// owner returns the account running a process, as DOMAIN\user.
+func (o *processOwners) owner(pid uint32) (string, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", err
+	}
+	defer windows.CloseHandle(h) //nolint:errcheck
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(h, windows.TOKEN_QUERY, &token); err != nil {
+		return "", err
+	}
+	defer token.Close()
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", err
+	}
+
+	sid := user.User.Sid.String()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if account, ok := o.accounts[sid]; ok {
+		return account, nil
+	}
+	account, domain, _, err := user.User.Sid.LookupAccount("")
+	if err != nil {
+		// Report the SID of accounts that can't be resolved, e.g. deleted.
+		account, domain = sid, ""
+	}
+	if domain != "" {
+		account = domain + `\` + account
+	}
+	o.accounts[sid] = account
+	return account, nil
+}
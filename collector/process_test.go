@@ -1,7 +1,16 @@
 package collector
 
 import (
+	"errors"
+	"fmt"
+	"net"
 	"testing"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/leoluk/perflib_exporter/perflib"
+	"github.com/prometheus-community/windows_exporter/headers/iphlpapi"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 func BenchmarkProcessCollector(b *testing.B) {
@@ -12,3 +21,209 @@ func BenchmarkProcessCollector(b *testing.B) {
 	// No context name required as collector source is WMI
 	benchmarkCollector(b, "", newProcessCollector)
 }
+
+func TestProcessAggregate(t *testing.T) {
+	var a processAggregate
+	a.add(perflibProcess{Name: "msbuild", ElapsedTime: 200, PercentUserTime: 1.5, HandleCount: 100, WorkingSet: 1024})
+	a.add(perflibProcess{Name: "msbuild#1", ElapsedTime: 100, PercentUserTime: 2.5, HandleCount: 50, WorkingSet: 2048})
+	a.add(perflibProcess{Name: "msbuild#2", ElapsedTime: 300, PercentUserTime: 1, HandleCount: 10, WorkingSet: 512})
+
+	if a.numProcs != 3 {
+		t.Errorf("expected 3 processes, got %v", a.numProcs)
+	}
+	if a.startTime != 100 {
+		t.Errorf("expected the start time of the oldest process, got %v", a.startTime)
+	}
+	if a.sum.PercentUserTime != 5 || a.sum.HandleCount != 160 || a.sum.WorkingSet != 3584 {
+		t.Errorf("unexpected sums %+v", a.sum)
+	}
+}
+
+// processPerfObject returns a Process perflib object holding the instances,
+// given as counter values by name.
+func processPerfObject(instances map[string]map[string]int64) *perflib.PerfObject {
+	obj := &perflib.PerfObject{Name: "Process", Frequency: 1}
+	for name, counters := range instances {
+		instance := &perflib.PerfInstance{Name: name}
+		for counter, value := range counters {
+			instance.Counters = append(instance.Counters, &perflib.PerfCounter{Value: value, Def: &perflib.PerfCounterDef{Name: counter}})
+		}
+		obj.Instances = append(obj.Instances, instance)
+	}
+	return obj
+}
+
+// scrapeContextCollector collects c with the scrape context ctx, whatever
+// the context it is given.
+type scrapeContextCollector struct {
+	c   Collector
+	ctx *ScrapeContext
+}
+
+func (sc scrapeContextCollector) Collect(_ *ScrapeContext, ch chan<- prometheus.Metric) error {
+	return sc.c.Collect(sc.ctx, ch)
+}
+
+func TestProcessCollectorAggregate(t *testing.T) {
+	ctx := &ScrapeContext{perfObjects: map[string]*perflib.PerfObject{
+		"Process": processPerfObject(map[string]map[string]int64{
+			"msbuild":   {"ID Process": 100, "Creating Process ID": 1, "Elapsed Time": 200, "Working Set": 1024, "Thread Count": 4},
+			"msbuild#1": {"ID Process": 101, "Creating Process ID": 1, "Elapsed Time": 100, "Working Set": 2048, "Thread Count": 2},
+			"_Total":    {"Working Set": 3072},
+		}),
+	}}
+
+	for _, aggregate := range []bool{false, true} {
+		app := kingpin.New("test", "")
+		newProcessCollectorFlags(app)
+		args := []string{"--collector.process.include=.+", "--collector.process.iis-source=native"}
+		if aggregate {
+			args = append(args, "--collector.process.aggregate")
+		}
+		if _, err := app.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		c, err := newProcessCollector()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		values := collectValues(t, scrapeContextCollector{c, ctx})
+		perProcess := map[string]float64{
+			`windows_process_working_set_bytes{creating_process_id=1,process=msbuild,process_id=100}`: 1024,
+			`windows_process_working_set_bytes{creating_process_id=1,process=msbuild,process_id=101}`: 2048,
+			`windows_process_start_time{creating_process_id=1,process=msbuild,process_id=101}`:        100,
+		}
+		byName := map[string]float64{
+			`windows_process_name_num_procs{process=msbuild}`:         2,
+			`windows_process_name_working_set_bytes{process=msbuild}`: 3072,
+			`windows_process_name_threads{process=msbuild}`:           6,
+			`windows_process_name_start_time{process=msbuild}`:        100,
+		}
+		expected, unexpected := perProcess, byName
+		if aggregate {
+			expected, unexpected = byName, perProcess
+		}
+		for key, value := range expected {
+			if v, ok := values[key]; !ok || v != value {
+				t.Errorf("aggregate %v: expected %s to be %v, got %v (present: %v)", aggregate, key, value, v, ok)
+			}
+		}
+		for key := range unexpected {
+			if _, ok := values[key]; ok {
+				t.Errorf("aggregate %v: unexpected %s", aggregate, key)
+			}
+		}
+	}
+}
+
+func TestProcessCollectorAggregateFlags(t *testing.T) {
+	for _, flag := range []string{FlagProcessCmdline, FlagProcessOwner, FlagProcessConnections, FlagProcessHandleTypes, FlagProcessJobs} {
+		app := kingpin.New("test", "")
+		newProcessCollectorFlags(app)
+		if _, err := app.Parse([]string{"--" + FlagProcessAggregate, "--" + flag}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := newProcessCollector(); err == nil {
+			t.Errorf("expected --%s to be rejected with --%s", flag, FlagProcessAggregate)
+		}
+	}
+}
+
+func TestProcessDetailsCache(t *testing.T) {
+	reads := map[string]int{}
+	c := newProcessDetailsCache(
+		func(pid uint32) (string, error) {
+			reads["cmdline"]++
+			return fmt.Sprintf("app.exe --id %d", pid), nil
+		},
+		func(pid uint32) (string, error) {
+			reads["owner"]++
+			return "", errors.New("access denied")
+		},
+	)
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	process := perflibProcess{IDProcess: 100, ElapsedTime: 1000}
+	for i := 0; i < 3; i++ {
+		d := c.get(process, true, true)
+		if d.cmdline != "app.exe --id 100" || d.ownerErr == nil {
+			t.Fatalf("unexpected details %+v", d)
+		}
+	}
+	if reads["cmdline"] != 1 || reads["owner"] != 1 {
+		t.Errorf("expected the details to be read once, got %v", reads)
+	}
+
+	// A new process reusing the ID is read again.
+	c.get(perflibProcess{IDProcess: 100, ElapsedTime: 2000}, true, false)
+	if reads["cmdline"] != 2 || reads["owner"] != 1 {
+		t.Errorf("expected the command line of the new process only to be read, got %v", reads)
+	}
+
+	now = now.Add(processDetailsExpiry / 2)
+	c.get(process, true, true)
+	c.prune(now.Add(processDetailsExpiry/2 + time.Second))
+	if len(c.processes) != 1 {
+		t.Errorf("expected the process not seen to be forgotten, got %v", c.processes)
+	}
+}
+
+func TestProcessSockets(t *testing.T) {
+	s := newProcessSockets()
+	s.addTCP("ipv4", []iphlpapi.TCPConnection{
+		{State: iphlpapi.MIB_TCP_STATE_LISTEN, LocalAddr: net.IPv4zero, LocalPort: 443, PID: 100},
+		{State: iphlpapi.MIB_TCP_STATE_ESTAB, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 443, RemotePort: 50000, PID: 100},
+		{State: iphlpapi.MIB_TCP_STATE_ESTAB, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 443, RemotePort: 50001, PID: 100},
+		{State: iphlpapi.MIB_TCP_STATE_TIME_WAIT, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 50002, PID: 0},
+	})
+	s.addTCP("ipv6", []iphlpapi.TCPConnection{
+		{State: iphlpapi.MIB_TCP_STATE_LISTEN, LocalAddr: net.IPv6zero, LocalPort: 443, PID: 100},
+	})
+	s.addUDP("ipv4", []iphlpapi.UDPEndpoint{
+		{LocalAddr: net.IPv4zero, LocalPort: 53, PID: 200},
+		{LocalAddr: net.IPv4zero, LocalPort: 53, PID: 200},
+	})
+
+	tcp := map[processSocketKey]float64{
+		{"ipv4", "listen"}:      1,
+		{"ipv4", "established"}: 2,
+		{"ipv6", "listen"}:      1,
+	}
+	if len(s.tcp[100]) != len(tcp) {
+		t.Errorf("expected TCP connections %v, got %v", tcp, s.tcp[100])
+	}
+	for key, count := range tcp {
+		if s.tcp[100][key] != count {
+			t.Errorf("expected %v %v connections, got %v", count, key, s.tcp[100][key])
+		}
+	}
+	if s.tcp[0][processSocketKey{"ipv4", "time_wait"}] != 1 {
+		t.Errorf("expected the connections without process to be counted, got %v", s.tcp[0])
+	}
+	if s.udp[200]["ipv4"] != 2 {
+		t.Errorf("expected 2 UDP endpoints, got %v", s.udp[200])
+	}
+
+	listeners := map[uint32]map[processListener]bool{
+		100: {
+			{"tcp", "ipv4", "0.0.0.0", "443"}: true,
+			{"tcp", "ipv6", "::", "443"}:      true,
+		},
+		// The endpoints bound twice are listed once.
+		200: {
+			{"udp", "ipv4", "0.0.0.0", "53"}: true,
+		},
+	}
+	for pid, expected := range listeners {
+		if len(s.listeners[pid]) != len(expected) {
+			t.Errorf("expected listeners %v of process %d, got %v", expected, pid, s.listeners[pid])
+		}
+		for l := range expected {
+			if !s.listeners[pid][l] {
+				t.Errorf("expected listener %v of process %d", l, pid)
+			}
+		}
+	}
+}
@@ -4,7 +4,10 @@
 package collector
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,28 +42,81 @@ var (
 		nil,
 		nil,
 	)
+
+	// collectorErrors counts the failures of the collectors on the local
+	// machine across scrapes.
+	collectorErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "exporter",
+			Name:      "collector_errors_total",
+			Help:      "windows_exporter: Number of failed collections, by kind of failure.",
+		},
+		[]string{"collector", "kind"},
+	)
+)
+
+// Label values of windows_exporter_collector_errors_total.
+const (
+	collectorErrorFailed         = "error"
+	collectorErrorTimeout        = "timeout"
+	collectorErrorWMITimeout     = "wmi_timeout"
+	collectorErrorWMICircuitOpen = "wmi_circuit_open"
+	collectorErrorWMIBusy        = "wmi_busy"
 )
 
+var collectorErrorKinds = []string{
+	collectorErrorFailed,
+	collectorErrorTimeout,
+	collectorErrorWMITimeout,
+	collectorErrorWMICircuitOpen,
+	collectorErrorWMIBusy,
+}
+
 // Prometheus implements prometheus.Collector for a set of Windows collectors.
 type Prometheus struct {
 	maxScrapeDuration time.Duration
 	collectors        map[string]Collector
+	wmi               *wmiClient
+	// target is the remote host to scrape, empty for the local machine.
+	target string
+	// ctx is cancelled once the scrape is abandoned, nil if it never is.
+	ctx context.Context
 }
 
 // NewPrometheus returns a new Prometheus where the set of collectors must
 // return metrics within the given timeout.
 func NewPrometheus(timeout time.Duration, cs map[string]Collector) *Prometheus {
+	// Export the error counters of the collectors before their first
+	// failure, so that their increase can be alerted on.
+	for name := range cs {
+		for _, kind := range collectorErrorKinds {
+			collectorErrors.WithLabelValues(name, kind)
+		}
+	}
 	return &Prometheus{
 		maxScrapeDuration: timeout,
 		collectors:        cs,
+		wmi:               defaultWMIClient,
 	}
 }
 
+// WithContext sets the context of the scrape. Collectors still running are
+// abandoned once it is cancelled, as they are when they time out.
+func (coll *Prometheus) WithContext(ctx context.Context) *Prometheus {
+	coll.ctx = ctx
+	return coll
+}
+
 // Describe sends all the descriptors of the collectors included to
 // the provided channel.
 func (coll *Prometheus) Describe(ch chan<- *prometheus.Desc) {
 	ch <- scrapeDurationDesc
 	ch <- scrapeSuccessDesc
+	if coll.target == "" {
+		collectorErrors.Describe(ch)
+	}
+	coll.wmi.Describe(ch)
 }
 
 type collectorOutcome int
@@ -74,21 +130,12 @@ const (
 // Collect sends the collected metrics from each of the collectors to
 // prometheus.
 func (coll *Prometheus) Collect(ch chan<- prometheus.Metric) {
-	t := time.Now()
-	cs := make([]string, 0, len(coll.collectors))
-	for name := range coll.collectors {
-		cs = append(cs, name)
-	}
-	scrapeContext, err := PrepareScrapeContext(cs)
-	ch <- prometheus.MustNewConstMetric(
-		snapshotDuration,
-		prometheus.GaugeValue,
-		time.Since(t).Seconds(),
-	)
+	scrapeContext, err := coll.prepareScrapeContext(ch)
 	if err != nil {
 		ch <- prometheus.NewInvalidMetric(scrapeSuccessDesc, fmt.Errorf("failed to prepare scrape: %v", err))
 		return
 	}
+	scrapeContext.ctx = coll.ctx
 
 	wg := sync.WaitGroup{}
 	wg.Add(len(coll.collectors))
@@ -133,6 +180,7 @@ func (coll *Prometheus) Collect(ch chan<- prometheus.Metric) {
 	select {
 	case <-allDone:
 	case <-time.After(coll.maxScrapeDuration):
+	case <-scrapeContext.context().Done():
 	}
 
 	l.Lock()
@@ -144,6 +192,9 @@ func (coll *Prometheus) Collect(ch chan<- prometheus.Metric) {
 		if outcome == pending {
 			timeoutValue = 1.0
 			remainingCollectorNames = append(remainingCollectorNames, name)
+			if coll.target == "" {
+				collectorErrors.WithLabelValues(name, collectorErrorTimeout).Inc()
+			}
 		}
 		if outcome == success {
 			successValue = 1.0
@@ -164,12 +215,46 @@ func (coll *Prometheus) Collect(ch chan<- prometheus.Metric) {
 	}
 
 	if len(remainingCollectorNames) > 0 {
-		log.Warn("Collection timed out, still waiting for ", remainingCollectorNames)
+		logger := log.With("remaining", strings.Join(remainingCollectorNames, ","))
+		if coll.target != "" {
+			logger = logger.With(log.InstanceField, coll.target)
+		}
+		if scrapeContext.context().Err() != nil {
+			logger.Warn("Scrape cancelled, abandoning collectors")
+		} else {
+			logger.Warn("Collection timed out, still waiting for collectors")
+		}
+	}
+
+	if coll.target == "" {
+		collectorErrors.Collect(ch)
 	}
+	coll.wmi.Collect(ch)
 
 	l.Unlock()
 }
 
+// prepareScrapeContext creates the ScrapeContext of a single scrape. Perflib
+// is only available on the local machine, remote scrapes rely on WMI alone.
+func (coll *Prometheus) prepareScrapeContext(ch chan<- prometheus.Metric) (*ScrapeContext, error) {
+	if coll.target != "" {
+		return &ScrapeContext{wmi: coll.wmi, target: coll.target}, nil
+	}
+
+	t := time.Now()
+	cs := make([]string, 0, len(coll.collectors))
+	for name := range coll.collectors {
+		cs = append(cs, name)
+	}
+	scrapeContext, err := PrepareScrapeContext(cs)
+	ch <- prometheus.MustNewConstMetric(
+		snapshotDuration,
+		prometheus.GaugeValue,
+		time.Since(t).Seconds(),
+	)
+	return scrapeContext, err
+}
+
 func execute(name string, c Collector, ctx *ScrapeContext, ch chan<- prometheus.Metric) collectorOutcome {
 	t := time.Now()
 	err := c.Collect(ctx, ch)
@@ -181,10 +266,30 @@ func execute(name string, c Collector, ctx *ScrapeContext, ch chan<- prometheus.
 		name,
 	)
 
+	logger := log.WithFields(log.Fields{log.CollectorField: name, log.DurationField: duration})
+	if ctx != nil && ctx.target != "" {
+		logger = logger.With(log.InstanceField, ctx.target)
+	}
 	if err != nil {
-		log.Errorf("collector %s failed after %fs: %s", name, duration, err)
+		if ctx == nil || ctx.target == "" {
+			collectorErrors.WithLabelValues(name, collectorErrorKind(err)).Inc()
+		}
+		logger.WithError(err).Error("Collector failed")
 		return failed
 	}
-	log.Debugf("collector %s succeeded after %fs.", name, duration)
+	logger.Debug("Collector succeeded")
 	return success
 }
+
+// collectorErrorKind returns the kind of failure of a collector.
+func collectorErrorKind(err error) string {
+	switch {
+	case errors.Is(err, errWMIQueryTimeout):
+		return collectorErrorWMITimeout
+	case errors.Is(err, errWMICircuitOpen):
+		return collectorErrorWMICircuitOpen
+	case errors.Is(err, errWMIBusy):
+		return collectorErrorWMIBusy
+	}
+	return collectorErrorFailed
+}
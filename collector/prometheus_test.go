@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type failingCollector struct {
+	err error
+}
+
+func (c failingCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
+	return c.err
+}
+
+func collectorErrorsValue(t *testing.T, collector, kind string) float64 {
+	var m dto.Metric
+	if err := collectorErrors.WithLabelValues(collector, kind).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestExecuteCountsErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind string
+	}{
+		{errors.New("access denied"), collectorErrorFailed},
+		{fmt.Errorf("querying Win32_Service: %w", errWMIQueryTimeout), collectorErrorWMITimeout},
+		{errWMICircuitOpen, collectorErrorWMICircuitOpen},
+		{fmt.Errorf("querying Win32_Service: %w", errWMIBusy), collectorErrorWMIBusy},
+	}
+	for _, c := range cases {
+		t.Run(c.kind, func(t *testing.T) {
+			before := collectorErrorsValue(t, "failing", c.kind)
+			ch := make(chan prometheus.Metric, 1)
+			if outcome := execute("failing", failingCollector{c.err}, &ScrapeContext{}, ch); outcome != failed {
+				t.Errorf("expected the collector to fail, got outcome %v", outcome)
+			}
+			if v := collectorErrorsValue(t, "failing", c.kind); v != before+1 {
+				t.Errorf("expected windows_exporter_collector_errors_total to be %v, got %v", before+1, v)
+			}
+		})
+	}
+
+	// Failures of remote scrapes aren't counted.
+	before := collectorErrorsValue(t, "failing", collectorErrorFailed)
+	ch := make(chan prometheus.Metric, 1)
+	execute("failing", failingCollector{errors.New("access denied")}, &ScrapeContext{target: "appliance01"}, ch)
+	if v := collectorErrorsValue(t, "failing", collectorErrorFailed); v != before {
+		t.Errorf("expected remote failures not to be counted, got %v", v)
+	}
+}
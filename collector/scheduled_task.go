@@ -4,15 +4,21 @@
 package collector
 
 import (
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
 	ole "github.com/go-ole/go-ole"
 	"github.com/go-ole/go-ole/oleutil"
+	"github.com/prometheus-community/windows_exporter/headers/wevtapi"
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -23,6 +29,11 @@ const (
 
 	FlagScheduledTaskExclude = "collector.scheduled_task.exclude"
 	FlagScheduledTaskInclude = "collector.scheduled_task.include"
+
+	FlagScheduledTaskFolderExclude   = "collector.scheduled_task.folder-exclude"
+	FlagScheduledTaskFolderInclude   = "collector.scheduled_task.folder-include"
+	FlagScheduledTaskHidden          = "collector.scheduled_task.hidden"
+	FlagScheduledTaskRefreshInterval = "collector.scheduled_task.refresh-interval"
 )
 
 var (
@@ -34,15 +45,41 @@ var (
 
 	taskIncludeSet bool
 	taskExcludeSet bool
+
+	taskFolderExclude   *string
+	taskFolderInclude   *string
+	taskHidden          *bool
+	taskRefreshInterval *time.Duration
 )
 
 type ScheduledTaskCollector struct {
-	LastResult *prometheus.Desc
-	MissedRuns *prometheus.Desc
-	State      *prometheus.Desc
+	logger log.Logger
+
+	LastResult      *prometheus.Desc
+	LastResultCode  *prometheus.Desc
+	LastRunTime     *prometheus.Desc
+	LastRunDuration *prometheus.Desc
+	NextRunTime     *prometheus.Desc
+	MissedRuns      *prometheus.Desc
+	State           *prometheus.Desc
+	Info            *prometheus.Desc
 
 	taskIncludePattern *regexp.Regexp
 	taskExcludePattern *regexp.Regexp
+
+	folderIncludePattern *regexp.Regexp
+	folderExcludePattern *regexp.Regexp
+
+	service taskService
+	history *taskHistory
+
+	// The tasks are listed again once refreshInterval elapsed, on every
+	// scrape if 0.
+	refreshInterval time.Duration
+	now             func() time.Time
+	tasksMu         sync.Mutex
+	tasks           ScheduledTasks
+	tasksTime       time.Time
 }
 
 // TaskState ...
@@ -60,6 +97,23 @@ const (
 	TASK_RESULT_SUCCESS TaskResult = 0x0
 )
 
+// Names of the TASK_TRIGGER_TYPE2 values.
+// https://docs.microsoft.com/en-us/windows/win32/api/taskschd/ne-taskschd-task_trigger_type2
+var taskTriggerTypes = map[int32]string{
+	0:  "event",
+	1:  "time",
+	2:  "daily",
+	3:  "weekly",
+	4:  "monthly",
+	5:  "monthly_dow",
+	6:  "idle",
+	7:  "registration",
+	8:  "boot",
+	9:  "logon",
+	11: "session_state_change",
+	12: "custom",
+}
+
 // RegisteredTask ...
 type ScheduledTask struct {
 	Name            string
@@ -68,10 +122,37 @@ type ScheduledTask struct {
 	State           TaskState
 	MissedRunsCount float64
 	LastTaskResult  TaskResult
+	// LastRunTime and NextRunTime are zero if the task never ran or isn't
+	// scheduled.
+	LastRunTime time.Time
+	NextRunTime time.Time
+	Author      string
+	RunAs       string
+	// TriggerTypes are the sorted names of the types of the triggers.
+	TriggerTypes []string
+	// DefinitionErr is the error reading the definition of the task, holding
+	// its author, account and triggers, if any.
+	DefinitionErr error
 }
 
 type ScheduledTasks []ScheduledTask
 
+// A taskService gives access to the Task Scheduler.
+type taskService interface {
+	// RootFolder calls f with the root folder, valid during the call only.
+	RootFolder(f func(taskFolder) error) error
+}
+
+// A taskFolder is a folder of the Task Scheduler.
+type taskFolder interface {
+	// Path returns the path of the folder, with backslashes.
+	Path() (string, error)
+	// Tasks returns the tasks of the folder.
+	Tasks() (ScheduledTasks, error)
+	// EachFolder calls f with each subfolder, valid during the call only.
+	EachFolder(f func(taskFolder) error) error
+}
+
 // newScheduledTask ...
 func newScheduledTaskFlags(app *kingpin.Application) {
 	taskInclude = app.Flag(
@@ -90,6 +171,26 @@ func newScheduledTaskFlags(app *kingpin.Application) {
 		return nil
 	}).String()
 
+	taskFolderInclude = app.Flag(
+		FlagScheduledTaskFolderInclude,
+		"Regexp of folders to list the tasks of. Folder path must both match include and not match exclude for its tasks to be listed.",
+	).Default(".+").String()
+
+	taskFolderExclude = app.Flag(
+		FlagScheduledTaskFolderExclude,
+		"Regexp of folders to skip, along with their subfolders.",
+	).Default("").String()
+
+	taskHidden = app.Flag(
+		FlagScheduledTaskHidden,
+		"Include hidden tasks.",
+	).Default("true").Bool()
+
+	taskRefreshInterval = app.Flag(
+		FlagScheduledTaskRefreshInterval,
+		"Interval at which the tasks are listed, reporting the tasks last listed in between. 0 to list them on every scrape.",
+	).Default("0s").Duration()
+
 	taskOldInclude = app.Flag(
 		FlagScheduledTaskOldInclude,
 		"DEPRECATED: Use --collector.scheduled_task.include",
@@ -102,9 +203,10 @@ func newScheduledTaskFlags(app *kingpin.Application) {
 
 // newScheduledTask ...
 func newScheduledTask() (Collector, error) {
+	logger := log.With(log.CollectorField, "scheduled_task")
 	if *taskOldExclude != "" {
 		if !taskExcludeSet {
-			log.Warnln("msg", "--collector.scheduled_task.blacklist is DEPRECATED and will be removed in a future release, use --collector.scheduled_task.exclude")
+			logger.Warnln("msg", "--collector.scheduled_task.blacklist is DEPRECATED and will be removed in a future release, use --collector.scheduled_task.exclude")
 			*taskExclude = *taskOldExclude
 		} else {
 			return nil, errors.New("--collector.scheduled_task.blacklist and --collector.scheduled_task.exclude are mutually exclusive")
@@ -112,15 +214,13 @@ func newScheduledTask() (Collector, error) {
 	}
 	if *taskOldInclude != "" {
 		if !taskIncludeSet {
-			log.Warnln("msg", "--collector.scheduled_task.whitelist is DEPRECATED and will be removed in a future release, use --collector.scheduled_task.include")
+			logger.Warnln("msg", "--collector.scheduled_task.whitelist is DEPRECATED and will be removed in a future release, use --collector.scheduled_task.include")
 			*taskInclude = *taskOldInclude
 		} else {
 			return nil, errors.New("--collector.scheduled_task.whitelist and --collector.scheduled_task.include are mutually exclusive")
 		}
 	}
 
-	const subsystem = "scheduled_task"
-
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
@@ -133,7 +233,21 @@ func newScheduledTask() (Collector, error) {
 	}
 	defer ole.CoUninitialize()
 
+	c := newScheduledTaskCollectorWith(oleTaskService{hidden: *taskHidden}, eventLogTaskEvents{})
+	c.logger = logger
+	c.taskIncludePattern = regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *taskInclude))
+	c.taskExcludePattern = regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *taskExclude))
+	c.folderIncludePattern = regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *taskFolderInclude))
+	c.folderExcludePattern = regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *taskFolderExclude))
+	c.refreshInterval = *taskRefreshInterval
+	return c, nil
+}
+
+func newScheduledTaskCollectorWith(service taskService, events taskEventSource) *ScheduledTaskCollector {
+	const subsystem = "scheduled_task"
+
 	return &ScheduledTaskCollector{
+		logger: log.With(log.CollectorField, "scheduled_task"),
 		LastResult: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "last_result"),
 			"The result that was returned the last time the registered task was run",
@@ -141,6 +255,34 @@ func newScheduledTask() (Collector, error) {
 			nil,
 		),
 
+		LastResultCode: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "last_result_code"),
+			"The code (HRESULT) that was returned the last time the registered task was run",
+			[]string{"task"},
+			nil,
+		),
+
+		LastRunTime: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "last_run_time_seconds"),
+			"The time the registered task was last run, in seconds since the epoch",
+			[]string{"task"},
+			nil,
+		),
+
+		LastRunDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "last_run_duration_seconds"),
+			"The duration of the last completed run of the registered task, from the task history",
+			[]string{"task"},
+			nil,
+		),
+
+		NextRunTime: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "next_run_time_seconds"),
+			"The time the registered task is next scheduled to run, in seconds since the epoch",
+			[]string{"task"},
+			nil,
+		),
+
 		MissedRuns: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "missed_runs"),
 			"The number of times the registered task missed a scheduled run",
@@ -155,14 +297,28 @@ func newScheduledTask() (Collector, error) {
 			nil,
 		),
 
-		taskIncludePattern: regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *taskInclude)),
-		taskExcludePattern: regexp.MustCompile(fmt.Sprintf("^(?:%s)$", *taskExclude)),
-	}, nil
+		Info: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "info"),
+			"A metric with a constant '1' value labeled with the author, account, enabled flag and trigger types of the registered task",
+			[]string{"task", "author", "run_as", "enabled", "triggers"},
+			nil,
+		),
+
+		taskIncludePattern: regexp.MustCompile("^(?:.+)$"),
+		taskExcludePattern: regexp.MustCompile("^(?:)$"),
+
+		folderIncludePattern: regexp.MustCompile("^(?:.+)$"),
+		folderExcludePattern: regexp.MustCompile("^(?:)$"),
+
+		service: service,
+		history: newTaskHistory(events),
+		now:     time.Now,
+	}
 }
 
 func (c *ScheduledTaskCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collect(ch); err != nil {
-		log.Error("failed collecting user metrics:", desc, err)
+		c.logger.Error("failed collecting user metrics:", desc, err)
 		return err
 	}
 
@@ -172,11 +328,17 @@ func (c *ScheduledTaskCollector) Collect(ctx *ScrapeContext, ch chan<- prometheu
 var TASK_STATES = []string{"disabled", "queued", "ready", "running", "unknown"}
 
 func (c *ScheduledTaskCollector) collect(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
-	scheduledTasks, err := getScheduledTasks()
+	scheduledTasks, err := c.getScheduledTasks()
 	if err != nil {
 		return nil, err
 	}
 
+	durations, err := c.history.update(scheduledTasks)
+	if err != nil {
+		// The durations known so far are still reported.
+		c.logger.Debugf("Failed to read the task history: %v", err)
+	}
+
 	for _, task := range scheduledTasks {
 		if c.taskExcludePattern.MatchString(task.Path) ||
 			!c.taskIncludePattern.MatchString(task.Path) {
@@ -195,6 +357,40 @@ func (c *ScheduledTaskCollector) collect(ch chan<- prometheus.Metric) (*promethe
 			task.Path,
 		)
 
+		ch <- prometheus.MustNewConstMetric(
+			c.LastResultCode,
+			prometheus.GaugeValue,
+			float64(task.LastTaskResult),
+			task.Path,
+		)
+
+		if !task.LastRunTime.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.LastRunTime,
+				prometheus.GaugeValue,
+				float64(task.LastRunTime.UnixNano())/1e9,
+				task.Path,
+			)
+		}
+
+		if duration, ok := durations[task.Path]; ok {
+			ch <- prometheus.MustNewConstMetric(
+				c.LastRunDuration,
+				prometheus.GaugeValue,
+				duration.Seconds(),
+				task.Path,
+			)
+		}
+
+		if !task.NextRunTime.IsZero() {
+			ch <- prometheus.MustNewConstMetric(
+				c.NextRunTime,
+				prometheus.GaugeValue,
+				float64(task.NextRunTime.UnixNano())/1e9,
+				task.Path,
+			)
+		}
+
 		ch <- prometheus.MustNewConstMetric(
 			c.MissedRuns,
 			prometheus.GaugeValue,
@@ -217,52 +413,193 @@ func (c *ScheduledTaskCollector) collect(ch chan<- prometheus.Metric) (*promethe
 				state,
 			)
 		}
+
+		if task.DefinitionErr != nil {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.Info,
+			prometheus.GaugeValue,
+			1.0,
+			task.Path,
+			task.Author,
+			task.RunAs,
+			strconv.FormatBool(task.Enabled),
+			strings.Join(task.TriggerTypes, ","),
+		)
 	}
 
 	return nil, nil
 }
 
+// getScheduledTasks returns the tasks of the folders walked, listing them
+// again once the refresh interval elapsed.
+func (c *ScheduledTaskCollector) getScheduledTasks() (ScheduledTasks, error) {
+	c.tasksMu.Lock()
+	defer c.tasksMu.Unlock()
+
+	if c.refreshInterval > 0 && !c.tasksTime.IsZero() && c.now().Sub(c.tasksTime) < c.refreshInterval {
+		return c.tasks, nil
+	}
+
+	var scheduledTasks ScheduledTasks
+	err := c.service.RootFolder(func(root taskFolder) error {
+		return c.fetchTasksRecursively(root, &scheduledTasks)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range scheduledTasks {
+		if task.DefinitionErr != nil {
+			c.logger.Warnf("Failed to read the definition of task %s, skipping its info: %v", task.Path, task.DefinitionErr)
+		}
+	}
+
+	c.tasks, c.tasksTime = scheduledTasks, c.now()
+	return scheduledTasks, nil
+}
+
+// fetchTasksRecursively lists the tasks of the folders included, without
+// walking the folders excluded or those the include pattern can't match.
+func (c *ScheduledTaskCollector) fetchTasksRecursively(folder taskFolder, scheduledTasks *ScheduledTasks) error {
+	path, err := folder.Path()
+	if err != nil {
+		return err
+	}
+	path = taskPath(path)
+	if c.folderExcludePattern.MatchString(path) {
+		return nil
+	}
+
+	if c.folderIncludePattern.MatchString(path) {
+		tasks, err := folder.Tasks()
+		if err != nil {
+			return err
+		}
+		*scheduledTasks = append(*scheduledTasks, tasks...)
+	}
+
+	if !c.folderIncludesSubfolders(path) {
+		return nil
+	}
+	return folder.EachFolder(func(subFolder taskFolder) error {
+		return c.fetchTasksRecursively(subFolder, scheduledTasks)
+	})
+}
+
+// folderIncludesSubfolders returns whether the include pattern may match the
+// subfolders of the folder at path, or their own subfolders. The literal
+// prefix of the pattern, which is anchored, is treated as a path prefix.
+func (c *ScheduledTaskCollector) folderIncludesSubfolders(path string) bool {
+	prefix, complete := c.folderIncludePattern.LiteralPrefix()
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+	if strings.HasPrefix(prefix, path) {
+		return true
+	}
+	return !complete && strings.HasPrefix(path, prefix)
+}
+
+// taskPath returns the path of a task, with forward slashes.
+func taskPath(path string) string {
+	return strings.ReplaceAll(path, "\\", "/")
+}
+
+// taskTime converts a time read from the Task Scheduler, in local time. The
+// Task Scheduler reports 1899-12-30, the zero DATE, or 1999-11-30 for tasks
+// that never ran or aren't scheduled, for which the zero time is returned.
+func taskTime(t time.Time) time.Time {
+	if t.Year() < 2000 {
+		return time.Time{}
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
+
+// taskTriggerTypeNames returns the sorted names of the trigger types.
+func taskTriggerTypeNames(types []int32) []string {
+	names := make([]string, 0, len(types))
+	seen := map[string]bool{}
+	for _, t := range types {
+		name, ok := taskTriggerTypes[t]
+		if !ok {
+			name = "unknown"
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
 const SCHEDULED_TASK_PROGRAM_ID = "Schedule.Service.1"
 
 // S_FALSE is returned by CoInitialize if it was already called on this thread.
 const S_FALSE = 0x00000001
 
-func getScheduledTasks() (scheduledTasks ScheduledTasks, err error) {
+// oleTaskService is the Task Scheduler, through its COM interface.
+type oleTaskService struct {
+	// hidden lists the hidden tasks too.
+	hidden bool
+}
+
+func (s oleTaskService) RootFolder(f func(taskFolder) error) error {
 	schedClassID, err := ole.ClassIDFrom(SCHEDULED_TASK_PROGRAM_ID)
 	if err != nil {
-		return scheduledTasks, err
+		return err
 	}
 
 	taskSchedulerObj, err := ole.CreateInstance(schedClassID, nil)
 	if err != nil || taskSchedulerObj == nil {
-		return scheduledTasks, err
+		return err
 	}
 	defer taskSchedulerObj.Release()
 
 	taskServiceObj := taskSchedulerObj.MustQueryInterface(ole.IID_IDispatch)
 	_, err = oleutil.CallMethod(taskServiceObj, "Connect")
 	if err != nil {
-		return scheduledTasks, err
+		return err
 	}
 	defer taskServiceObj.Release()
 
 	res, err := oleutil.CallMethod(taskServiceObj, "GetFolder", `\`)
 	if err != nil {
-		return scheduledTasks, err
+		return err
 	}
 
 	rootFolderObj := res.ToIDispatch()
 	defer rootFolderObj.Release()
 
-	err = fetchTasksRecursively(rootFolderObj, &scheduledTasks)
+	return f(oleTaskFolder{rootFolderObj, s.hidden})
+}
 
-	return scheduledTasks, err
+// oleTaskFolder is an ITaskFolder.
+type oleTaskFolder struct {
+	folder *ole.IDispatch
+	hidden bool
 }
 
-func fetchTasksInFolder(folder *ole.IDispatch, scheduledTasks *ScheduledTasks) error {
-	res, err := oleutil.CallMethod(folder, "GetTasks", 1)
+func (f oleTaskFolder) Path() (string, error) {
+	path, err := oleProperty(f.folder, "Path")
 	if err != nil {
-		return err
+		return "", err
+	}
+	s, _ := path.(string)
+	return s, nil
+}
+
+func (f oleTaskFolder) Tasks() (scheduledTasks ScheduledTasks, err error) {
+	// TASK_ENUM_HIDDEN
+	flags := 0
+	if f.hidden {
+		flags = 1
+	}
+	res, err := oleutil.CallMethod(f.folder, "GetTasks", flags)
+	if err != nil {
+		return nil, err
 	}
 
 	tasks := res.ToIDispatch()
@@ -277,20 +614,16 @@ func fetchTasksInFolder(folder *ole.IDispatch, scheduledTasks *ScheduledTasks) e
 			return err
 		}
 
-		*scheduledTasks = append(*scheduledTasks, parsedTask)
+		scheduledTasks = append(scheduledTasks, parsedTask)
 
 		return nil
 	})
 
-	return err
+	return scheduledTasks, err
 }
 
-func fetchTasksRecursively(folder *ole.IDispatch, scheduledTasks *ScheduledTasks) error {
-	if err := fetchTasksInFolder(folder, scheduledTasks); err != nil {
-		return err
-	}
-
-	res, err := oleutil.CallMethod(folder, "GetFolders", 1)
+func (f oleTaskFolder) EachFolder(fn func(taskFolder) error) error {
+	res, err := oleutil.CallMethod(f.folder, "GetFolders", 1)
 	if err != nil {
 		return err
 	}
@@ -298,84 +631,113 @@ func fetchTasksRecursively(folder *ole.IDispatch, scheduledTasks *ScheduledTasks
 	subFolders := res.ToIDispatch()
 	defer subFolders.Release()
 
-	err = oleutil.ForEach(subFolders, func(v *ole.VARIANT) error {
+	return oleutil.ForEach(subFolders, func(v *ole.VARIANT) error {
 		subFolder := v.ToIDispatch()
 		defer subFolder.Release()
-		return fetchTasksRecursively(subFolder, scheduledTasks)
+		return fn(oleTaskFolder{subFolder, f.hidden})
 	})
-
-	return err
 }
 
-func parseTask(task *ole.IDispatch) (scheduledTask ScheduledTask, err error) {
-	taskNameVar, err := oleutil.GetProperty(task, "Name")
+// oleProperty returns the value of a property of a COM object, which mustn't
+// be an object.
+func oleProperty(disp *ole.IDispatch, name string) (interface{}, error) {
+	v, err := oleutil.GetProperty(disp, name)
 	if err != nil {
-		return scheduledTask, err
+		return nil, fmt.Errorf("failed to get property %s: %w", name, err)
 	}
-	defer func() {
-		if tempErr := taskNameVar.Clear(); tempErr != nil {
-			err = tempErr
+	defer v.Clear() //nolint:errcheck
+	return v.Value(), nil
+}
+
+// parseTask reads the properties of an IRegisteredTask.
+func parseTask(task *ole.IDispatch) (scheduledTask ScheduledTask, err error) {
+	props := map[string]interface{}{}
+	for _, name := range []string{"Name", "Path", "Enabled", "State", "NumberOfMissedRuns", "LastTaskResult", "LastRunTime", "NextRunTime"} {
+		if props[name], err = oleProperty(task, name); err != nil {
+			return scheduledTask, err
 		}
-	}()
+	}
 
-	taskPathVar, err := oleutil.GetProperty(task, "Path")
-	if err != nil {
-		return scheduledTask, err
+	scheduledTask.Name, _ = props["Name"].(string)
+	path, _ := props["Path"].(string)
+	scheduledTask.Path = taskPath(path)
+	scheduledTask.Enabled, _ = props["Enabled"].(bool)
+	state, _ := props["State"].(int32)
+	scheduledTask.State = TaskState(state)
+	missedRuns, _ := props["NumberOfMissedRuns"].(int32)
+	scheduledTask.MissedRunsCount = float64(missedRuns)
+	// HRESULTs are read as negative numbers.
+	lastResult, _ := props["LastTaskResult"].(int32)
+	scheduledTask.LastTaskResult = TaskResult(uint32(lastResult))
+	if t, ok := props["LastRunTime"].(time.Time); ok {
+		scheduledTask.LastRunTime = taskTime(t)
+	}
+	if t, ok := props["NextRunTime"].(time.Time); ok {
+		scheduledTask.NextRunTime = taskTime(t)
 	}
-	defer func() {
-		if tempErr := taskPathVar.Clear(); tempErr != nil {
-			err = tempErr
-		}
-	}()
 
-	taskEnabledVar, err := oleutil.GetProperty(task, "Enabled")
+	scheduledTask.DefinitionErr = parseTaskDefinition(task, &scheduledTask)
+	return scheduledTask, nil
+}
+
+// parseTaskDefinition reads the author, account and trigger types of an
+// IRegisteredTask from its definition.
+func parseTaskDefinition(task *ole.IDispatch, scheduledTask *ScheduledTask) error {
+	definitionVar, err := oleutil.GetProperty(task, "Definition")
 	if err != nil {
-		return scheduledTask, err
+		return err
 	}
-	defer func() {
-		if tempErr := taskEnabledVar.Clear(); tempErr != nil {
-			err = tempErr
-		}
-	}()
+	defer definitionVar.Clear() //nolint:errcheck
+	definition := definitionVar.ToIDispatch()
 
-	taskStateVar, err := oleutil.GetProperty(task, "State")
+	registrationInfoVar, err := oleutil.GetProperty(definition, "RegistrationInfo")
 	if err != nil {
-		return scheduledTask, err
+		return err
 	}
-	defer func() {
-		if tempErr := taskStateVar.Clear(); tempErr != nil {
-			err = tempErr
-		}
-	}()
+	defer registrationInfoVar.Clear() //nolint:errcheck
+	author, err := oleProperty(registrationInfoVar.ToIDispatch(), "Author")
+	if err != nil {
+		return err
+	}
+	scheduledTask.Author, _ = author.(string)
 
-	taskNumberOfMissedRunsVar, err := oleutil.GetProperty(task, "NumberOfMissedRuns")
+	principalVar, err := oleutil.GetProperty(definition, "Principal")
 	if err != nil {
-		return scheduledTask, err
+		return err
 	}
-	defer func() {
-		if tempErr := taskNumberOfMissedRunsVar.Clear(); tempErr != nil {
-			err = tempErr
+	defer principalVar.Clear() //nolint:errcheck
+	// Tasks run either as a user or as a group of users.
+	for _, name := range []string{"UserId", "GroupId"} {
+		id, err := oleProperty(principalVar.ToIDispatch(), name)
+		if err != nil {
+			return err
+		}
+		if scheduledTask.RunAs, _ = id.(string); scheduledTask.RunAs != "" {
+			break
 		}
-	}()
+	}
 
-	taskLastTaskResultVar, err := oleutil.GetProperty(task, "LastTaskResult")
+	triggersVar, err := oleutil.GetProperty(definition, "Triggers")
 	if err != nil {
-		return scheduledTask, err
+		return err
 	}
-	defer func() {
-		if tempErr := taskLastTaskResultVar.Clear(); tempErr != nil {
-			err = tempErr
-		}
-	}()
+	defer triggersVar.Clear() //nolint:errcheck
+	var triggerTypes []int32
+	err = oleutil.ForEach(triggersVar.ToIDispatch(), func(v *ole.VARIANT) error {
+		trigger := v.ToIDispatch()
+		defer trigger.Release()
 
-	scheduledTask.Name = taskNameVar.ToString()
-	scheduledTask.Path = strings.ReplaceAll(taskPathVar.ToString(), "\\", "/")
-	scheduledTask.Enabled = taskEnabledVar.Value().(bool)
-	scheduledTask.State = TaskState(taskStateVar.Val)
-	scheduledTask.MissedRunsCount = float64(taskNumberOfMissedRunsVar.Val)
-	scheduledTask.LastTaskResult = TaskResult(taskLastTaskResultVar.Val)
+		triggerType, err := oleProperty(trigger, "Type")
+		if err != nil {
+			return err
+		}
+		t, _ := triggerType.(int32)
+		triggerTypes = append(triggerTypes, t)
+		return nil
+	})
+	scheduledTask.TriggerTypes = taskTriggerTypeNames(triggerTypes)
 
-	return scheduledTask, err
+	return err
 }
 
 func (t TaskState) String() string {
@@ -394,3 +756,172 @@ func (t TaskState) String() string {
 		return ""
 	}
 }
+
+// Events of the task history.
+// https://docs.microsoft.com/en-us/windows/win32/taskschd/task-scheduler-start-page
+const (
+	taskSchedulerChannel = "Microsoft-Windows-TaskScheduler/Operational"
+	taskStartedEvent     = 100
+	taskCompletedEvent   = 102
+	// Runs started longer ago than this before the last event are assumed
+	// never to complete, e.g. when the history was disabled meanwhile.
+	taskHistoryMaxPending = 7 * 24 * time.Hour
+	// Only the events created within this window are read on the first
+	// update, rather than the whole channel.
+	taskHistoryWindow = 7 * 24 * time.Hour
+)
+
+// A taskEvent is a task started or completed event of the task history.
+type taskEvent struct {
+	RecordID   uint64
+	ID         uint32
+	Task       string
+	InstanceID string
+	Time       time.Time
+}
+
+// A taskEventSource reads the task history.
+type taskEventSource interface {
+	// Events returns the task started and completed events recorded after
+	// the event record ID, oldest first, and the number of events skipped
+	// as they couldn't be parsed. If the ID is 0, only the events created
+	// within taskHistoryWindow are returned.
+	Events(after uint64) ([]taskEvent, int, error)
+}
+
+// taskHistory keeps the duration of the last completed run of the tasks,
+// reading the task history incrementally.
+type taskHistory struct {
+	source taskEventSource
+
+	mu         sync.Mutex
+	lastRecord uint64
+	// started holds the start events of the runs in progress, by instance.
+	started   map[string]taskEvent
+	durations map[string]time.Duration
+}
+
+func newTaskHistory(source taskEventSource) *taskHistory {
+	return &taskHistory{
+		source:    source,
+		started:   map[string]taskEvent{},
+		durations: map[string]time.Duration{},
+	}
+}
+
+// update reads the events recorded since the last update and returns the
+// duration of the last completed run of the tasks, by path. The durations of
+// the tasks no longer listed are forgotten.
+func (h *taskHistory) update(tasks ScheduledTasks) (map[string]time.Duration, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	events, skipped, err := h.source.Events(h.lastRecord)
+	if err == nil && skipped > 0 {
+		err = fmt.Errorf("skipped %d task events that could not be parsed", skipped)
+	}
+	var last time.Time
+	for _, e := range events {
+		if e.RecordID > h.lastRecord {
+			h.lastRecord = e.RecordID
+		}
+		if e.Time.After(last) {
+			last = e.Time
+		}
+		switch e.ID {
+		case taskStartedEvent:
+			h.started[e.InstanceID] = e
+		case taskCompletedEvent:
+			start, ok := h.started[e.InstanceID]
+			if !ok {
+				// Started before the history was read or cleared.
+				continue
+			}
+			delete(h.started, e.InstanceID)
+			if !e.Time.Before(start.Time) {
+				h.durations[e.Task] = e.Time.Sub(start.Time)
+			}
+		}
+	}
+	for id, start := range h.started {
+		if last.Sub(start.Time) > taskHistoryMaxPending {
+			delete(h.started, id)
+		}
+	}
+
+	listed := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		listed[task.Path] = true
+	}
+	durations := make(map[string]time.Duration, len(h.durations))
+	for task, d := range h.durations {
+		if !listed[task] {
+			delete(h.durations, task)
+			continue
+		}
+		durations[task] = d
+	}
+	return durations, err
+}
+
+// taskEventXML is the part of the XML rendering of a task event read.
+type taskEventXML struct {
+	System struct {
+		EventID       uint32 `xml:"EventID"`
+		EventRecordID uint64 `xml:"EventRecordID"`
+		TimeCreated   struct {
+			SystemTime time.Time `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+	} `xml:"System"`
+	Data []struct {
+		Name  string `xml:"Name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"EventData>Data"`
+}
+
+// parseTaskEvent parses the XML rendering of a task event.
+func parseTaskEvent(s string) (taskEvent, error) {
+	var x taskEventXML
+	if err := xml.Unmarshal([]byte(s), &x); err != nil {
+		return taskEvent{}, fmt.Errorf("failed to parse task event: %w", err)
+	}
+	e := taskEvent{
+		RecordID: x.System.EventRecordID,
+		ID:       x.System.EventID,
+		Time:     x.System.TimeCreated.SystemTime,
+	}
+	for _, d := range x.Data {
+		switch d.Name {
+		case "TaskName":
+			e.Task = taskPath(d.Value)
+		case "InstanceId":
+			e.InstanceID = d.Value
+		}
+	}
+	return e, nil
+}
+
+// eventLogTaskEvents reads the task history from the event log.
+type eventLogTaskEvents struct{}
+
+func (eventLogTaskEvents) Events(after uint64) ([]taskEvent, int, error) {
+	query := fmt.Sprintf("*[System[(EventID=%d or EventID=%d) and EventRecordID>%d]]", taskStartedEvent, taskCompletedEvent, after)
+	if after == 0 {
+		query = fmt.Sprintf("*[System[(EventID=%d or EventID=%d) and TimeCreated[timediff(@SystemTime) <= %d]]]", taskStartedEvent, taskCompletedEvent, taskHistoryWindow.Milliseconds())
+	}
+	xmls, err := wevtapi.QueryXML(taskSchedulerChannel, query, wevtapi.EvtQueryChannelPath|wevtapi.EvtQueryForwardDirection)
+
+	// Unparseable events are skipped, the record ID of the next ones
+	// moving the history past them.
+	events := make([]taskEvent, 0, len(xmls))
+	skipped := 0
+	for _, s := range xmls {
+		e, err := parseTaskEvent(s)
+		if err != nil {
+			skipped++
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, skipped, err
+}
@@ -1,9 +1,422 @@
 package collector
 
 import (
+	"errors"
+	"reflect"
+	"regexp"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 )
 
 func BenchmarkScheduledTaskCollector(b *testing.B) {
 	benchmarkCollector(b, "scheduled_task", newScheduledTask)
 }
+
+type fakeTaskService struct {
+	root fakeTaskFolder
+	// The number of walks and the folders walked and listed.
+	walks  int
+	walked []string
+	listed []string
+}
+
+func (s *fakeTaskService) RootFolder(fn func(taskFolder) error) error {
+	s.walks++
+	return fn(fakeTaskFolderOf{&s.root, s})
+}
+
+type fakeTaskFolder struct {
+	path    string
+	tasks   ScheduledTasks
+	folders []fakeTaskFolder
+}
+
+type fakeTaskFolderOf struct {
+	*fakeTaskFolder
+	service *fakeTaskService
+}
+
+func (f fakeTaskFolderOf) Path() (string, error) {
+	return f.path, nil
+}
+
+func (f fakeTaskFolderOf) Tasks() (ScheduledTasks, error) {
+	f.service.listed = append(f.service.listed, f.path)
+	return f.tasks, nil
+}
+
+func (f fakeTaskFolderOf) EachFolder(fn func(taskFolder) error) error {
+	f.service.walked = append(f.service.walked, f.path)
+	for i := range f.folders {
+		if err := fn(fakeTaskFolderOf{&f.folders[i], f.service}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fakeTaskEvents struct {
+	events  []taskEvent
+	skipped int
+	err     error
+}
+
+func (f *fakeTaskEvents) Events(after uint64) ([]taskEvent, int, error) {
+	var events []taskEvent
+	for _, e := range f.events {
+		if e.RecordID > after {
+			events = append(events, e)
+		}
+	}
+	return events, f.skipped, f.err
+}
+
+// collectTaskMetrics returns the metrics of the collector by description and
+// task.
+func collectTaskMetrics(t *testing.T, c *ScheduledTaskCollector) map[*prometheus.Desc]map[string]*dto.Metric {
+	ch := make(chan prometheus.Metric, 100)
+	if _, err := c.collect(ch); err != nil {
+		t.Fatal(err)
+	}
+	close(ch)
+
+	metrics := map[*prometheus.Desc]map[string]*dto.Metric{}
+	for m := range ch {
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		if metrics[m.Desc()] == nil {
+			metrics[m.Desc()] = map[string]*dto.Metric{}
+		}
+		for _, l := range pb.GetLabel() {
+			if l.GetName() == "task" {
+				metrics[m.Desc()][l.GetValue()] = &pb
+			}
+		}
+	}
+	return metrics
+}
+
+func TestScheduledTaskCollector(t *testing.T) {
+	lastRun := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	nextRun := lastRun.Add(24 * time.Hour)
+	root := fakeTaskFolder{
+		path: `\`,
+		tasks: ScheduledTasks{{
+			Name:           "Backup",
+			Path:           "/Backup",
+			Enabled:        true,
+			State:          TASK_STATE_READY,
+			LastTaskResult: 0x8004131F,
+			LastRunTime:    lastRun,
+			NextRunTime:    nextRun,
+			Author:         `CORP\admin`,
+			RunAs:          "SYSTEM",
+			TriggerTypes:   []string{"daily", "event"},
+		}},
+		folders: []fakeTaskFolder{{
+			path: `\Jobs`,
+			tasks: ScheduledTasks{{
+				Name:  "Never",
+				Path:  "/Jobs/Never",
+				State: TASK_STATE_DISABLED,
+			}},
+		}},
+	}
+	events := &fakeTaskEvents{events: []taskEvent{
+		{RecordID: 1, ID: taskStartedEvent, Task: "/Backup", InstanceID: "a", Time: lastRun},
+		{RecordID: 2, ID: taskCompletedEvent, Task: "/Backup", InstanceID: "a", Time: lastRun.Add(90 * time.Second)},
+	}}
+	c := newScheduledTaskCollectorWith(&fakeTaskService{root: root}, events)
+
+	metrics := collectTaskMetrics(t, c)
+	if v := metrics[c.LastResult]["/Backup"].GetGauge().GetValue(); v != 0 {
+		t.Errorf("expected last_result 0, got %v", v)
+	}
+	if v := metrics[c.LastResultCode]["/Backup"].GetGauge().GetValue(); v != 0x8004131F {
+		t.Errorf("expected last_result_code %v, got %v", 0x8004131F, v)
+	}
+	if v := metrics[c.LastRunTime]["/Backup"].GetGauge().GetValue(); v != float64(lastRun.Unix()) {
+		t.Errorf("expected last_run_time_seconds %v, got %v", lastRun.Unix(), v)
+	}
+	if v := metrics[c.NextRunTime]["/Backup"].GetGauge().GetValue(); v != float64(nextRun.Unix()) {
+		t.Errorf("expected next_run_time_seconds %v, got %v", nextRun.Unix(), v)
+	}
+	if v := metrics[c.LastRunDuration]["/Backup"].GetGauge().GetValue(); v != 90 {
+		t.Errorf("expected last_run_duration_seconds 90, got %v", v)
+	}
+
+	info := map[string]string{}
+	for _, l := range metrics[c.Info]["/Backup"].GetLabel() {
+		info[l.GetName()] = l.GetValue()
+	}
+	expectedInfo := map[string]string{"task": "/Backup", "author": `CORP\admin`, "run_as": "SYSTEM", "enabled": "true", "triggers": "daily,event"}
+	if !reflect.DeepEqual(info, expectedInfo) {
+		t.Errorf("expected info labels %v, got %v", expectedInfo, info)
+	}
+
+	// Tasks of subfolders are collected, without times for those never run.
+	if _, ok := metrics[c.State]["/Jobs/Never"]; !ok {
+		t.Error("expected the task of the subfolder to be collected")
+	}
+	for _, desc := range []*prometheus.Desc{c.LastRunTime, c.NextRunTime, c.LastRunDuration} {
+		if _, ok := metrics[desc]["/Jobs/Never"]; ok {
+			t.Errorf("expected no %s for a task never run", desc)
+		}
+	}
+}
+
+func TestScheduledTaskDefinitionError(t *testing.T) {
+	lastRun := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	root := fakeTaskFolder{
+		path: `\`,
+		tasks: ScheduledTasks{
+			{Path: "/Broken", State: TASK_STATE_READY, LastRunTime: lastRun, DefinitionErr: errors.New("access denied")},
+			{Path: "/Fine", State: TASK_STATE_READY, LastRunTime: lastRun, Author: "admin"},
+		},
+	}
+	events := &fakeTaskEvents{events: []taskEvent{
+		{RecordID: 1, ID: taskStartedEvent, Task: "/Broken", InstanceID: "a", Time: lastRun},
+		{RecordID: 2, ID: taskCompletedEvent, Task: "/Broken", InstanceID: "a", Time: lastRun.Add(time.Minute)},
+		{RecordID: 3, ID: taskStartedEvent, Task: "/Fine", InstanceID: "b", Time: lastRun},
+		{RecordID: 4, ID: taskCompletedEvent, Task: "/Fine", InstanceID: "b", Time: lastRun.Add(time.Minute)},
+	}}
+	c := newScheduledTaskCollectorWith(&fakeTaskService{root: root}, events)
+
+	metrics := collectTaskMetrics(t, c)
+	if _, ok := metrics[c.Info]["/Broken"]; ok {
+		t.Error("expected no info for a task whose definition failed")
+	}
+	if _, ok := metrics[c.Info]["/Fine"]; !ok {
+		t.Error("expected info for the other tasks")
+	}
+	// The duration comes from the history, not from the definition.
+	for _, desc := range []*prometheus.Desc{c.State, c.LastRunTime, c.LastResult, c.LastRunDuration} {
+		if _, ok := metrics[desc]["/Broken"]; !ok {
+			t.Errorf("expected %s for a task whose definition failed", desc)
+		}
+	}
+}
+
+func TestScheduledTaskFolderFilters(t *testing.T) {
+	service := &fakeTaskService{root: fakeTaskFolder{
+		path:  `\`,
+		tasks: ScheduledTasks{{Path: "/Root"}},
+		folders: []fakeTaskFolder{
+			{
+				path:    `\Microsoft`,
+				tasks:   ScheduledTasks{{Path: "/Microsoft/Defrag"}},
+				folders: []fakeTaskFolder{{path: `\Microsoft\Windows`, tasks: ScheduledTasks{{Path: "/Microsoft/Windows/Chkdsk"}}}},
+			},
+			{
+				path:    `\Jobs`,
+				folders: []fakeTaskFolder{{path: `\Jobs\Nightly`, tasks: ScheduledTasks{{Path: "/Jobs/Nightly/Backup"}}}},
+			},
+		},
+	}}
+	c := newScheduledTaskCollectorWith(service, &fakeTaskEvents{})
+	c.folderIncludePattern = regexp.MustCompile("^(?:/Jobs/.+)$")
+	c.folderExcludePattern = regexp.MustCompile("^(?:/Microsoft)$")
+
+	tasks, err := c.getScheduledTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Path != "/Jobs/Nightly/Backup" {
+		t.Errorf("expected the tasks of /Jobs/Nightly only, got %v", tasks)
+	}
+	// Excluded folders aren't walked, and the tasks of the folders not
+	// included aren't listed.
+	if expected := []string{`\Jobs\Nightly`}; !reflect.DeepEqual(service.listed, expected) {
+		t.Errorf("expected the folders listed to be %v, got %v", expected, service.listed)
+	}
+	if expected := []string{`\`, `\Jobs`, `\Jobs\Nightly`}; !reflect.DeepEqual(service.walked, expected) {
+		t.Errorf("expected the folders walked to be %v, got %v", expected, service.walked)
+	}
+}
+
+func TestScheduledTaskFolderPruning(t *testing.T) {
+	service := &fakeTaskService{root: fakeTaskFolder{
+		path: `\`,
+		folders: []fakeTaskFolder{
+			{path: `\Microsoft`, folders: []fakeTaskFolder{{path: `\Microsoft\Windows`}}},
+			{
+				path: `\Jobs`,
+				folders: []fakeTaskFolder{
+					{path: `\Jobs\Nightly`, folders: []fakeTaskFolder{{path: `\Jobs\Nightly\Backup`}}},
+					{path: `\Jobs\Weekly`, folders: []fakeTaskFolder{{path: `\Jobs\Weekly\Backup`}}},
+				},
+			},
+		},
+	}}
+	c := newScheduledTaskCollectorWith(service, &fakeTaskEvents{})
+	c.folderIncludePattern = regexp.MustCompile("^(?:/Jobs/Nightly(/.+)?)$")
+
+	if _, err := c.getScheduledTasks(); err != nil {
+		t.Fatal(err)
+	}
+	// Only the folders on the way to /Jobs/Nightly and its subfolders are
+	// opened, the others are never walked nor listed.
+	if expected := []string{`\`, `\Jobs`, `\Jobs\Nightly`, `\Jobs\Nightly\Backup`}; !reflect.DeepEqual(service.walked, expected) {
+		t.Errorf("expected the folders walked to be %v, got %v", expected, service.walked)
+	}
+	if expected := []string{`\Jobs\Nightly`, `\Jobs\Nightly\Backup`}; !reflect.DeepEqual(service.listed, expected) {
+		t.Errorf("expected the folders listed to be %v, got %v", expected, service.listed)
+	}
+
+	// A pattern matching a single folder doesn't walk its subfolders.
+	service.walked, service.listed = nil, nil
+	c.folderIncludePattern = regexp.MustCompile("^(?:/Jobs/Nightly)$")
+	c.tasksTime = time.Time{}
+	if _, err := c.getScheduledTasks(); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{`\`, `\Jobs`}; !reflect.DeepEqual(service.walked, expected) {
+		t.Errorf("expected the folders walked to be %v, got %v", expected, service.walked)
+	}
+}
+
+func TestScheduledTaskRefreshInterval(t *testing.T) {
+	service := &fakeTaskService{root: fakeTaskFolder{path: `\`, tasks: ScheduledTasks{{Path: "/Backup"}}}}
+	c := newScheduledTaskCollectorWith(service, &fakeTaskEvents{})
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	// Without interval, the tasks are listed on every scrape.
+	for i := 0; i < 2; i++ {
+		if _, err := c.getScheduledTasks(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if service.walks != 2 {
+		t.Errorf("expected 2 walks, got %d", service.walks)
+	}
+
+	c.refreshInterval = time.Minute
+	service.root.tasks = append(service.root.tasks, ScheduledTask{Path: "/Cleanup"})
+	now = now.Add(30 * time.Second)
+	tasks, err := c.getScheduledTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.walks != 2 || len(tasks) != 1 {
+		t.Errorf("expected the tasks last listed within the interval, got %d walks and tasks %v", service.walks, tasks)
+	}
+
+	now = now.Add(30 * time.Second)
+	tasks, err = c.getScheduledTasks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if service.walks != 3 || len(tasks) != 2 {
+		t.Errorf("expected the tasks to be listed again after the interval, got %d walks and tasks %v", service.walks, tasks)
+	}
+}
+
+func TestTaskHistory(t *testing.T) {
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	events := &fakeTaskEvents{events: []taskEvent{
+		{RecordID: 10, ID: taskStartedEvent, Task: "/A", InstanceID: "1", Time: start},
+		{RecordID: 11, ID: taskStartedEvent, Task: "/A", InstanceID: "2", Time: start.Add(time.Minute)},
+		// Started before the history was read.
+		{RecordID: 12, ID: taskCompletedEvent, Task: "/B", InstanceID: "0", Time: start.Add(time.Minute)},
+		{RecordID: 13, ID: taskCompletedEvent, Task: "/A", InstanceID: "1", Time: start.Add(2 * time.Minute)},
+	}}
+	h := newTaskHistory(events)
+	tasks := ScheduledTasks{{Path: "/A"}, {Path: "/B"}}
+
+	durations, err := h.update(tasks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]time.Duration{"/A": 2 * time.Minute}; !reflect.DeepEqual(durations, expected) {
+		t.Errorf("expected durations %v, got %v", expected, durations)
+	}
+
+	// Runs completing in a later update are paired with their start, and
+	// failures to read the history keep the durations known.
+	events.events = append(events.events, taskEvent{RecordID: 14, ID: taskCompletedEvent, Task: "/A", InstanceID: "2", Time: start.Add(5 * time.Minute)})
+	events.err = errors.New("channel not found")
+	durations, err = h.update(tasks)
+	if err == nil {
+		t.Error("expected the error to be returned")
+	}
+	if expected := map[string]time.Duration{"/A": 4 * time.Minute}; !reflect.DeepEqual(durations, expected) {
+		t.Errorf("expected durations %v, got %v", expected, durations)
+	}
+	if h.lastRecord != 14 {
+		t.Errorf("expected the history to be read up to record 14, got %d", h.lastRecord)
+	}
+
+	// Unparseable events are skipped, the history still moving past them.
+	events.err = nil
+	events.skipped = 1
+	events.events = append(events.events, taskEvent{RecordID: 16, ID: taskStartedEvent, Task: "/B", InstanceID: "3", Time: start.Add(6 * time.Minute)})
+	if _, err = h.update(tasks); err == nil {
+		t.Error("expected the skipped events to be reported")
+	}
+	if h.lastRecord != 16 {
+		t.Errorf("expected the history to be read up to record 16, got %d", h.lastRecord)
+	}
+
+	// The durations of the tasks no longer listed are forgotten.
+	events.skipped = 0
+	durations, err = h.update(ScheduledTasks{{Path: "/B"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(durations) != 0 || len(h.durations) != 0 {
+		t.Errorf("expected no durations, got %v", durations)
+	}
+}
+
+func TestParseTaskEvent(t *testing.T) {
+	const event = `<Event xmlns="http://schemas.microsoft.com/win/2004/08/events/event">` +
+		`<System><Provider Name="Microsoft-Windows-TaskScheduler"/><EventID>102</EventID>` +
+		`<TimeCreated SystemTime="2023-05-01T10:01:30.1234567Z"/><EventRecordID>4242</EventRecordID></System>` +
+		`<EventData Name="TaskSuccessEvent"><Data Name="TaskName">\Jobs\Backup</Data><Data Name="UserContext">CORP\svc</Data>` +
+		`<Data Name="InstanceId">{6b1a9c3e-0000-4000-8000-000000000001}</Data></EventData></Event>`
+
+	e, err := parseTaskEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := taskEvent{
+		RecordID:   4242,
+		ID:         taskCompletedEvent,
+		Task:       "/Jobs/Backup",
+		InstanceID: "{6b1a9c3e-0000-4000-8000-000000000001}",
+		Time:       time.Date(2023, 5, 1, 10, 1, 30, 123456700, time.UTC),
+	}
+	if !e.Time.Equal(expected.Time) {
+		t.Errorf("expected time %v, got %v", expected.Time, e.Time)
+	}
+	e.Time = expected.Time
+	if e != expected {
+		t.Errorf("expected event %+v, got %+v", expected, e)
+	}
+}
+
+func TestTaskTime(t *testing.T) {
+	if got := taskTime(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("expected the zero DATE to be the zero time, got %v", got)
+	}
+	if got := taskTime(time.Date(1999, 11, 30, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("expected 1999-11-30 to be the zero time, got %v", got)
+	}
+	expected := time.Date(2023, 5, 1, 10, 0, 0, 0, time.Local)
+	if got := taskTime(time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)); !got.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestTaskTriggerTypeNames(t *testing.T) {
+	got := taskTriggerTypeNames([]int32{2, 0, 2, 42})
+	if expected := []string{"daily", "event", "unknown"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
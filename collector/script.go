@@ -0,0 +1,138 @@
+//go:build !noscript
+// +build !noscript
+
+package collector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus-community/windows_exporter/config"
+	"github.com/prometheus-community/windows_exporter/log"
+	"github.com/prometheus-community/windows_exporter/script"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	FlagScriptConfigFile     = "collector.script.config-file"
+	FlagScriptTimeout        = "collector.script.timeout"
+	FlagScriptMaxConcurrency = "collector.script.max-concurrency"
+)
+
+var (
+	scriptConfigFile     *string
+	scriptTimeout        *time.Duration
+	scriptMaxConcurrency *int
+)
+
+// A scriptCollector runs commands and exports the metrics they print in the
+// Prometheus text format.
+type scriptCollector struct {
+	logger log.Logger
+
+	ExitCode *prometheus.Desc
+	Duration *prometheus.Desc
+	Success  *prometheus.Desc
+
+	runner *script.Runner
+}
+
+// newScriptCollectorFlags ...
+func newScriptCollectorFlags(app *kingpin.Application) {
+	scriptConfigFile = app.Flag(
+		FlagScriptConfigFile,
+		"YAML file listing the scripts to run.",
+	).Default("").String()
+	scriptTimeout = app.Flag(
+		FlagScriptTimeout,
+		"Timeout of the scripts not setting their own.",
+	).Default("30s").Duration()
+	scriptMaxConcurrency = app.Flag(
+		FlagScriptMaxConcurrency,
+		"Maximum number of scripts running concurrently.",
+	).Default("4").Int()
+}
+
+// newScriptCollector ...
+func newScriptCollector() (Collector, error) {
+	var scripts []config.Script
+	if *scriptConfigFile == "" {
+		log.With(log.CollectorField, "script").Warn("No config file specified for script collector, no scripts will be run.")
+	} else {
+		c, err := config.LoadScriptConfig(*scriptConfigFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load script config file %s: %w", *scriptConfigFile, err)
+		}
+		scripts = c.Scripts
+	}
+
+	c := newScriptCollectorWith(scripts, *scriptTimeout, *scriptMaxConcurrency)
+	c.runner.Start()
+	return c, nil
+}
+
+func newScriptCollectorWith(scripts []config.Script, timeout time.Duration, maxConcurrency int) *scriptCollector {
+	const subsystem = "script"
+
+	logger := log.With(log.CollectorField, "script")
+	return &scriptCollector{
+		logger: logger,
+		ExitCode: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "exit_code"),
+			"Exit code of the last run of the script, -1 if it couldn't be started or timed out",
+			[]string{"script"},
+			nil,
+		),
+		Duration: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "duration_seconds"),
+			"Duration of the last run of the script",
+			[]string{"script"},
+			nil,
+		),
+		Success: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "success"),
+			"1 if the last run of the script exited with code 0 and its metrics were accepted, 0 otherwise",
+			[]string{"script"},
+			nil,
+		),
+		runner: script.NewRunner(scripts, timeout, maxConcurrency, logger),
+	}
+}
+
+// Close stops the scripts run on an interval, killing those running.
+func (c *scriptCollector) Close() error {
+	return c.runner.Close()
+}
+
+// Collect sends the metric values for each metric
+// to the provided prometheus Metric channel.
+func (c *scriptCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
+	// Scripts without interval run concurrently on every scrape, the others
+	// report their last result.
+	results := c.runner.RunAll(ctx.context())
+
+	merger := newMetricFamilyMerger("Metric returned by script")
+	for i, s := range c.runner.Scripts() {
+		result := results[i]
+		if result == nil {
+			// Not run yet.
+			continue
+		}
+
+		err := result.Err
+		if err == nil {
+			err = merger.add(s.Name, result.Families)
+		}
+		if err != nil {
+			c.logger.With("script", s.Name).WithError(err).Warn("Script failed")
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.ExitCode, prometheus.GaugeValue, float64(result.ExitCode), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.Duration, prometheus.GaugeValue, result.Duration.Seconds(), s.Name)
+		ch <- prometheus.MustNewConstMetric(c.Success, prometheus.GaugeValue, boolToFloat(err == nil), s.Name)
+	}
+	merger.collect(ch)
+
+	return nil
+}
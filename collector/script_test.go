@@ -0,0 +1,64 @@
+package collector
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/prometheus-community/windows_exporter/config"
+)
+
+// shellScript returns a script running the given shell command.
+func shellScript(t *testing.T, name, command string) config.Script {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required to run the test scripts")
+	}
+	return config.Script{Name: name, Command: "sh", Args: []string{"-c", command}}
+}
+
+func TestScriptCollector(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{
+		"metrics.prom": "# TYPE job_success gauge\njob_success{job=\"dir\"} 1\n",
+	})
+
+	workingDir := shellScript(t, "working_dir", "cat metrics.prom")
+	workingDir.WorkingDir = dir
+	slow := shellScript(t, "slow", "sleep 5")
+	slow.Timeout = 100 * time.Millisecond
+
+	c := newScriptCollectorWith([]config.Script{
+		shellScript(t, "ok", `printf '# TYPE job_success gauge\njob_success{job="ok"} 1\n'`),
+		shellScript(t, "failing", `echo 'job_success{job="failing"} 0'; exit 3`),
+		shellScript(t, "invalid", `echo 'job_success{job="invalid" 0'`),
+		shellScript(t, "duplicate", `echo 'job_success{job="ok"} 0'`),
+		workingDir,
+		slow,
+	}, time.Minute, 4)
+	values := collectValues(t, c)
+
+	expected := map[string]float64{
+		`job_success{job=ok}`:                        1,
+		`job_success{job=dir}`:                       1,
+		`windows_script_success{script=ok}`:          1,
+		`windows_script_exit_code{script=ok}`:        0,
+		`windows_script_success{script=failing}`:     0,
+		`windows_script_exit_code{script=failing}`:   3,
+		`windows_script_success{script=invalid}`:     0,
+		`windows_script_success{script=duplicate}`:   0,
+		`windows_script_success{script=working_dir}`: 1,
+		`windows_script_success{script=slow}`:        0,
+		`windows_script_exit_code{script=slow}`:      -1,
+	}
+	for key, value := range expected {
+		if v, ok := values[key]; !ok || v != value {
+			t.Errorf("Expected %s to be %v, got %v (present: %v)", key, value, v, ok)
+		}
+	}
+	if _, ok := values[`job_success{job=failing}`]; ok {
+		t.Error("Output of failing scripts must be ignored")
+	}
+	if d := values[`windows_script_duration_seconds{script=slow}`]; d >= 5 {
+		t.Errorf("Expected the slow script to be killed, took %vs", d)
+	}
+}
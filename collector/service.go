@@ -5,13 +5,17 @@ package collector
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
+	"unsafe"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/svc/mgr"
 )
@@ -19,21 +23,48 @@ import (
 const (
 	FlagServiceWhereClause = "collector.service.services-where"
 	FlagServiceUseAPI      = "collector.service.use-api"
+	FlagServiceUseNotify   = "collector.service.use-notifications"
+	FlagServiceExclude     = "collector.service.exclude"
+	FlagServiceInclude     = "collector.service.include"
 )
 
 var (
 	serviceWhereClause *string
 	useAPI             *bool
+	useNotifications   *bool
+	serviceInclude     *string
+	serviceExclude     *string
 )
 
 // A serviceCollector is a Prometheus collector for WMI Win32_Service metrics
 type serviceCollector struct {
-	Information *prometheus.Desc
-	State       *prometheus.Desc
-	StartMode   *prometheus.Desc
-	Status      *prometheus.Desc
+	logger log.Logger
+
+	Information        *prometheus.Desc
+	State              *prometheus.Desc
+	StartMode          *prometheus.Desc
+	Status             *prometheus.Desc
+	Type               *prometheus.Desc
+	StartTime          *prometheus.Desc
+	Restarts           *prometheus.Desc
+	DelayedAutoStart   *prometheus.Desc
+	TriggerStart       *prometheus.Desc
+	FailureActionDelay *prometheus.Desc
+	FailureResetPeriod *prometheus.Desc
+	StateChanges       *prometheus.Desc
 
 	queryWhereClause string
+	useAPI           bool
+
+	serviceIncludePattern *regexp.Regexp
+	serviceExcludePattern *regexp.Regexp
+
+	restarts *serviceRestarts
+
+	// The services are read from table, maintained by notifier, if set.
+	notifier serviceNotifier
+	table    *serviceTable
+	wg       sync.WaitGroup
 }
 
 // newServiceCollectorFlags ...
@@ -46,20 +77,52 @@ func newServiceCollectorFlags(app *kingpin.Application) {
 		FlagServiceUseAPI,
 		"Use API calls to collect service data instead of WMI. Flag 'collector.service.services-where' won't be effective.",
 	).Default("false").Bool()
+	useNotifications = app.Flag(
+		FlagServiceUseNotify,
+		"Maintain the services from the notifications of the service control manager instead of querying them on every scrape. Flag 'collector.service.services-where' won't be effective.",
+	).Default("false").Bool()
+	serviceInclude = app.Flag(
+		FlagServiceInclude,
+		"Regexp of services to include. Service name or display name must match include, and neither match exclude, to be included.",
+	).Default(".+").String()
+	serviceExclude = app.Flag(
+		FlagServiceExclude,
+		"Regexp of services to exclude. Service name or display name must match include, and neither match exclude, to be included.",
+	).Default("").String()
 }
 
 // newserviceCollector ...
 func newserviceCollector() (Collector, error) {
-	const subsystem = "service"
+	logger := log.With(log.CollectorField, "service")
 
-	if *serviceWhereClause == "" {
-		log.Warn("No where-clause specified for service collector. This will generate a very large number of metrics!")
+	if *serviceWhereClause == "" && *serviceInclude == ".+" && *serviceExclude == "" {
+		logger.Warn("No where-clause specified for service collector. This will generate a very large number of metrics!")
 	}
 	if *useAPI {
-		log.Warn("API collection is enabled.")
+		logger.Warn("API collection is enabled.")
 	}
 
-	return &serviceCollector{
+	var notifier serviceNotifier
+	if *useNotifications {
+		n, err := newSCMNotifier(logger)
+		if err != nil {
+			return nil, err
+		}
+		notifier = n
+	}
+
+	c := newServiceCollectorWith(*serviceWhereClause, *serviceInclude, *serviceExclude, notifier)
+	c.logger = logger
+	c.useAPI = *useAPI
+	c.start()
+	return c, nil
+}
+
+func newServiceCollectorWith(whereClause, include, exclude string, notifier serviceNotifier) *serviceCollector {
+	const subsystem = "service"
+
+	c := &serviceCollector{
+		logger: log.With(log.CollectorField, "service"),
 		Information: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "info"),
 			"A metric with a constant '1' value labeled with service information",
@@ -84,21 +147,108 @@ func newserviceCollector() (Collector, error) {
 			[]string{"name", "status"},
 			nil,
 		),
-		queryWhereClause: *serviceWhereClause,
-	}, nil
+		Type: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "type"),
+			"A metric with a constant '1' value labeled with the type of the service (ServiceType)",
+			[]string{"name", "type"},
+			nil,
+		),
+		StartTime: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "start_time_seconds"),
+			"The creation time of the process of the service, in seconds since the epoch",
+			[]string{"name"},
+			nil,
+		),
+		Restarts: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "restarts_total"),
+			"The number of times the process of the service changed since the exporter started",
+			[]string{"name"},
+			nil,
+		),
+		DelayedAutoStart: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "delayed_auto_start"),
+			"1 if the service is started after the other auto-start services, 0 otherwise",
+			[]string{"name"},
+			nil,
+		),
+		TriggerStart: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "trigger_start"),
+			"1 if the service is started or stopped by triggers, 0 otherwise",
+			[]string{"name"},
+			nil,
+		),
+		FailureActionDelay: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "failure_action_delay_seconds"),
+			"The delay before the action taken on the nth consecutive failure of the service, the last action being repeated on the next failures",
+			[]string{"name", "failure", "action"},
+			nil,
+		),
+		FailureResetPeriod: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "failure_reset_period_seconds"),
+			"The time without failure after which the failure count of the service is reset",
+			[]string{"name"},
+			nil,
+		),
+		StateChanges: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "state_changes_total"),
+			"The number of changes of the state of the service notified since the exporter started",
+			[]string{"name", "from", "to"},
+			nil,
+		),
+		queryWhereClause:      whereClause,
+		serviceIncludePattern: regexp.MustCompile(fmt.Sprintf("^(?:%s)$", include)),
+		serviceExcludePattern: regexp.MustCompile(fmt.Sprintf("^(?:%s)$", exclude)),
+		restarts:              newServiceRestarts(),
+		notifier:              notifier,
+	}
+	if notifier != nil {
+		c.table = newServiceTable()
+	}
+	return c
+}
+
+// start maintains the table of the services from the notifications, until
+// the collector is closed. The services are queried from the service control
+// manager until the table is synchronized or if the notifications stop.
+func (c *serviceCollector) start() {
+	if c.notifier == nil {
+		return
+	}
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		if err := c.notifier.Run(c.table.apply); err != nil {
+			c.logger.Errorf("Service notifications stopped, querying the services on every scrape: %v", err)
+		}
+		c.table.reset()
+	}()
+}
+
+// Close stops the notifications.
+func (c *serviceCollector) Close() error {
+	if c.notifier == nil {
+		return nil
+	}
+	err := c.notifier.Close()
+	c.wg.Wait()
+	return err
 }
 
 // Collect sends the metric values for each metric
 // to the provided prometheus Metric channel.
 func (c *serviceCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
-	if *useAPI {
-		if err := c.collectAPI(ch); err != nil {
-			log.Error("failed collecting API service metrics:", err)
+	// The service control manager API is only available for the local machine.
+	if c.table != nil && ctx.target == "" && c.collectTable(ctx, ch) {
+		return nil
+	}
+	if (c.useAPI || c.table != nil) && ctx.target == "" {
+		if err := c.collectAPI(ctx, ch); err != nil {
+			c.logger.Error("failed collecting API service metrics:", err)
 			return err
 		}
 	} else {
-		if err := c.collectWMI(ch); err != nil {
-			log.Error("failed collecting WMI service metrics:", err)
+		if err := c.collectWMI(ctx, ch); err != nil {
+			c.logger.Error("failed collecting WMI service metrics:", err)
 			return err
 		}
 	}
@@ -115,6 +265,7 @@ type Win32_Service struct {
 	Status      string
 	StartMode   string
 	StartName   *string
+	ServiceType string
 }
 
 var (
@@ -151,6 +302,20 @@ var (
 		windows.SERVICE_DISABLED:     "disabled",
 		windows.SERVICE_SYSTEM_START: "system",
 	}
+	apiServiceTypeValues = map[uint32]string{
+		windows.SERVICE_KERNEL_DRIVER:       "kernel driver",
+		windows.SERVICE_FILE_SYSTEM_DRIVER:  "file system driver",
+		windows.SERVICE_ADAPTER:             "adapter",
+		windows.SERVICE_RECOGNIZER_DRIVER:   "recognizer driver",
+		windows.SERVICE_WIN32_OWN_PROCESS:   "own process",
+		windows.SERVICE_WIN32_SHARE_PROCESS: "share process",
+	}
+	apiFailureActionValues = map[int]string{
+		mgr.NoAction:       "none",
+		mgr.ServiceRestart: "restart",
+		mgr.ComputerReboot: "reboot",
+		mgr.RunCommand:     "run command",
+	}
 	allStatuses = []string{
 		"ok",
 		"error",
@@ -167,13 +332,17 @@ var (
 	}
 )
 
-func (c *serviceCollector) collectWMI(ch chan<- prometheus.Metric) error {
+func (c *serviceCollector) collectWMI(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	var dst []Win32_Service
-	q := queryAllWhere(&dst, c.queryWhereClause)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAllWhere(&dst, c.queryWhereClause, c.logger)
+	if err := ctx.wmiQuery(q, &dst); err != nil {
 		return err
 	}
+	defer c.restarts.prune(time.Now())
 	for _, service := range dst {
+		if !c.serviceIncluded(strings.ToLower(service.Name), service.DisplayName) {
+			continue
+		}
 		pid := fmt.Sprintf("%d", uint64(service.ProcessId))
 
 		runAs := ""
@@ -231,11 +400,25 @@ func (c *serviceCollector) collectWMI(ch chan<- prometheus.Metric) error {
 				status,
 			)
 		}
+
+		ch <- prometheus.MustNewConstMetric(
+			c.Type,
+			prometheus.GaugeValue,
+			1.0,
+			strings.ToLower(service.Name),
+			strings.ToLower(service.ServiceType),
+		)
+
+		var startTime time.Time
+		if ctx.target == "" {
+			startTime = serviceStartTime(strings.ToLower(service.Name), service.ProcessId, c.logger)
+		}
+		c.collectProcess(ctx, ch, strings.ToLower(service.Name), service.ProcessId, startTime)
 	}
 	return nil
 }
 
-func (c *serviceCollector) collectAPI(ch chan<- prometheus.Metric) error {
+func (c *serviceCollector) collectAPI(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	svcmgrConnection, err := mgr.Connect()
 	if err != nil {
 		return err
@@ -249,78 +432,379 @@ func (c *serviceCollector) collectAPI(ch chan<- prometheus.Metric) error {
 	}
 
 	// Iterate through the Services List.
+	defer c.restarts.prune(time.Now())
 	for _, service := range serviceList {
-		// Get UTF16 service name.
-		serviceName, err := syscall.UTF16PtrFromString(service)
-		if err != nil {
-			log.Warnf("Service %s get name error:  %#v", service, err)
-			continue
+		if err := c.collectAPIService(ctx, ch, svcmgrConnection, service); err != nil {
+			c.logger.Warnf("Service %s: %v", service, err)
 		}
+	}
+	return nil
+}
 
-		// Open connection for service handler.
-		serviceHandle, err := windows.OpenService(svcmgrConnection.Handle, serviceName, windows.GENERIC_READ)
+func (c *serviceCollector) collectAPIService(ctx *ScrapeContext, ch chan<- prometheus.Metric, svcmgrConnection *mgr.Mgr, service string) error {
+	// Services excluded by name aren't opened.
+	name := strings.ToLower(service)
+	if c.serviceExcludePattern.MatchString(name) {
+		return nil
+	}
+
+	// Get UTF16 service name.
+	serviceName, err := syscall.UTF16PtrFromString(service)
+	if err != nil {
+		return fmt.Errorf("get name error: %w", err)
+	}
+
+	// Open connection for service handler.
+	serviceHandle, err := windows.OpenService(svcmgrConnection.Handle, serviceName, windows.GENERIC_READ)
+	if err != nil {
+		return fmt.Errorf("open service error: %w", err)
+	}
+
+	// Create handle for each service.
+	serviceManager := &mgr.Service{Name: service, Handle: serviceHandle}
+	defer serviceManager.Close()
+
+	// The display name is matched against the filters before reading the
+	// rest of the service.
+	var s apiService
+	s.Config, err = serviceManager.Config()
+	if err != nil {
+		return fmt.Errorf("get service config error: %w", err)
+	}
+	if !c.serviceIncluded(name, s.Config.DisplayName) {
+		return nil
+	}
+	if err := readAPIServiceStatus(serviceManager, &s, c.logger); err != nil {
+		return err
+	}
+	if ctx.target == "" {
+		s.StartTime = serviceStartTime(name, s.ProcessID, c.logger)
+	}
+	c.sendAPIService(ctx, ch, name, s)
+	return nil
+}
+
+// serviceIncluded returns whether the service, given its name as reported in
+// the name label, is included by the filters. An empty display name is
+// ignored, as the default exclude pattern matches it.
+func (c *serviceCollector) serviceIncluded(name, displayName string) bool {
+	matches := func(pattern *regexp.Regexp) bool {
+		return pattern.MatchString(name) || (displayName != "" && pattern.MatchString(displayName))
+	}
+	return matches(c.serviceIncludePattern) && !matches(c.serviceExcludePattern)
+}
+
+// apiService is the configuration and status of a service, read from the
+// service control manager.
+type apiService struct {
+	Config    mgr.Config
+	State     uint32
+	ProcessID uint32
+	// TriggerStart is nil if the triggers couldn't be read.
+	TriggerStart    *bool
+	RecoveryActions []mgr.RecoveryAction
+	ResetPeriod     uint32
+	// StartTime is the creation time of the process of the service, zero if
+	// it isn't running or the time couldn't be read.
+	StartTime time.Time
+}
+
+func readAPIService(serviceManager *mgr.Service, logger log.Logger) (apiService, error) {
+	var s apiService
+	var err error
+
+	// Get Service Configuration.
+	s.Config, err = serviceManager.Config()
+	if err != nil {
+		return s, fmt.Errorf("get service config error: %w", err)
+	}
+	err = readAPIServiceStatus(serviceManager, &s, logger)
+	return s, err
+}
+
+// readAPIServiceStatus reads the status, triggers and recovery actions of the
+// service into s.
+func readAPIServiceStatus(serviceManager *mgr.Service, s *apiService, logger log.Logger) error {
+	// Get Service Current Status.
+	serviceStatus, err := serviceManager.Query()
+	if err != nil {
+		return fmt.Errorf("get service status error: %w", err)
+	}
+	s.State = uint32(serviceStatus.State)
+	s.ProcessID = serviceStatus.ProcessId
+
+	triggerStart, err := serviceTriggerStart(serviceManager.Handle)
+	if err != nil {
+		logger.Debugf("Failed to get triggers of service %s: %v", serviceManager.Name, err)
+	} else {
+		s.TriggerStart = &triggerStart
+	}
+
+	s.RecoveryActions, err = serviceManager.RecoveryActions()
+	if err != nil {
+		logger.Debugf("Failed to get recovery actions of service %s: %v", serviceManager.Name, err)
+	} else if len(s.RecoveryActions) > 0 {
+		s.ResetPeriod, err = serviceManager.ResetPeriod()
 		if err != nil {
-			log.Warnf("Open service %s error:  %#v", service, err)
-			continue
+			logger.Debugf("Failed to get recovery reset period of service %s: %v", serviceManager.Name, err)
+			s.RecoveryActions = nil
 		}
+	}
+	return nil
+}
 
-		// Create handle for each service.
-		serviceManager := &mgr.Service{Name: service, Handle: serviceHandle}
-		defer serviceManager.Close()
+func (c *serviceCollector) sendAPIService(ctx *ScrapeContext, ch chan<- prometheus.Metric, name string, s apiService) {
+	pid := fmt.Sprintf("%d", uint64(s.ProcessID))
 
-		// Get Service Configuration.
-		serviceConfig, err := serviceManager.Config()
-		if err != nil {
-			log.Warnf("Get ervice %s config error:  %#v", service, err)
-			continue
+	ch <- prometheus.MustNewConstMetric(
+		c.Information,
+		prometheus.GaugeValue,
+		1.0,
+		name,
+		s.Config.DisplayName,
+		pid,
+		s.Config.ServiceStartName,
+	)
+
+	for _, state := range apiStateValues {
+		isCurrentState := 0.0
+		if state == apiStateValues[uint(s.State)] {
+			isCurrentState = 1.0
 		}
+		ch <- prometheus.MustNewConstMetric(
+			c.State,
+			prometheus.GaugeValue,
+			isCurrentState,
+			name,
+			state,
+		)
+	}
 
-		// Get Service Current Status.
-		serviceStatus, err := serviceManager.Query()
-		if err != nil {
-			log.Warnf("Get service %s status error:  %#v", service, err)
-			continue
+	for _, startMode := range apiStartModeValues {
+		isCurrentStartMode := 0.0
+		if startMode == apiStartModeValues[s.Config.StartType] {
+			isCurrentStartMode = 1.0
 		}
+		ch <- prometheus.MustNewConstMetric(
+			c.StartMode,
+			prometheus.GaugeValue,
+			isCurrentStartMode,
+			name,
+			startMode,
+		)
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		c.Type,
+		prometheus.GaugeValue,
+		1.0,
+		name,
+		apiServiceType(s.Config.ServiceType),
+	)
+
+	ch <- prometheus.MustNewConstMetric(
+		c.DelayedAutoStart,
+		prometheus.GaugeValue,
+		boolToFloat(s.Config.DelayedAutoStart),
+		name,
+	)
 
-		pid := fmt.Sprintf("%d", uint64(serviceStatus.ProcessId))
+	c.collectProcess(ctx, ch, name, s.ProcessID, s.StartTime)
 
+	if s.TriggerStart != nil {
 		ch <- prometheus.MustNewConstMetric(
-			c.Information,
+			c.TriggerStart,
 			prometheus.GaugeValue,
-			1.0,
-			strings.ToLower(service),
-			serviceConfig.DisplayName,
-			pid,
-			serviceConfig.ServiceStartName,
+			boolToFloat(*s.TriggerStart),
+			name,
 		)
+	}
 
-		for _, state := range apiStateValues {
-			isCurrentState := 0.0
-			if state == apiStateValues[uint(serviceStatus.State)] {
-				isCurrentState = 1.0
-			}
-			ch <- prometheus.MustNewConstMetric(
-				c.State,
-				prometheus.GaugeValue,
-				isCurrentState,
-				strings.ToLower(service),
-				state,
-			)
+	for i, action := range s.RecoveryActions {
+		ch <- prometheus.MustNewConstMetric(
+			c.FailureActionDelay,
+			prometheus.GaugeValue,
+			action.Delay.Seconds(),
+			name,
+			strconv.Itoa(i+1),
+			apiFailureActionValues[action.Type],
+		)
+	}
+	if len(s.RecoveryActions) > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.FailureResetPeriod,
+			prometheus.GaugeValue,
+			float64(s.ResetPeriod),
+			name,
+		)
+	}
+}
+
+// collectTable sends the metrics of the services of the table, returning
+// false if it isn't synchronized.
+func (c *serviceCollector) collectTable(ctx *ScrapeContext, ch chan<- prometheus.Metric) bool {
+	services, changes, ok := c.table.snapshot()
+	if !ok {
+		return false
+	}
+
+	defer c.restarts.prune(time.Now())
+	for name, s := range services {
+		if c.serviceIncluded(name, s.Config.DisplayName) {
+			c.sendAPIService(ctx, ch, name, s)
+		}
+	}
+	for t, count := range changes {
+		if !c.serviceIncluded(t.name, services[t.name].Config.DisplayName) {
+			continue
 		}
+		ch <- prometheus.MustNewConstMetric(
+			c.StateChanges,
+			prometheus.CounterValue,
+			count,
+			t.name,
+			t.from,
+			t.to,
+		)
+	}
+	return true
+}
 
-		for _, startMode := range apiStartModeValues {
-			isCurrentStartMode := 0.0
-			if startMode == apiStartModeValues[serviceConfig.StartType] {
-				isCurrentStartMode = 1.0
-			}
-			ch <- prometheus.MustNewConstMetric(
-				c.StartMode,
-				prometheus.GaugeValue,
-				isCurrentStartMode,
-				strings.ToLower(service),
-				startMode,
-			)
+// collectProcess sends the start time of the process of the service, unless
+// zero, and its restarts.
+func (c *serviceCollector) collectProcess(ctx *ScrapeContext, ch chan<- prometheus.Metric, name string, pid uint32, startTime time.Time) {
+	ch <- prometheus.MustNewConstMetric(
+		c.Restarts,
+		prometheus.CounterValue,
+		c.restarts.observe(ctx.target, name, pid, time.Now()),
+		name,
+	)
+
+	if startTime.IsZero() {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		c.StartTime,
+		prometheus.GaugeValue,
+		float64(startTime.UnixNano())/1e9,
+		name,
+	)
+}
+
+// apiServiceType returns the name of the type of a service, as reported by
+// WMI.
+func apiServiceType(serviceType uint32) string {
+	// Interactive services are otherwise own or share process services.
+	if name, ok := apiServiceTypeValues[serviceType&^windows.SERVICE_INTERACTIVE_PROCESS]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// serviceStartTime returns the creation time of the process of the service
+// on the local machine, zero if it isn't running or the time couldn't be
+// read.
+func serviceStartTime(name string, pid uint32, logger log.Logger) time.Time {
+	if pid == 0 {
+		return time.Time{}
+	}
+	startTime, err := processStartTime(pid)
+	if err != nil {
+		logger.Debugf("Failed to get start time of process %d of service %s: %v", pid, name, err)
+	}
+	return startTime
+}
+
+// processStartTime returns the creation time of the process.
+func processStartTime(pid uint32) (time.Time, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer windows.CloseHandle(h) //nolint:errcheck
+
+	var creation, exit, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(h, &creation, &exit, &kernel, &user); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
+
+// serviceTriggerStart returns whether the service has triggers.
+func serviceTriggerStart(h windows.Handle) (bool, error) {
+	n := uint32(1024)
+	for {
+		b := make([]byte, n)
+		err := windows.QueryServiceConfig2(h, windows.SERVICE_CONFIG_TRIGGER_INFO, &b[0], n, &n)
+		if err == nil {
+			// SERVICE_TRIGGER_INFO starts with the number of triggers.
+			return *(*uint32)(unsafe.Pointer(&b[0])) > 0, nil
+		}
+		if err != windows.ERROR_INSUFFICIENT_BUFFER || n <= uint32(len(b)) {
+			return false, err
+		}
+	}
+}
+
+// serviceRestartsExpiry is the time after which services no longer seen are
+// forgotten.
+const serviceRestartsExpiry = time.Hour
+
+type serviceKey struct {
+	target string
+	name   string
+}
+
+type serviceProcess struct {
+	// pid is the last process the service ran in.
+	pid      uint32
+	restarts float64
+	seen     time.Time
+}
+
+// serviceRestarts counts the restarts of the services, seen as changes of
+// their process between scrapes.
+type serviceRestarts struct {
+	mu       sync.Mutex
+	services map[serviceKey]*serviceProcess
+}
+
+func newServiceRestarts() *serviceRestarts {
+	return &serviceRestarts{services: map[serviceKey]*serviceProcess{}}
+}
+
+// observe records the process the service of the target runs in, 0 if
+// stopped, and returns its restarts.
+func (r *serviceRestarts) observe(target, name string, pid uint32, now time.Time) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := serviceKey{target, name}
+	p, ok := r.services[key]
+	if !ok {
+		p = &serviceProcess{pid: pid}
+		r.services[key] = p
+	}
+	p.seen = now
+	if pid == 0 {
+		return p.restarts
+	}
+	// Stopping and starting the service counts as a restart too.
+	if p.pid != 0 && p.pid != pid {
+		p.restarts++
+	}
+	p.pid = pid
+	return p.restarts
+}
+
+// prune forgets the services not seen for serviceRestartsExpiry.
+func (r *serviceRestarts) prune(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, p := range r.services {
+		if now.Sub(p.seen) > serviceRestartsExpiry {
+			delete(r.services, key)
 		}
 	}
-	return nil
 }
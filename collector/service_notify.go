@@ -0,0 +1,404 @@
+//go:build windows
+// +build windows
+
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/prometheus-community/windows_exporter/log"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// A serviceNotification reports a service or the end of the initial listing
+// of the services.
+type serviceNotification struct {
+	Name    string
+	Service apiService
+	// Deleted is set once the service was deleted.
+	Deleted bool
+	// Synced is set once all the services were notified.
+	Synced bool
+}
+
+// A serviceNotifier notifies the services and the changes of their status.
+type serviceNotifier interface {
+	// Run notifies the services, then a Synced notification, then the
+	// changes of the services until closed.
+	Run(notify func(serviceNotification)) error
+	Close() error
+}
+
+type serviceTransition struct {
+	name string
+	from string
+	to   string
+}
+
+// serviceTable maintains the services from the notifications, along with
+// the changes of their states.
+type serviceTable struct {
+	mu       sync.Mutex
+	synced   bool
+	services map[string]apiService
+	changes  map[serviceTransition]float64
+}
+
+func newServiceTable() *serviceTable {
+	return &serviceTable{
+		services: map[string]apiService{},
+		changes:  map[serviceTransition]float64{},
+	}
+}
+
+func (t *serviceTable) apply(n serviceNotification) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	name := strings.ToLower(n.Name)
+	switch {
+	case n.Synced:
+		t.synced = true
+	case n.Deleted:
+		delete(t.services, name)
+		for transition := range t.changes {
+			if transition.name == name {
+				delete(t.changes, transition)
+			}
+		}
+	default:
+		if old, ok := t.services[name]; ok && old.State != n.Service.State {
+			t.changes[serviceTransition{name, apiStateValues[uint(old.State)], apiStateValues[uint(n.Service.State)]}]++
+		}
+		t.services[name] = n.Service
+	}
+}
+
+// reset empties the table once the notifications stopped.
+func (t *serviceTable) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.synced = false
+	t.services = map[string]apiService{}
+	t.changes = map[serviceTransition]float64{}
+}
+
+// snapshot returns copies of the services and of the state changes, and
+// whether the table is synchronized.
+func (t *serviceTable) snapshot() (map[string]apiService, map[serviceTransition]float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.synced {
+		return nil, nil, false
+	}
+	services := make(map[string]apiService, len(t.services))
+	for name, s := range t.services {
+		services[name] = s
+	}
+	changes := make(map[serviceTransition]float64, len(t.changes))
+	for transition, count := range t.changes {
+		changes[transition] = count
+	}
+	return services, changes, true
+}
+
+const (
+	// serviceResyncInterval is the interval at which all the services are
+	// read again, refreshing their configuration, which isn't notified.
+	serviceResyncInterval = 5 * time.Minute
+
+	serviceNotifyStates = windows.SERVICE_NOTIFY_STOPPED | windows.SERVICE_NOTIFY_START_PENDING |
+		windows.SERVICE_NOTIFY_STOP_PENDING | windows.SERVICE_NOTIFY_RUNNING | windows.SERVICE_NOTIFY_CONTINUE_PENDING |
+		windows.SERVICE_NOTIFY_PAUSE_PENDING | windows.SERVICE_NOTIFY_PAUSED
+)
+
+var (
+	procWaitForSingleObjectEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("WaitForSingleObjectEx")
+
+	serviceNotifyCallbackOnce sync.Once
+	serviceNotifyCallback     uintptr
+	// serviceNotifyFired holds the addresses of the SERVICE_NOTIFY whose
+	// callback ran.
+	serviceNotifyMu    sync.Mutex
+	serviceNotifyFired = map[uintptr]bool{}
+)
+
+// serviceWatch is a registration for the status changes of a service.
+type serviceWatch struct {
+	service *mgr.Service
+	s       apiService
+	notify  windows.SERVICE_NOTIFY
+}
+
+// scmNotifier notifies the services from the notifications of the service
+// control manager, delivered to the thread running Run while it waits.
+type scmNotifier struct {
+	logger log.Logger
+	stop   windows.Handle
+	// done is closed once Run returned.
+	done chan struct{}
+
+	scm     *mgr.Mgr
+	scmWait windows.SERVICE_NOTIFY
+	watches map[string]*serviceWatch
+}
+
+func newSCMNotifier(logger log.Logger) (*scmNotifier, error) {
+	stop, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	serviceNotifyCallbackOnce.Do(func() {
+		serviceNotifyCallback = windows.NewCallback(func(notify uintptr) uintptr {
+			serviceNotifyMu.Lock()
+			serviceNotifyFired[notify] = true
+			serviceNotifyMu.Unlock()
+			return 0
+		})
+	})
+	return &scmNotifier{logger: logger, stop: stop, done: make(chan struct{}), watches: map[string]*serviceWatch{}}, nil
+}
+
+// fired returns whether the callback of the notification ran, resetting it.
+func fired(notify *windows.SERVICE_NOTIFY) bool {
+	serviceNotifyMu.Lock()
+	defer serviceNotifyMu.Unlock()
+
+	addr := uintptr(unsafe.Pointer(notify))
+	if !serviceNotifyFired[addr] {
+		return false
+	}
+	delete(serviceNotifyFired, addr)
+	return true
+}
+
+func (n *scmNotifier) Run(notify func(serviceNotification)) error {
+	// The callbacks are run by the thread registering the notifications.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer close(n.done)
+
+	h, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT|windows.SC_MANAGER_ENUMERATE_SERVICE)
+	if err != nil {
+		return err
+	}
+	n.scm = &mgr.Mgr{Handle: h}
+	defer func() {
+		for name, w := range n.watches {
+			w.service.Close()
+			fired(&w.notify)
+			delete(n.watches, name)
+		}
+		n.scm.Disconnect() //nolint:errcheck
+		fired(&n.scmWait)
+	}()
+
+	if err := n.watchSCM(); err != nil {
+		return err
+	}
+	if err := n.resync(notify); err != nil {
+		return err
+	}
+	notify(serviceNotification{Synced: true})
+
+	nextResync := time.Now().Add(serviceResyncInterval)
+	for {
+		timeout := time.Until(nextResync)
+		if timeout < 0 {
+			timeout = 0
+		}
+		r, _, err := procWaitForSingleObjectEx.Call(uintptr(n.stop), uintptr(timeout/time.Millisecond), 1)
+		switch uint32(r) {
+		case windows.WAIT_OBJECT_0:
+			return nil
+		case windows.WAIT_IO_COMPLETION:
+			if fired(&n.scmWait) {
+				// A service was created or deleted.
+				if n.scmWait.ServiceNames != nil {
+					windows.LocalFree(windows.Handle(unsafe.Pointer(n.scmWait.ServiceNames))) //nolint:errcheck
+				}
+				if err := n.watchSCM(); err != nil {
+					return err
+				}
+				if err := n.resync(notify); err != nil {
+					return err
+				}
+			}
+			if !n.processWatches(notify) {
+				if err := n.resync(notify); err != nil {
+					return err
+				}
+			}
+		case uint32(windows.WAIT_TIMEOUT):
+			if err := n.resync(notify); err != nil {
+				return err
+			}
+			nextResync = time.Now().Add(serviceResyncInterval)
+		default:
+			return fmt.Errorf("failed to wait for service notifications: %w", err)
+		}
+	}
+}
+
+// Close stops Run, which must have been called.
+func (n *scmNotifier) Close() error {
+	if err := windows.SetEvent(n.stop); err != nil {
+		return err
+	}
+	<-n.done
+	return windows.CloseHandle(n.stop)
+}
+
+// watchSCM registers for the creation and deletion of services.
+func (n *scmNotifier) watchSCM() error {
+	n.scmWait = windows.SERVICE_NOTIFY{
+		Version:        windows.SERVICE_NOTIFY_STATUS_CHANGE,
+		NotifyCallback: serviceNotifyCallback,
+	}
+	if err := windows.NotifyServiceStatusChange(n.scm.Handle, windows.SERVICE_NOTIFY_CREATED|windows.SERVICE_NOTIFY_DELETED, &n.scmWait); err != nil {
+		return fmt.Errorf("failed to register for service notifications: %w", err)
+	}
+	return nil
+}
+
+// resync reads all the services, watching the new ones and forgetting those
+// deleted.
+func (n *scmNotifier) resync(notify func(serviceNotification)) error {
+	names, err := n.scm.ListServices()
+	if err != nil {
+		return err
+	}
+
+	listed := make(map[string]bool, len(names))
+	for _, name := range names {
+		listed[name] = true
+		w, ok := n.watches[name]
+		if !ok {
+			if w, err = n.watch(name); err != nil {
+				n.logger.Debugf("Failed to watch service %s: %v", name, err)
+				continue
+			}
+			n.watches[name] = w
+		} else {
+			previous := w.s
+			if w.s, err = readAPIService(w.service, n.logger); err != nil {
+				n.logger.Debugf("Failed to read service %s: %v", name, err)
+				continue
+			}
+			n.updateStartTime(name, w, previous)
+		}
+		notify(serviceNotification{Name: name, Service: w.s})
+	}
+
+	for name, w := range n.watches {
+		if !listed[name] {
+			n.unwatch(name, w, notify)
+		}
+	}
+	return nil
+}
+
+// watch opens the service and registers for the changes of its status.
+func (n *scmNotifier) watch(name string) (*serviceWatch, error) {
+	serviceName, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+	h, err := windows.OpenService(n.scm.Handle, serviceName, windows.GENERIC_READ)
+	if err != nil {
+		return nil, err
+	}
+	service := &mgr.Service{Name: name, Handle: h}
+	w := &serviceWatch{service: service}
+	if w.s, err = readAPIService(service, n.logger); err != nil {
+		service.Close()
+		return nil, err
+	}
+	w.s.StartTime = serviceStartTime(name, w.s.ProcessID, n.logger)
+	if err := n.register(w); err != nil {
+		service.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// register registers for the changes of the status of the service from its
+// current state, of which the callback would be run immediately otherwise.
+func (n *scmNotifier) register(w *serviceWatch) error {
+	w.notify = windows.SERVICE_NOTIFY{
+		Version:        windows.SERVICE_NOTIFY_STATUS_CHANGE,
+		NotifyCallback: serviceNotifyCallback,
+	}
+	mask := uint32(serviceNotifyStates) | windows.SERVICE_NOTIFY_DELETE_PENDING
+	if w.s.State > 0 {
+		mask &^= 1 << (w.s.State - 1)
+	}
+	return windows.NotifyServiceStatusChange(w.service.Handle, mask, &w.notify)
+}
+
+// unwatch closes the service, notifying its deletion if deleted.
+func (n *scmNotifier) unwatch(name string, w *serviceWatch, notify func(serviceNotification)) {
+	w.service.Close()
+	fired(&w.notify)
+	delete(n.watches, name)
+	if notify != nil {
+		notify(serviceNotification{Name: name, Deleted: true})
+	}
+}
+
+// processWatches notifies the services whose status changed and registers
+// for their next change. It returns false if services must be watched again
+// by a resync.
+func (n *scmNotifier) processWatches(notify func(serviceNotification)) bool {
+	ok := true
+	for name, w := range n.watches {
+		if !fired(&w.notify) {
+			continue
+		}
+		if w.notify.NotificationTriggered&windows.SERVICE_NOTIFY_DELETE_PENDING != 0 ||
+			w.notify.NotificationStatus == uint32(windows.ERROR_SERVICE_MARKED_FOR_DELETE) {
+			n.unwatch(name, w, notify)
+			continue
+		}
+
+		err := error(windows.Errno(w.notify.NotificationStatus))
+		if w.notify.NotificationStatus == uint32(windows.NO_ERROR) {
+			previous := w.s
+			w.s.State = w.notify.ServiceStatus.CurrentState
+			w.s.ProcessID = w.notify.ServiceStatus.ProcessId
+			n.updateStartTime(name, w, previous)
+			notify(serviceNotification{Name: name, Service: w.s})
+			err = n.register(w)
+		}
+		if errors.Is(err, windows.ERROR_SERVICE_MARKED_FOR_DELETE) {
+			n.unwatch(name, w, notify)
+		} else if err != nil {
+			// E.g. ERROR_SERVICE_NOTIFY_CLIENT_LAGGING, the service is
+			// opened again by the resync.
+			n.logger.Debugf("Failed to watch service %s: %v", name, err)
+			n.unwatch(name, w, nil)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// updateStartTime reads the start time of the process of the service once
+// it runs in a new process, keeping the previous one otherwise, so that the
+// process isn't opened on every scrape.
+func (n *scmNotifier) updateStartTime(name string, w *serviceWatch, previous apiService) {
+	if w.s.ProcessID == previous.ProcessID {
+		w.s.StartTime = previous.StartTime
+		return
+	}
+	w.s.StartTime = serviceStartTime(name, w.s.ProcessID, n.logger)
+}
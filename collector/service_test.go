@@ -1,9 +1,258 @@
 package collector
 
 import (
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/sys/windows"
 )
 
 func BenchmarkServiceCollector(b *testing.B) {
 	benchmarkCollector(b, "service", newserviceCollector)
 }
+
+func TestServiceRestarts(t *testing.T) {
+	r := newServiceRestarts()
+	now := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		pid      uint32
+		restarts float64
+	}{
+		{100, 0},
+		{100, 0},
+		// Crashed and restarted by the service control manager.
+		{200, 1},
+		// Stopped, then started again.
+		{0, 1},
+		{300, 2},
+	}
+	for i, step := range steps {
+		if restarts := r.observe("", "spooler", step.pid, now); restarts != step.restarts {
+			t.Errorf("step %d: expected %v restarts, got %v", i, step.restarts, restarts)
+		}
+	}
+
+	// Services are tracked by target.
+	if restarts := r.observe("appliance01", "spooler", 400, now); restarts != 0 {
+		t.Errorf("expected no restarts of the service of another target, got %v", restarts)
+	}
+
+	r.prune(now.Add(serviceRestartsExpiry / 2))
+	if len(r.services) != 2 {
+		t.Errorf("expected the services recently seen to be kept, got %d", len(r.services))
+	}
+	r.observe("", "spooler", 300, now.Add(serviceRestartsExpiry))
+	r.prune(now.Add(serviceRestartsExpiry + time.Minute))
+	if _, ok := r.services[serviceKey{"appliance01", "spooler"}]; ok || len(r.services) != 1 {
+		t.Errorf("expected the services no longer seen to be forgotten, got %v", r.services)
+	}
+}
+
+func TestAPIServiceType(t *testing.T) {
+	cases := map[uint32]string{
+		windows.SERVICE_WIN32_OWN_PROCESS:                                       "own process",
+		windows.SERVICE_WIN32_SHARE_PROCESS:                                     "share process",
+		windows.SERVICE_WIN32_OWN_PROCESS | windows.SERVICE_INTERACTIVE_PROCESS: "own process",
+		windows.SERVICE_KERNEL_DRIVER:                                           "kernel driver",
+		0x1000:                                                                  "unknown",
+	}
+	for serviceType, expected := range cases {
+		if got := apiServiceType(serviceType); got != expected {
+			t.Errorf("expected type %#x to be %q, got %q", serviceType, expected, got)
+		}
+	}
+}
+
+// fakeServiceNotifier sends the notifications, then blocks until closed.
+type fakeServiceNotifier struct {
+	notifications []serviceNotification
+	stop          chan struct{}
+}
+
+func (n *fakeServiceNotifier) Run(notify func(serviceNotification)) error {
+	for _, notification := range n.notifications {
+		notify(notification)
+	}
+	<-n.stop
+	return nil
+}
+
+func (n *fakeServiceNotifier) Close() error {
+	close(n.stop)
+	return nil
+}
+
+func serviceInState(state uint32) apiService {
+	return apiService{State: state}
+}
+
+func TestServiceTable(t *testing.T) {
+	table := newServiceTable()
+	table.apply(serviceNotification{Name: "Spooler", Service: serviceInState(windows.SERVICE_RUNNING)})
+	table.apply(serviceNotification{Name: "W32Time", Service: serviceInState(windows.SERVICE_RUNNING)})
+	if _, _, ok := table.snapshot(); ok {
+		t.Fatal("expected the table not to be synchronized before all the services were notified")
+	}
+
+	table.apply(serviceNotification{Synced: true})
+	table.apply(serviceNotification{Name: "Spooler", Service: serviceInState(windows.SERVICE_STOPPED)})
+	table.apply(serviceNotification{Name: "Spooler", Service: serviceInState(windows.SERVICE_RUNNING)})
+	table.apply(serviceNotification{Name: "Spooler", Service: serviceInState(windows.SERVICE_STOPPED)})
+	// Resyncs notify the services in the same state again.
+	table.apply(serviceNotification{Name: "W32Time", Service: serviceInState(windows.SERVICE_RUNNING)})
+
+	services, changes, ok := table.snapshot()
+	if !ok {
+		t.Fatal("expected the table to be synchronized")
+	}
+	if len(services) != 2 || services["spooler"].State != windows.SERVICE_STOPPED {
+		t.Errorf("unexpected services %v", services)
+	}
+	expected := map[serviceTransition]float64{
+		{"spooler", "running", "stopped"}: 2,
+		{"spooler", "stopped", "running"}: 1,
+	}
+	if len(changes) != len(expected) {
+		t.Errorf("expected state changes %v, got %v", expected, changes)
+	}
+	for transition, count := range expected {
+		if changes[transition] != count {
+			t.Errorf("expected %v changes %v, got %v", count, transition, changes[transition])
+		}
+	}
+
+	// The snapshot is a copy.
+	delete(services, "spooler")
+	changes[serviceTransition{"w32time", "running", "stopped"}] = 1
+
+	table.apply(serviceNotification{Name: "Spooler", Deleted: true})
+	services, changes, _ = table.snapshot()
+	if _, ok := services["spooler"]; ok || len(services) != 1 {
+		t.Errorf("expected the deleted service to be removed, got %v", services)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected the state changes of the deleted service to be removed, got %v", changes)
+	}
+
+	table.reset()
+	if _, _, ok := table.snapshot(); ok {
+		t.Error("expected the table not to be synchronized once reset")
+	}
+}
+
+func TestServiceCollectorNotifications(t *testing.T) {
+	notifier := &fakeServiceNotifier{
+		notifications: []serviceNotification{
+			{Name: "Spooler", Service: serviceInState(windows.SERVICE_RUNNING)},
+			{Synced: true},
+			{Name: "Spooler", Service: serviceInState(windows.SERVICE_STOPPED)},
+		},
+		stop: make(chan struct{}),
+	}
+	c := newServiceCollectorWith("", ".+", "", notifier)
+	c.start()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, changes, ok := c.table.snapshot(); ok && len(changes) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the notifications")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ch := make(chan prometheus.Metric, 100)
+	if !c.collectTable(&ScrapeContext{}, ch) {
+		t.Fatal("expected the services to be collected from the table")
+	}
+	close(ch)
+	var found bool
+	for m := range ch {
+		if m.Desc() != c.StateChanges {
+			continue
+		}
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		labels := map[string]string{}
+		for _, l := range pb.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if labels["name"] != "spooler" || labels["from"] != "running" || labels["to"] != "stopped" || pb.GetCounter().GetValue() != 1 {
+			t.Errorf("unexpected state change %v", &pb)
+		}
+		found = true
+	}
+	if !found {
+		t.Error("expected the state change to be reported")
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// The services are queried again once the notifications stopped.
+	if c.collectTable(&ScrapeContext{}, make(chan prometheus.Metric, 100)) {
+		t.Error("expected the table to be reset once closed")
+	}
+}
+
+func TestServiceCollectorTableStartTime(t *testing.T) {
+	c := newServiceCollectorWith("", ".+", "", &fakeServiceNotifier{stop: make(chan struct{})})
+	startTime := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	// The process doesn't exist, the start time is read from the table only.
+	c.table.apply(serviceNotification{Name: "Spooler", Service: apiService{State: windows.SERVICE_RUNNING, ProcessID: 0xFFFFFFF0, StartTime: startTime}})
+	c.table.apply(serviceNotification{Name: "W32Time", Service: serviceInState(windows.SERVICE_STOPPED)})
+	c.table.apply(serviceNotification{Synced: true})
+
+	ch := make(chan prometheus.Metric, 100)
+	if !c.collectTable(&ScrapeContext{}, ch) {
+		t.Fatal("expected the services to be collected from the table")
+	}
+	close(ch)
+	startTimes := map[string]float64{}
+	for m := range ch {
+		if m.Desc() != c.StartTime {
+			continue
+		}
+		var pb dto.Metric
+		if err := m.Write(&pb); err != nil {
+			t.Fatal(err)
+		}
+		startTimes[pb.GetLabel()[0].GetValue()] = pb.GetGauge().GetValue()
+	}
+	if expected := map[string]float64{"spooler": float64(startTime.Unix())}; !reflect.DeepEqual(startTimes, expected) {
+		t.Errorf("expected start times %v, got %v", expected, startTimes)
+	}
+}
+
+func TestServiceIncluded(t *testing.T) {
+	cases := []struct {
+		include, exclude  string
+		name, displayName string
+		included          bool
+	}{
+		{".+", "", "spooler", "Print Spooler", true},
+		{".+", "", "spooler", "", true},
+		{"spooler", "", "spooler", "Print Spooler", true},
+		{"Print .+", "", "spooler", "Print Spooler", true},
+		{"w32time", "", "spooler", "Print Spooler", false},
+		// The patterns match the whole name.
+		{"spool", "", "spooler", "Print Spooler", false},
+		{".+", "spooler", "spooler", "Print Spooler", false},
+		{".+", "Print .+", "spooler", "Print Spooler", false},
+		{".+", "Windows .+", "spooler", "Print Spooler", true},
+	}
+	for _, tc := range cases {
+		c := newServiceCollectorWith("", tc.include, tc.exclude, nil)
+		if included := c.serviceIncluded(tc.name, tc.displayName); included != tc.included {
+			t.Errorf("include %q, exclude %q: expected %s (%q) included to be %v", tc.include, tc.exclude, tc.name, tc.displayName, tc.included)
+		}
+	}
+}
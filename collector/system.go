@@ -10,6 +10,8 @@ import (
 
 // A SystemCollector is a Prometheus collector for WMI metrics
 type SystemCollector struct {
+	logger log.Logger
+
 	ContextSwitchesTotal     *prometheus.Desc
 	ExceptionDispatchesTotal *prometheus.Desc
 	ProcessorQueueLength     *prometheus.Desc
@@ -23,6 +25,7 @@ func newSystemCollector() (Collector, error) {
 	const subsystem = "system"
 
 	return &SystemCollector{
+		logger: log.With(log.CollectorField, "system"),
 		ContextSwitchesTotal: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "context_switches_total"),
 			"Total number of context switches (WMI source: PerfOS_System.ContextSwitchesPersec)",
@@ -66,7 +69,7 @@ func newSystemCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *SystemCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collect(ctx, ch); err != nil {
-		log.Error("failed collecting system metrics:", desc, err)
+		c.logger.Error("failed collecting system metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -85,7 +88,7 @@ type system struct {
 
 func (c *SystemCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []system
-	if err := unmarshalObject(ctx.perfObjects["System"], &dst); err != nil {
+	if err := unmarshalObject(ctx.perfObjects["System"], &dst, c.logger); err != nil {
 		return nil, err
 	}
 
@@ -4,12 +4,29 @@
 package collector
 
 import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/prometheus-community/windows_exporter/headers/iphlpapi"
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	FlagTCPConnectionsByPort = "collector.tcp.connections-by-port"
+)
+
+var (
+	tcpConnectionsByPort *bool
 )
 
 // A TCPCollector is a Prometheus collector for WMI Win32_PerfRawData_Tcpip_TCPv{4,6} metrics
 type TCPCollector struct {
+	logger log.Logger
+
 	ConnectionFailures         *prometheus.Desc
 	ConnectionsActive          *prometheus.Desc
 	ConnectionsEstablished     *prometheus.Desc
@@ -19,6 +36,22 @@ type TCPCollector struct {
 	SegmentsReceivedTotal      *prometheus.Desc
 	SegmentsRetransmittedTotal *prometheus.Desc
 	SegmentsSentTotal          *prometheus.Desc
+	Connections                *prometheus.Desc
+	LocalPortConnections       *prometheus.Desc
+	DynamicPortsStart          *prometheus.Desc
+	DynamicPorts               *prometheus.Desc
+	DynamicPortsUsed           *prometheus.Desc
+
+	connectionsByPort bool
+	dynamicPorts      tcpDynamicPortsCache
+}
+
+// newTCPCollectorFlags ...
+func newTCPCollectorFlags(app *kingpin.Application) {
+	tcpConnectionsByPort = app.Flag(
+		FlagTCPConnectionsByPort,
+		"Report the TCP connections of the listening ports by local port.",
+	).Default("false").Bool()
 }
 
 // newTCPCollector ...
@@ -26,6 +59,7 @@ func newTCPCollector() (Collector, error) {
 	const subsystem = "tcp"
 
 	return &TCPCollector{
+		logger: log.With(log.CollectorField, "tcp"),
 		ConnectionFailures: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "connection_failures_total"),
 			"(TCP.ConnectionFailures)",
@@ -80,6 +114,37 @@ func newTCPCollector() (Collector, error) {
 			[]string{"af"},
 			nil,
 		),
+		Connections: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "connections"),
+			"Number of TCP connections by address family and state, from the TCP table.",
+			[]string{"family", "state"},
+			nil,
+		),
+		LocalPortConnections: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "local_port_connections"),
+			"Number of TCP connections of a listening port by address family and state, from the TCP table.",
+			[]string{"family", "port", "state"},
+			nil,
+		),
+		DynamicPortsStart: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "dynamic_ports_start"),
+			"First port of the dynamic port range.",
+			nil,
+			nil,
+		),
+		DynamicPorts: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "dynamic_ports"),
+			"Number of ports of the dynamic port range.",
+			nil,
+			nil,
+		),
+		DynamicPortsUsed: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "dynamic_ports_used"),
+			"Number of ports of the dynamic port range used by TCP connections, from the TCP table.",
+			nil,
+			nil,
+		),
+		connectionsByPort: *tcpConnectionsByPort,
 	}, nil
 }
 
@@ -87,9 +152,13 @@ func newTCPCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *TCPCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collect(ctx, ch); err != nil {
-		log.Error("failed collecting tcp metrics:", desc, err)
+		c.logger.Error("failed collecting tcp metrics:", desc, err)
 		return err
 	}
+	// The performance counters are still reported without the TCP table.
+	if err := c.collectTable(ctx, ch); err != nil {
+		c.logger.Error("failed collecting tcp table metrics:", err)
+	}
 	return nil
 }
 
@@ -169,7 +238,7 @@ func (c *TCPCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric)
 	var dst []tcp
 
 	// TCPv4 counters
-	if err := unmarshalObject(ctx.perfObjects["TCPv4"], &dst); err != nil {
+	if err := unmarshalObject(ctx.perfObjects["TCPv4"], &dst, c.logger); err != nil {
 		return nil, err
 	}
 	if len(dst) != 0 {
@@ -177,7 +246,7 @@ func (c *TCPCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric)
 	}
 
 	// TCPv6 counters
-	if err := unmarshalObject(ctx.perfObjects["TCPv6"], &dst); err != nil {
+	if err := unmarshalObject(ctx.perfObjects["TCPv6"], &dst, c.logger); err != nil {
 		return nil, err
 	}
 	if len(dst) != 0 {
@@ -186,3 +255,193 @@ func (c *TCPCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric)
 
 	return nil, nil
 }
+
+// MSFT_NetTCPSetting docs:
+// - https://learn.microsoft.com/en-us/previous-versions/windows/desktop/tcpip/msft-nettcpsetting
+type MSFT_NetTCPSetting struct {
+	DynamicPortRangeStartPort     *uint16
+	DynamicPortRangeNumberOfPorts *uint16
+}
+
+// tcpPortRange is a range of ports, empty if unknown.
+type tcpPortRange struct {
+	start uint16
+	count uint16
+}
+
+func (r tcpPortRange) contains(port uint16) bool {
+	return port >= r.start && uint32(port) < uint32(r.start)+uint32(r.count)
+}
+
+// tcpTable is the TCP table of an address family.
+type tcpTable struct {
+	family      string
+	connections []iphlpapi.TCPConnection
+}
+
+type tcpStateKey struct {
+	family string
+	state  string
+}
+
+type tcpPortKey struct {
+	family string
+	port   uint16
+	state  string
+}
+
+// tcpTableStats aggregates TCP tables.
+type tcpTableStats struct {
+	// states counts the connections of every family and state.
+	states map[tcpStateKey]float64
+	// ports counts the connections of the listening ports, if enabled.
+	ports map[tcpPortKey]float64
+	// dynamicPortsUsed is the number of distinct local ports of the
+	// connections within the dynamic port range.
+	dynamicPortsUsed float64
+}
+
+func aggregateTCPTables(tables []tcpTable, byPort bool, dynamicPorts tcpPortRange) *tcpTableStats {
+	stats := &tcpTableStats{states: map[tcpStateKey]float64{}}
+	if byPort {
+		stats.ports = map[tcpPortKey]float64{}
+	}
+	usedPorts := map[uint16]bool{}
+
+	for _, table := range tables {
+		for _, state := range iphlpapi.TCPStates {
+			stats.states[tcpStateKey{table.family, state}] = 0
+		}
+
+		listening := map[uint16]bool{}
+		for _, conn := range table.connections {
+			if conn.State == iphlpapi.MIB_TCP_STATE_LISTEN {
+				listening[conn.LocalPort] = true
+			}
+		}
+
+		for _, conn := range table.connections {
+			state, ok := iphlpapi.TCPStates[conn.State]
+			if !ok {
+				state = "unknown"
+			}
+			stats.states[tcpStateKey{table.family, state}]++
+			if byPort && listening[conn.LocalPort] {
+				stats.ports[tcpPortKey{table.family, conn.LocalPort, state}]++
+			}
+			if conn.State != iphlpapi.MIB_TCP_STATE_LISTEN && dynamicPorts.contains(conn.LocalPort) {
+				usedPorts[conn.LocalPort] = true
+			}
+		}
+	}
+	stats.dynamicPortsUsed = float64(len(usedPorts))
+	return stats
+}
+
+// tcpDynamicPortsRefresh is the time after which the dynamic port range is
+// queried again.
+const tcpDynamicPortsRefresh = 5 * time.Minute
+
+// tcpDynamicPortsCache caches the dynamic port range, which rarely changes.
+type tcpDynamicPortsCache struct {
+	mu      sync.Mutex
+	ports   tcpPortRange
+	updated time.Time
+}
+
+// get returns the cached range, calling query once it is older than
+// tcpDynamicPortsRefresh. The last known range is kept if query fails.
+func (c *tcpDynamicPortsCache) get(now time.Time, query func() (tcpPortRange, error)) (tcpPortRange, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.updated.IsZero() && now.Sub(c.updated) < tcpDynamicPortsRefresh {
+		return c.ports, nil
+	}
+	// Failures are retried after tcpDynamicPortsRefresh too, as
+	// MSFT_NetTCPSetting may not exist at all.
+	c.updated = now
+	ports, err := query()
+	if err != nil {
+		return c.ports, err
+	}
+	c.ports = ports
+	return ports, nil
+}
+
+// dynamicPortRange queries the dynamic port range, shared by the TCP
+// settings that set it.
+func (c *TCPCollector) dynamicPortRange(ctx *ScrapeContext) (tcpPortRange, error) {
+	var dst []MSFT_NetTCPSetting
+	q := queryAll(&dst, c.logger)
+	if err := ctx.wmiQueryNamespace(q, &dst, "root\\StandardCimv2"); err != nil {
+		return tcpPortRange{}, err
+	}
+	for _, setting := range dst {
+		if setting.DynamicPortRangeStartPort != nil && setting.DynamicPortRangeNumberOfPorts != nil && *setting.DynamicPortRangeNumberOfPorts > 0 {
+			return tcpPortRange{*setting.DynamicPortRangeStartPort, *setting.DynamicPortRangeNumberOfPorts}, nil
+		}
+	}
+	return tcpPortRange{}, nil
+}
+
+// collectTable sends the metrics of the TCP tables.
+func (c *TCPCollector) collectTable(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
+	var tables []tcpTable
+	for _, family := range []struct {
+		af   uint32
+		name string
+	}{{windows.AF_INET, "ipv4"}, {windows.AF_INET6, "ipv6"}} {
+		connections, err := iphlpapi.GetTCPConnections(family.af)
+		if err != nil {
+			return err
+		}
+		tables = append(tables, tcpTable{family.name, connections})
+	}
+
+	dynamicPorts, err := c.dynamicPorts.get(time.Now(), func() (tcpPortRange, error) {
+		return c.dynamicPortRange(ctx)
+	})
+	if err != nil {
+		c.logger.Debugf("Could not query the dynamic port range: %v", err)
+	}
+
+	stats := aggregateTCPTables(tables, c.connectionsByPort, dynamicPorts)
+	for key, count := range stats.states {
+		ch <- prometheus.MustNewConstMetric(
+			c.Connections,
+			prometheus.GaugeValue,
+			count,
+			key.family,
+			key.state,
+		)
+	}
+	for key, count := range stats.ports {
+		ch <- prometheus.MustNewConstMetric(
+			c.LocalPortConnections,
+			prometheus.GaugeValue,
+			count,
+			key.family,
+			strconv.Itoa(int(key.port)),
+			key.state,
+		)
+	}
+	if dynamicPorts.count > 0 {
+		ch <- prometheus.MustNewConstMetric(
+			c.DynamicPortsStart,
+			prometheus.GaugeValue,
+			float64(dynamicPorts.start),
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.DynamicPorts,
+			prometheus.GaugeValue,
+			float64(dynamicPorts.count),
+		)
+		ch <- prometheus.MustNewConstMetric(
+			c.DynamicPortsUsed,
+			prometheus.GaugeValue,
+			stats.dynamicPortsUsed,
+		)
+	}
+	return nil
+}
@@ -1,9 +1,154 @@
 package collector
 
 import (
+	"errors"
+	"net"
 	"testing"
+	"time"
+
+	"github.com/prometheus-community/windows_exporter/headers/iphlpapi"
 )
 
 func BenchmarkTCPCollector(b *testing.B) {
+	// Flags are not set in testing context (kingpin flags not parsed).
+	localConnectionsByPort := false
+	tcpConnectionsByPort = &localConnectionsByPort
+
 	benchmarkCollector(b, "tcp", newTCPCollector)
 }
+
+// tcpFixtureTables are the TCP tables of a web server listening on 443, with
+// outbound connections to a database.
+var tcpFixtureTables = []tcpTable{
+	{
+		family: "ipv4",
+		connections: []iphlpapi.TCPConnection{
+			{State: iphlpapi.MIB_TCP_STATE_LISTEN, LocalAddr: net.IPv4zero, LocalPort: 443, PID: 4},
+			{State: iphlpapi.MIB_TCP_STATE_LISTEN, LocalAddr: net.IPv4zero, LocalPort: 3389, PID: 1000},
+			{State: iphlpapi.MIB_TCP_STATE_ESTAB, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 443, RemoteAddr: net.IPv4(10, 0, 1, 1), RemotePort: 51000, PID: 4},
+			{State: iphlpapi.MIB_TCP_STATE_ESTAB, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 443, RemoteAddr: net.IPv4(10, 0, 1, 2), RemotePort: 51000, PID: 4},
+			{State: iphlpapi.MIB_TCP_STATE_TIME_WAIT, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 443, RemoteAddr: net.IPv4(10, 0, 1, 3), RemotePort: 52000},
+			// Outbound connections from dynamic ports, two to different
+			// databases sharing a port.
+			{State: iphlpapi.MIB_TCP_STATE_ESTAB, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 49152, RemoteAddr: net.IPv4(10, 0, 2, 1), RemotePort: 1433, PID: 2000},
+			{State: iphlpapi.MIB_TCP_STATE_ESTAB, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 49152, RemoteAddr: net.IPv4(10, 0, 2, 2), RemotePort: 1433, PID: 2000},
+			{State: iphlpapi.MIB_TCP_STATE_CLOSE_WAIT, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 49153, RemoteAddr: net.IPv4(10, 0, 2, 1), RemotePort: 1433, PID: 2000},
+			{State: iphlpapi.MIB_TCP_STATE_TIME_WAIT, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 65535, RemoteAddr: net.IPv4(10, 0, 2, 1), RemotePort: 1433},
+			// Outside of the dynamic port range.
+			{State: iphlpapi.MIB_TCP_STATE_ESTAB, LocalAddr: net.IPv4(10, 0, 0, 1), LocalPort: 40000, RemoteAddr: net.IPv4(10, 0, 2, 1), RemotePort: 1433, PID: 2000},
+		},
+	},
+	{
+		family: "ipv6",
+		connections: []iphlpapi.TCPConnection{
+			{State: iphlpapi.MIB_TCP_STATE_LISTEN, LocalAddr: net.IPv6zero, LocalPort: 443, PID: 4},
+			{State: iphlpapi.MIB_TCP_STATE_ESTAB, LocalAddr: net.ParseIP("fd00::1"), LocalPort: 443, RemoteAddr: net.ParseIP("fd00::2"), RemotePort: 53000, PID: 4},
+			// Listened on for IPv4 only.
+			{State: iphlpapi.MIB_TCP_STATE_ESTAB, LocalAddr: net.ParseIP("fd00::1"), LocalPort: 3389, RemoteAddr: net.ParseIP("fd00::2"), RemotePort: 53001, PID: 1000},
+			{State: iphlpapi.MIB_TCP_STATE_SYN_SENT, LocalAddr: net.ParseIP("fd00::1"), LocalPort: 49154, RemoteAddr: net.ParseIP("fd00::3"), RemotePort: 443, PID: 2000},
+		},
+	},
+}
+
+func TestAggregateTCPTables(t *testing.T) {
+	stats := aggregateTCPTables(tcpFixtureTables, true, tcpPortRange{start: 49152, count: 16384})
+
+	states := map[tcpStateKey]float64{
+		{"ipv4", "listen"}:      2,
+		{"ipv4", "established"}: 5,
+		{"ipv4", "time_wait"}:   2,
+		{"ipv4", "close_wait"}:  1,
+		{"ipv6", "listen"}:      1,
+		{"ipv6", "established"}: 2,
+		{"ipv6", "syn_sent"}:    1,
+	}
+	// All the states are reported for both families.
+	if len(stats.states) != 2*len(iphlpapi.TCPStates) {
+		t.Errorf("expected %d states, got %v", 2*len(iphlpapi.TCPStates), stats.states)
+	}
+	for key, count := range stats.states {
+		if count != states[key] {
+			t.Errorf("expected %v %v connections, got %v", states[key], key, count)
+		}
+	}
+
+	ports := map[tcpPortKey]float64{
+		{"ipv4", 443, "listen"}:      1,
+		{"ipv4", 443, "established"}: 2,
+		{"ipv4", 443, "time_wait"}:   1,
+		{"ipv4", 3389, "listen"}:     1,
+		{"ipv6", 443, "listen"}:      1,
+		{"ipv6", 443, "established"}: 1,
+	}
+	if len(stats.ports) != len(ports) {
+		t.Errorf("expected ports %v, got %v", ports, stats.ports)
+	}
+	for key, count := range ports {
+		if stats.ports[key] != count {
+			t.Errorf("expected %v connections of %v, got %v", count, key, stats.ports[key])
+		}
+	}
+
+	// 49152, 49153, 49154 and 65535.
+	if stats.dynamicPortsUsed != 4 {
+		t.Errorf("expected 4 dynamic ports used, got %v", stats.dynamicPortsUsed)
+	}
+}
+
+func TestAggregateTCPTablesDefaults(t *testing.T) {
+	stats := aggregateTCPTables(tcpFixtureTables, false, tcpPortRange{})
+	if stats.ports != nil {
+		t.Errorf("expected no connections by port, got %v", stats.ports)
+	}
+	if stats.dynamicPortsUsed != 0 {
+		t.Errorf("expected no dynamic ports used without range, got %v", stats.dynamicPortsUsed)
+	}
+}
+
+func TestTCPPortRange(t *testing.T) {
+	r := tcpPortRange{start: 49152, count: 16384}
+	for port, expected := range map[uint16]bool{
+		49151: false,
+		49152: true,
+		65535: true,
+		1024:  false,
+	} {
+		if r.contains(port) != expected {
+			t.Errorf("expected %d in range to be %v", port, expected)
+		}
+	}
+	if (tcpPortRange{}).contains(0) {
+		t.Error("expected an empty range to contain no port")
+	}
+}
+
+func TestTCPDynamicPortsCache(t *testing.T) {
+	var c tcpDynamicPortsCache
+	queries := 0
+	ports := tcpPortRange{start: 49152, count: 16384}
+	var queryErr error
+	query := func() (tcpPortRange, error) {
+		queries++
+		return ports, queryErr
+	}
+
+	now := time.Unix(1700000000, 0)
+	if r, err := c.get(now, query); err != nil || r != ports {
+		t.Fatalf("expected %v, got %v, %v", ports, r, err)
+	}
+	// The range is cached until tcpDynamicPortsRefresh.
+	if r, _ := c.get(now.Add(tcpDynamicPortsRefresh-time.Second), query); r != ports || queries != 1 {
+		t.Errorf("expected the cached range, got %v after %d queries", r, queries)
+	}
+
+	// Failures keep the last known range.
+	queryErr = errors.New("Invalid class")
+	now = now.Add(tcpDynamicPortsRefresh)
+	if r, err := c.get(now, query); err == nil || r != ports || queries != 2 {
+		t.Errorf("expected the last known range and an error, got %v, %v after %d queries", r, err, queries)
+	}
+	// And are not retried before tcpDynamicPortsRefresh.
+	if _, err := c.get(now.Add(time.Second), query); err != nil || queries != 2 {
+		t.Errorf("expected no query, got %v after %d queries", err, queries)
+	}
+}
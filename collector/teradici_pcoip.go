@@ -8,7 +8,6 @@ import (
 
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A teradiciPcoipCollector is a Prometheus collector for WMI metrics:
@@ -19,6 +18,8 @@ import (
 // win32_PerfRawData_TeradiciPerf_PCoIPSessionUsbStatistics
 
 type teradiciPcoipCollector struct {
+	logger log.Logger
+
 	AudioBytesReceived       *prometheus.Desc
 	AudioBytesSent           *prometheus.Desc
 	AudioRXBWkbitPersec      *prometheus.Desc
@@ -66,6 +67,7 @@ type teradiciPcoipCollector struct {
 func newTeradiciPcoipCollector() (Collector, error) {
 	const subsystem = "teradici_pcoip"
 	return &teradiciPcoipCollector{
+		logger: log.With(log.CollectorField, "teradici_pcoip"),
 		AudioBytesReceived: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "audio_bytes_received_total"),
 			"(AudioBytesReceived)",
@@ -299,23 +301,23 @@ func newTeradiciPcoipCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *teradiciPcoipCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collectAudio(ch); err != nil {
-		log.Error("failed collecting teradici session audio metrics:", desc, err)
+		c.logger.Error("failed collecting teradici session audio metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectGeneral(ch); err != nil {
-		log.Error("failed collecting teradici session general metrics:", desc, err)
+		c.logger.Error("failed collecting teradici session general metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectImaging(ch); err != nil {
-		log.Error("failed collecting teradici session imaging metrics:", desc, err)
+		c.logger.Error("failed collecting teradici session imaging metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectNetwork(ch); err != nil {
-		log.Error("failed collecting teradici session network metrics:", desc, err)
+		c.logger.Error("failed collecting teradici session network metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectUsb(ch); err != nil {
-		log.Error("failed collecting teradici session USB metrics:", desc, err)
+		c.logger.Error("failed collecting teradici session USB metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -375,8 +377,8 @@ type win32_PerfRawData_TeradiciPerf_PCoIPSessionUsbStatistics struct {
 
 func (c *teradiciPcoipCollector) collectAudio(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_TeradiciPerf_PCoIPSessionAudioStatistics
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
@@ -418,8 +420,8 @@ func (c *teradiciPcoipCollector) collectAudio(ch chan<- prometheus.Metric) (*pro
 
 func (c *teradiciPcoipCollector) collectGeneral(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_TeradiciPerf_PCoIPSessionGeneralStatistics
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
@@ -473,8 +475,8 @@ func (c *teradiciPcoipCollector) collectGeneral(ch chan<- prometheus.Metric) (*p
 
 func (c *teradiciPcoipCollector) collectImaging(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_TeradiciPerf_PCoIPSessionImagingStatistics
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
@@ -552,8 +554,8 @@ func (c *teradiciPcoipCollector) collectImaging(ch chan<- prometheus.Metric) (*p
 
 func (c *teradiciPcoipCollector) collectNetwork(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_TeradiciPerf_PCoIPSessionNetworkStatistics
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
@@ -625,8 +627,8 @@ func (c *teradiciPcoipCollector) collectNetwork(ch chan<- prometheus.Metric) (*p
 
 func (c *teradiciPcoipCollector) collectUsb(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_TeradiciPerf_PCoIPSessionUsbStatistics
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 	if len(dst) == 0 {
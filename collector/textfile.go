@@ -18,29 +18,44 @@ package collector
 
 import (
 	"fmt"
-	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
-	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
-	"github.com/dimchansky/utfbom"
 	"github.com/prometheus-community/windows_exporter/log"
+	"github.com/prometheus-community/windows_exporter/textfile"
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
-	"github.com/prometheus/common/expfmt"
 )
 
 const (
-	FlagTextFileDirectory = "collector.textfile.directory"
+	FlagTextFileDirectory   = "collector.textfile.directory"
+	FlagTextFileTimestamps  = "collector.textfile.timestamps"
+	FlagTextFileMaxAge      = "collector.textfile.max-age"
+	FlagTextFileStaleAction = "collector.textfile.stale-action"
+	FlagTextFileMaxFileSize = "collector.textfile.max-file-size"
+	FlagTextFileMaxSeries   = "collector.textfile.max-series"
+)
+
+// Handling of files older than the max age.
+const (
+	textFileStaleFlag   = "flag"
+	textFileStaleIgnore = "ignore"
 )
 
 var (
-	textFileDirectory *string
+	textFileDirectory   *string
+	textFileTimestamps  *string
+	textFileMaxAge      *time.Duration
+	textFileStaleAction *string
+	textFileMaxFileSize *int64
+	textFileMaxSeries   *int
 
 	mtimeDesc = prometheus.NewDesc(
 		prometheus.BuildFQName(Namespace, "textfile", "mtime_seconds"),
@@ -48,55 +63,120 @@ var (
 		[]string{"file"},
 		nil,
 	)
+	staleDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "textfile", "stale"),
+		"1 if the mtime of the file is older than the max age, 0 otherwise",
+		[]string{"file"},
+		nil,
+	)
+	scrapeErrorDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "textfile", "scrape_error"),
+		"1 if there was an error opening or reading a file, 0 otherwise",
+		[]string{"file", "reason"},
+		nil,
+	)
+	cacheHitsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "textfile", "cache_hits_total"),
+		"Total number of files whose parsed metrics were reused from the cache",
+		nil,
+		nil,
+	)
+	cacheMissesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "textfile", "cache_misses_total"),
+		"Total number of files parsed because they were new or changed",
+		nil,
+		nil,
+	)
+	rejectedFilesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(Namespace, "textfile", "rejected_files_total"),
+		"Total number of times a file was rejected, by reason",
+		[]string{"reason"},
+		nil,
+	)
 )
 
 type textFileCollector struct {
-	path string
+	logger log.Logger
+
+	// Directories and glob patterns to read metric files from.
+	sources []string
+	// How client-side timestamps are handled, one of the textfile.Timestamps* values.
+	timestamps string
+	// Files with an mtime older than maxAge are stale, 0 to disable.
+	maxAge time.Duration
+	// What to do with stale files, one of the textFileStale* values.
+	staleAction string
+	// Files larger than maxFileSize bytes or holding more than maxSeries
+	// series are rejected, 0 to disable.
+	maxFileSize int64
+	maxSeries   int
 	// Only set for testing to get predictable output.
 	mtime *float64
+	now   func() time.Time
+
+	// lastErrors holds the last error of each file, so that a failing file
+	// is only logged when its error changes.
+	lastErrorsMu sync.Mutex
+	lastErrors   map[string]string
+
+	// cache holds the parse result of each file, which is reused as long as
+	// the file keeps the same mtime and size.
+	cacheMu     sync.Mutex
+	cache       map[string]textFileCacheEntry
+	cacheHits   uint64
+	cacheMisses uint64
+	rejected    map[string]uint64
+}
+
+type textFileCacheEntry struct {
+	modTime  time.Time
+	size     int64
+	families []*dto.MetricFamily
+	err      error
 }
 
 // newTextFileCollectorFlags ...
 func newTextFileCollectorFlags(app *kingpin.Application) {
 	textFileDirectory = app.Flag(
 		FlagTextFileDirectory,
-		"Directory to read text files with metrics from.",
+		"Comma-separated list of directories or glob patterns to read text files with metrics from.",
 	).Default(getDefaultPath()).String()
+	textFileTimestamps = app.Flag(
+		FlagTextFileTimestamps,
+		"How to handle client-side timestamps: 'reject' files holding them, 'honor' them, or 'strip' them with a warning.",
+	).Default(textfile.TimestampsReject).Enum(textfile.TimestampsReject, textfile.TimestampsHonor, textfile.TimestampsStrip)
+	textFileMaxAge = app.Flag(
+		FlagTextFileMaxAge,
+		"Files with an mtime older than this are considered stale. 0 to disable.",
+	).Default("0").Duration()
+	textFileStaleAction = app.Flag(
+		FlagTextFileStaleAction,
+		"What to do with stale files: 'flag' them in windows_textfile_stale while still exporting their metrics, or 'ignore' their metrics.",
+	).Default(textFileStaleFlag).Enum(textFileStaleFlag, textFileStaleIgnore)
+	textFileMaxFileSize = app.Flag(
+		FlagTextFileMaxFileSize,
+		"Files larger than this number of bytes are rejected. 0 to disable.",
+	).Default("0").Int64()
+	textFileMaxSeries = app.Flag(
+		FlagTextFileMaxSeries,
+		"Files holding more than this number of series are rejected. 0 to disable.",
+	).Default("0").Int()
 }
 
 // newTextFileCollector returns a new Collector exposing metrics read from files
-// in the given textfile directory.
+// in the given textfile directories.
 func newTextFileCollector() (Collector, error) {
 	return &textFileCollector{
-		path: *textFileDirectory,
+		logger:      log.With(log.CollectorField, "textfile"),
+		sources:     expandEnabledChildCollectors(*textFileDirectory),
+		timestamps:  *textFileTimestamps,
+		maxAge:      *textFileMaxAge,
+		staleAction: *textFileStaleAction,
+		maxFileSize: *textFileMaxFileSize,
+		maxSeries:   *textFileMaxSeries,
 	}, nil
 }
 
-// Given a slice of metric families, determine if any two entries are duplicates.
-// Duplicates will be detected where the metric name, labels and label values are identical.
-func duplicateMetricEntry(metricFamilies []*dto.MetricFamily) bool {
-	uniqueMetrics := make(map[string]map[string]string)
-	for _, metricFamily := range metricFamilies {
-		metric_name := *metricFamily.Name
-		for _, metric := range metricFamily.Metric {
-			metric_labels := metric.GetLabel()
-			labels := make(map[string]string)
-			for _, label := range metric_labels {
-				labels[label.GetName()] = label.GetValue()
-			}
-			// Check if key is present before appending
-			_, mapContainsKey := uniqueMetrics[metric_name]
-
-			// Duplicate metric found with identical labels & label values
-			if mapContainsKey == true && reflect.DeepEqual(uniqueMetrics[metric_name], labels) {
-				return true
-			}
-			uniqueMetrics[metric_name] = labels
-		}
-	}
-	return false
-}
-
 func convertMetricFamily(metricFamily *dto.MetricFamily, ch chan<- prometheus.Metric) {
 	var valType prometheus.ValueType
 	var val float64
@@ -112,10 +192,7 @@ func convertMetricFamily(metricFamily *dto.MetricFamily, ch chan<- prometheus.Me
 	}
 
 	for _, metric := range metricFamily.Metric {
-		if metric.TimestampMs != nil {
-			log.Warnf("Ignoring unsupported custom timestamp on textfile collector metric %v", metric)
-		}
-
+		var m prometheus.Metric
 		labels := metric.GetLabel()
 		var names []string
 		var values []string
@@ -157,7 +234,7 @@ func convertMetricFamily(metricFamily *dto.MetricFamily, ch chan<- prometheus.Me
 			for _, q := range metric.Summary.Quantile {
 				quantiles[q.GetQuantile()] = q.GetValue()
 			}
-			ch <- prometheus.MustNewConstSummary(
+			m = prometheus.MustNewConstSummary(
 				prometheus.NewDesc(
 					*metricFamily.Name,
 					metricFamily.GetHelp(),
@@ -172,7 +249,7 @@ func convertMetricFamily(metricFamily *dto.MetricFamily, ch chan<- prometheus.Me
 			for _, b := range metric.Histogram.Bucket {
 				buckets[b.GetUpperBound()] = b.GetCumulativeCount()
 			}
-			ch <- prometheus.MustNewConstHistogram(
+			m = prometheus.MustNewConstHistogram(
 				prometheus.NewDesc(
 					*metricFamily.Name,
 					metricFamily.GetHelp(),
@@ -187,7 +264,7 @@ func convertMetricFamily(metricFamily *dto.MetricFamily, ch chan<- prometheus.Me
 			continue
 		}
 		if metricType == dto.MetricType_GAUGE || metricType == dto.MetricType_COUNTER || metricType == dto.MetricType_UNTYPED {
-			ch <- prometheus.MustNewConstMetric(
+			m = prometheus.MustNewConstMetric(
 				prometheus.NewDesc(
 					*metricFamily.Name,
 					metricFamily.GetHelp(),
@@ -196,6 +273,11 @@ func convertMetricFamily(metricFamily *dto.MetricFamily, ch chan<- prometheus.Me
 				valType, val, values...,
 			)
 		}
+		// Client-side timestamps are only left in place when honoured.
+		if metric.TimestampMs != nil {
+			m = prometheus.NewMetricWithTimestamp(time.UnixMilli(metric.GetTimestampMs()), m)
+		}
+		ch <- m
 	}
 }
 
@@ -219,141 +301,371 @@ func (c *textFileCollector) exportMTimes(mtimes map[string]time.Time, ch chan<-
 	}
 }
 
-type carriageReturnFilteringReader struct {
-	r io.Reader
+// isTextFile reports whether the file holds metrics, based on its extension.
+func isTextFile(name string) bool {
+	switch filepath.Ext(name) {
+	case textfile.ExtProm, textfile.ExtOpenMetrics, textfile.ExtJSON:
+		return true
+	}
+	return false
+}
+
+// listFiles returns the metric files found in all sources, keyed by full path.
+// A source is either a directory, whose metric files are all read, or a glob
+// pattern matching directories or metric files. Errors are keyed by the path
+// of the source which couldn't be read.
+func (c *textFileCollector) listFiles() (map[string]os.FileInfo, map[string]error) {
+	errs := map[string]error{}
+	files := map[string]os.FileInfo{}
+
+	for _, source := range c.sources {
+		paths := []string{source}
+		if strings.ContainsAny(source, "*?[") {
+			matches, err := filepath.Glob(source)
+			if err != nil {
+				errs[source] = fmt.Errorf("invalid glob pattern %q: %s", source, err)
+				continue
+			}
+			paths = matches
+		}
+
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				errs[path] = fmt.Errorf("error reading textfile collector source %q: %s", path, err)
+				continue
+			}
+			if !info.IsDir() {
+				if isTextFile(info.Name()) {
+					files[path] = info
+				}
+				continue
+			}
+
+			entries, err := ioutil.ReadDir(path)
+			if err != nil {
+				errs[path] = fmt.Errorf("error reading textfile collector directory %q: %s", path, err)
+				continue
+			}
+			for _, entry := range entries {
+				if entry.Mode().IsRegular() && isTextFile(entry.Name()) {
+					files[filepath.Join(path, entry.Name())] = entry
+				}
+			}
+		}
+	}
+
+	return files, errs
+}
+
+// parseFile reads the metric families of a single file.
+func (c *textFileCollector) parseFile(path string) ([]*dto.MetricFamily, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, &textfile.Error{Reason: textfile.ErrorRead, Err: fmt.Errorf("error opening %q: %v", path, err)}
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			c.logger.Warnf("Error closing file %q: %v", path, err)
+		}
+	}()
+
+	families, err := textfile.Parse(file, fmt.Sprintf("textfile %q", path), filepath.Ext(path), c.timestamps, c.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if n := seriesCount(families); c.maxSeries > 0 && n > c.maxSeries {
+		return nil, &textfile.Error{Reason: textfile.ErrorSeries, Err: fmt.Errorf("textfile %q holds %d series, exceeding the limit of %d", path, n, c.maxSeries)}
+	}
+
+	return families, nil
+}
+
+// metricFamilyMerger merges the metric families of several sources, such as
+// files. Metrics of accepted sources are tracked across sources, so that a
+// source duplicating metrics of a previous one is rejected on its own.
+// Families present in several sources are merged, as a family can only be
+// exported with a single help and type.
+type metricFamilyMerger struct {
+	// helpPrefix, followed by the sources of a family, is the default help.
+	helpPrefix string
+
+	seen          map[string]struct{}
+	merged        map[string]*dto.MetricFamily
+	familySources map[string][]string
+	names         []string
+}
+
+func newMetricFamilyMerger(helpPrefix string) *metricFamilyMerger {
+	return &metricFamilyMerger{
+		helpPrefix:    helpPrefix,
+		seen:          map[string]struct{}{},
+		merged:        map[string]*dto.MetricFamily{},
+		familySources: map[string][]string{},
+	}
 }
 
-// Read returns data from the underlying io.Reader, but with \r filtered out
-func (cr carriageReturnFilteringReader) Read(p []byte) (int, error) {
-	buf := make([]byte, len(p))
-	n, err := cr.r.Read(buf)
+// add merges the families of a source, unless they conflict with the metrics
+// of previous sources. The families themselves are not modified.
+func (m *metricFamilyMerger) add(source string, families []*dto.MetricFamily) error {
+	for _, mf := range families {
+		if prev, ok := m.merged[mf.GetName()]; ok && prev.GetType() != mf.GetType() {
+			return &textfile.Error{
+				Reason: textfile.ErrorDuplicate,
+				Err:    fmt.Errorf("metric %s of %s has type %s, conflicting with type %s in other sources, skipping its processing", mf.GetName(), source, mf.GetType(), prev.GetType()),
+			}
+		}
+	}
+	if duplicates := textfile.DuplicateMetricKeys(families, m.seen); len(duplicates) > 0 {
+		return &textfile.Error{
+			Reason: textfile.ErrorDuplicate,
+			Err:    fmt.Errorf("%d metrics of %s are already present in other sources, skipping its processing", len(duplicates), source),
+		}
+	}
+
+	for _, mf := range families {
+		merged, ok := m.merged[mf.GetName()]
+		if !ok {
+			merged = &dto.MetricFamily{Name: mf.Name, Help: mf.Help, Type: mf.Type}
+			m.merged[mf.GetName()] = merged
+			m.names = append(m.names, mf.GetName())
+		}
+		merged.Metric = append(merged.Metric, mf.Metric...)
+		m.familySources[mf.GetName()] = append(m.familySources[mf.GetName()], source)
 
-	if err != nil && err != io.EOF {
-		return n, err
+		for _, metric := range mf.Metric {
+			m.seen[textfile.MetricKey(mf.GetName(), metric)] = struct{}{}
+		}
 	}
+	return nil
+}
 
-	pi := 0
-	for i := 0; i < n; i++ {
-		if buf[i] != '\r' {
-			p[pi] = buf[i]
-			pi++
+// collect sends the merged metrics.
+func (m *metricFamilyMerger) collect(ch chan<- prometheus.Metric) {
+	for _, name := range m.names {
+		mf := m.merged[name]
+		if mf.Help == nil {
+			help := fmt.Sprintf("%s %s", m.helpPrefix, strings.Join(m.familySources[name], ", "))
+			mf.Help = &help
 		}
+		convertMetricFamily(mf, ch)
+	}
+}
+
+// logFileError logs the error of a file, unless it is the same as the last
+// scrape's. A nil error logs the recovery of a previously failing file.
+func (c *textFileCollector) logFileError(path string, err error) {
+	c.lastErrorsMu.Lock()
+	defer c.lastErrorsMu.Unlock()
+	if c.lastErrors == nil {
+		c.lastErrors = map[string]string{}
 	}
 
-	return pi, err
+	last, failing := c.lastErrors[path]
+	switch {
+	case err == nil && failing:
+		c.logger.With("file", path).Info("Textfile is read successfully again")
+		delete(c.lastErrors, path)
+	case err == nil:
+	case failing && last == err.Error():
+		c.logger.With("file", path).WithError(err).Debug("Textfile still failing")
+	default:
+		c.logger.With("file", path).WithError(err).Error("Textfile failed")
+		c.lastErrors[path] = err.Error()
+	}
 }
 
 // Update implements the Collector interface.
 func (c *textFileCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
-	error := 0.0
 	mtimes := map[string]time.Time{}
+	fileErrors := map[string]error{}
 
 	// Iterate over files and accumulate their metrics.
-	files, err := ioutil.ReadDir(c.path)
-	if err != nil && c.path != "" {
-		log.Errorf("Error reading textfile collector directory %q: %s", c.path, err)
-		error = 1.0
-	}
-
-	// Create empty metricFamily slice here and append parsedFamilies to it inside the loop.
-	// Once loop is complete, raise error if any duplicates are present.
-	// This will ensure that duplicate metrics are correctly detected between multiple .prom files.
-	var metricFamilies = []*dto.MetricFamily{}
-fileLoop:
-	for _, f := range files {
-		if !strings.HasSuffix(f.Name(), ".prom") {
-			continue
-		}
-		path := filepath.Join(c.path, f.Name())
-		log.Debugf("Processing file %q", path)
-		file, err := os.Open(path)
-		if err != nil {
-			log.Errorf("Error opening %q: %v", path, err)
-			error = 1.0
-			continue
-		}
-		var parser expfmt.TextParser
-		r, encoding := utfbom.Skip(carriageReturnFilteringReader{r: file})
-		if err = checkBOM(encoding); err != nil {
-			log.Errorf("Invalid file encoding detected in %s: %s - file must be UTF8", path, err.Error())
-			error = 1.0
+	files, sourceErrors := c.listFiles()
+	for source, err := range sourceErrors {
+		fileErrors[source] = &textfile.Error{Reason: textfile.ErrorRead, Err: err}
+	}
+	// Files are processed in a predictable order, so that the same file is
+	// rejected on every scrape when files conflict.
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	stale := c.staleFiles(files)
+
+	merger := newMetricFamilyMerger("Metric read from")
+	for _, path := range paths {
+		c.logger.Debugf("Processing file %q", path)
+		if stale[path] && c.staleAction == textFileStaleIgnore {
+			c.logger.Debugf("Ignoring stale file %q", path)
+			fileErrors[path] = nil
 			continue
 		}
-		parsedFamilies, err := parser.TextToMetricFamilies(r)
-		closeErr := file.Close()
-		if closeErr != nil {
-			log.Warnf("Error closing file: %v", err)
+		families, err := c.cachedParseFile(path, files[path])
+		if err == nil {
+			err = merger.add(path, families)
 		}
+		fileErrors[path] = err
 		if err != nil {
-			log.Errorf("Error parsing %q: %v", path, err)
-			error = 1.0
+			c.countRejected(err)
 			continue
 		}
+		// Only set this once it has been parsed and validated, so that
+		// a failure does not appear fresh.
+		mtimes[path] = files[path].ModTime()
+	}
 
-		// Use temporary array to check for duplicates
-		var families_array []*dto.MetricFamily
+	merger.collect(ch)
 
-		for _, mf := range parsedFamilies {
-			families_array = append(families_array, mf)
-			for _, m := range mf.Metric {
-				if m.TimestampMs != nil {
-					log.Errorf("Textfile %q contains unsupported client-side timestamps, skipping entire file", path)
-					error = 1.0
-					continue fileLoop
-				}
-			}
-			if mf.Help == nil {
-				help := fmt.Sprintf("Metric read from %s", path)
-				mf.Help = &help
-			}
+	c.exportMTimes(mtimes, ch)
+	c.exportErrors(fileErrors, ch)
+	if c.maxAge > 0 {
+		for path, isStale := range stale {
+			ch <- prometheus.MustNewConstMetric(staleDesc, prometheus.GaugeValue, boolToFloat(isStale), path)
 		}
+	}
+	c.exportCache(files, ch)
 
-		// If duplicate metrics are detected in a *single* file, skip processing of file metrics
-		if duplicateMetricEntry(families_array) {
-			log.Errorf("Duplicate metrics detected in file %s. Skipping file processing.", f.Name())
-			error = 1.0
-			continue
-		}
+	return nil
+}
 
-		// Only set this once it has been parsed and validated, so that
-		// a failure does not appear fresh.
-		mtimes[f.Name()] = f.ModTime()
+// cachedParseFile returns the metric families of a file, only parsing it when
+// it is new or its mtime or size changed since the last scrape. Cached families
+// must not be modified.
+func (c *textFileCollector) cachedParseFile(path string, info os.FileInfo) ([]*dto.MetricFamily, error) {
+	c.cacheMu.Lock()
+	entry, ok := c.cache[path]
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		c.cacheHits++
+		c.cacheMu.Unlock()
+		return entry.families, entry.err
+	}
+	c.cacheMisses++
+	c.cacheMu.Unlock()
+
+	entry = textFileCacheEntry{modTime: info.ModTime(), size: info.Size()}
+	if c.maxFileSize > 0 && info.Size() > c.maxFileSize {
+		entry.err = &textfile.Error{Reason: textfile.ErrorSize, Err: fmt.Errorf("textfile %q is %d bytes large, exceeding the limit of %d bytes", path, info.Size(), c.maxFileSize)}
+	} else {
+		entry.families, entry.err = c.parseFile(path)
+	}
+	// Read errors may be transient, unlike the content of a file.
+	if tfErr, ok := entry.err.(*textfile.Error); ok && tfErr.Reason == textfile.ErrorRead {
+		return entry.families, entry.err
+	}
 
-		for _, metricFamily := range parsedFamilies {
-			metricFamilies = append(metricFamilies, metricFamily)
+	c.cacheMu.Lock()
+	if c.cache == nil {
+		c.cache = map[string]textFileCacheEntry{}
+	}
+	c.cache[path] = entry
+	c.cacheMu.Unlock()
+	return entry.families, entry.err
+}
+
+// countRejected counts a rejected file by reason.
+func (c *textFileCollector) countRejected(err error) {
+	reason := textfile.ErrorRead
+	if tfErr, ok := err.(*textfile.Error); ok {
+		reason = tfErr.Reason
+	}
+
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+	if c.rejected == nil {
+		c.rejected = map[string]uint64{}
+	}
+	c.rejected[reason]++
+}
+
+// exportCache exports the cache and rejection counters, and forgets the
+// files which disappeared.
+func (c *textFileCollector) exportCache(files map[string]os.FileInfo, ch chan<- prometheus.Metric) {
+	c.cacheMu.Lock()
+	defer c.cacheMu.Unlock()
+
+	for path := range c.cache {
+		if _, ok := files[path]; !ok {
+			delete(c.cache, path)
 		}
 	}
 
-	// If duplicates are detected across *multiple* files, return error.
-	if duplicateMetricEntry(metricFamilies) {
-		log.Errorf("Duplicate metrics detected across multiple files")
-		error = 1.0
-	} else {
-		for _, mf := range metricFamilies {
-			convertMetricFamily(mf, ch)
+	ch <- prometheus.MustNewConstMetric(cacheHitsDesc, prometheus.CounterValue, float64(c.cacheHits))
+	ch <- prometheus.MustNewConstMetric(cacheMissesDesc, prometheus.CounterValue, float64(c.cacheMisses))
+	for reason, count := range c.rejected {
+		ch <- prometheus.MustNewConstMetric(rejectedFilesDesc, prometheus.CounterValue, float64(count), reason)
+	}
+}
+
+// seriesCount returns the number of series exported for the families.
+func seriesCount(families []*dto.MetricFamily) int {
+	count := 0
+	for _, mf := range families {
+		for _, m := range mf.Metric {
+			switch mf.GetType() {
+			case dto.MetricType_SUMMARY:
+				// Quantiles, plus _sum and _count.
+				count += len(m.GetSummary().GetQuantile()) + 2
+			case dto.MetricType_HISTOGRAM:
+				// Buckets, plus _sum and _count. The +Inf bucket is
+				// always exported, even when missing from the file.
+				buckets := m.GetHistogram().GetBucket()
+				count += len(buckets) + 2
+				if len(buckets) == 0 || !math.IsInf(buckets[len(buckets)-1].GetUpperBound(), +1) {
+					count++
+				}
+			default:
+				count++
+			}
 		}
 	}
+	return count
+}
 
-	c.exportMTimes(mtimes, ch)
+// staleFiles reports for every file whether its mtime is older than the max age.
+func (c *textFileCollector) staleFiles(files map[string]os.FileInfo) map[string]bool {
+	stale := make(map[string]bool, len(files))
+	if c.maxAge <= 0 {
+		return stale
+	}
 
-	// Export if there were errors.
-	ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(
-			prometheus.BuildFQName(Namespace, "textfile", "scrape_error"),
-			"1 if there was an error opening or reading a file, 0 otherwise",
-			nil, nil,
-		),
-		prometheus.GaugeValue, error,
-	)
-	return nil
+	now := time.Now()
+	if c.now != nil {
+		now = c.now()
+	}
+	for path, f := range files {
+		stale[path] = now.Sub(f.ModTime()) > c.maxAge
+	}
+	return stale
 }
 
-func checkBOM(encoding utfbom.Encoding) error {
-	if encoding == utfbom.Unknown || encoding == utfbom.UTF8 {
-		return nil
+// exportErrors exports the error status of every file and source, and logs
+// the errors which changed since the last scrape.
+func (c *textFileCollector) exportErrors(fileErrors map[string]error, ch chan<- prometheus.Metric) {
+	for path, err := range fileErrors {
+		c.logFileError(path, err)
+
+		value, reason := 0.0, ""
+		if err != nil {
+			value, reason = 1.0, textfile.ErrorRead
+			if tfErr, ok := err.(*textfile.Error); ok {
+				reason = tfErr.Reason
+			}
+		}
+		ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, value, path, reason)
 	}
 
-	return fmt.Errorf(encoding.String())
+	// Forget files which disappeared.
+	c.lastErrorsMu.Lock()
+	defer c.lastErrorsMu.Unlock()
+	for path := range c.lastErrors {
+		if _, ok := fileErrors[path]; !ok {
+			delete(c.lastErrors, path)
+		}
+	}
 }
 
 func getDefaultPath() string {
@@ -1,154 +1,312 @@
 package collector
 
 import (
-	"github.com/dimchansky/utfbom"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/prometheus-community/windows_exporter/log"
+	"github.com/prometheus-community/windows_exporter/textfile"
+	"github.com/prometheus/client_golang/prometheus"
 
 	dto "github.com/prometheus/client_model/go"
 )
 
-func TestCRFilter(t *testing.T) {
-	sr := strings.NewReader("line 1\r\nline 2")
-	cr := carriageReturnFilteringReader{r: sr}
-	b, err := ioutil.ReadAll(cr)
-	if err != nil {
-		t.Error(err)
+func TestListFiles(t *testing.T) {
+	base := t.TempDir()
+	for _, name := range []string{"a/one.prom", "a/ignored.txt", "b/two.prom", "c/three.prom", "c/four.prom"} {
+		path := filepath.Join(base, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("metric 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
 	}
 
-	if string(b) != "line 1\nline 2" {
-		t.Errorf("Unexpected output %q", b)
+	c := &textFileCollector{
+		logger: log.NewNopLogger(),
+		sources: []string{
+			filepath.Join(base, "a"),
+			filepath.Join(base, "[b]"),
+			filepath.Join(base, "c", "th*.prom"),
+			// Files matched by several sources must only be read once.
+			filepath.Join(base, "a", "*.prom"),
+		},
+	}
+	files, errs := c.listFiles()
+	if len(errs) != 0 {
+		t.Fatal(errs)
 	}
-}
 
-func TestCheckBOM(t *testing.T) {
-	testdata := []struct {
-		encoding utfbom.Encoding
-		err      string
-	}{
-		{utfbom.Unknown, ""},
-		{utfbom.UTF8, ""},
-		{utfbom.UTF16BigEndian, "UTF16BigEndian"},
-		{utfbom.UTF16LittleEndian, "UTF16LittleEndian"},
-		{utfbom.UTF32BigEndian, "UTF32BigEndian"},
-		{utfbom.UTF32LittleEndian, "UTF32LittleEndian"},
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
 	}
-	for _, d := range testdata {
-		err := checkBOM(d.encoding)
-		if d.err == "" && err != nil {
-			t.Error(err)
-		}
-		if d.err != "" && err == nil {
-			t.Errorf("Missing expected error %s", d.err)
-		}
-		if err != nil && !strings.Contains(err.Error(), d.err) {
-			t.Error(err)
-		}
+	sort.Strings(paths)
+	expected := []string{
+		filepath.Join(base, "a", "one.prom"),
+		filepath.Join(base, "b", "two.prom"),
+		filepath.Join(base, "c", "three.prom"),
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Unexpected files %v, expected %v", paths, expected)
 	}
+
+	missing := filepath.Join(base, "missing")
+	c.sources = append(c.sources, missing)
+	if _, errs := c.listFiles(); errs[missing] == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
+
+// testCollector adapts a Collector not needing a scrape context to
+// prometheus.Collector.
+type testCollector struct {
+	c Collector
 }
 
-func TestDuplicateMetricEntry(t *testing.T) {
-	metric_name := "windows_sometest"
-	metric_help := "This is a Test."
-	metric_type := dto.MetricType_GAUGE
+func (tc testCollector) Describe(ch chan<- *prometheus.Desc) {}
 
-	gauge_value := 1.0
+func (tc testCollector) Collect(ch chan<- prometheus.Metric) {
+	tc.c.Collect(nil, ch) //nolint:errcheck
+}
 
-	gauge := dto.Gauge{
-		Value: &gauge_value,
+// collectValues returns the values of the metrics collected by c, keyed by
+// metric name and labels.
+func collectValues(t *testing.T, c Collector) map[string]float64 {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(testCollector{c})
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	label1_name := "display_name"
-	label1_value := "foobar"
+	values := map[string]float64{}
+	for _, mf := range families {
+		for _, m := range mf.Metric {
+			labels := make([]string, 0, len(m.Label))
+			for _, l := range m.Label {
+				labels = append(labels, l.GetName()+"="+l.GetValue())
+			}
+			key := mf.GetName() + "{" + strings.Join(labels, ",") + "}"
+			switch mf.GetType() {
+			case dto.MetricType_GAUGE:
+				values[key] = m.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				values[key] = m.GetCounter().GetValue()
+			case dto.MetricType_UNTYPED:
+				values[key] = m.GetUntyped().GetValue()
+			}
+		}
+	}
+	return values
+}
 
-	label1 := dto.LabelPair{
-		Name:  &label1_name,
-		Value: &label1_value,
+func writeTextFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
 	}
+}
 
-	label2_name := "display_version"
-	label2_value := "13.4.0"
+func TestTextFileCollectorPartialAcceptance(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{
+		"a.prom": "# TYPE job_success gauge\njob_success{job=\"a\"} 1\n",
+		"b.prom": "# TYPE job_success gauge\njob_success{job=\"a\"} 0\n",
+		"c.prom": "# TYPE job_success gauge\njob_success{job=\"c\"} 1\n",
+		"d.prom": "job_success{job=\"d\" 1\n",
+		"e.prom": "# TYPE job_success counter\njob_success{job=\"e\"} 1\n",
+	})
 
-	label2 := dto.LabelPair{
-		Name:  &label2_name,
-		Value: &label2_value,
-	}
+	c := &textFileCollector{logger: log.NewNopLogger(), sources: []string{dir}}
+	values := collectValues(t, c)
 
-	metric1 := dto.Metric{
-		Label: []*dto.LabelPair{&label1, &label2},
-		Gauge: &gauge,
+	expected := map[string]float64{
+		`job_success{job=a}`: 1,
+		`job_success{job=c}`: 1,
+		"windows_textfile_scrape_error{file=" + filepath.Join(dir, "a.prom") + ",reason=}":          0,
+		"windows_textfile_scrape_error{file=" + filepath.Join(dir, "b.prom") + ",reason=duplicate}": 1,
+		"windows_textfile_scrape_error{file=" + filepath.Join(dir, "c.prom") + ",reason=}":          0,
+		"windows_textfile_scrape_error{file=" + filepath.Join(dir, "d.prom") + ",reason=parse}":     1,
+		"windows_textfile_scrape_error{file=" + filepath.Join(dir, "e.prom") + ",reason=duplicate}": 1,
+	}
+	for key, value := range expected {
+		if v, ok := values[key]; !ok || v != value {
+			t.Errorf("Expected %s to be %v, got %v (present: %v)", key, value, v, ok)
+		}
+	}
+	if _, ok := values["windows_textfile_mtime_seconds{file="+filepath.Join(dir, "b.prom")+"}"]; ok {
+		t.Error("Rejected file must not have an mtime")
 	}
+}
 
-	metric2 := dto.Metric{
-		Label: []*dto.LabelPair{&label1, &label2},
-		Gauge: &gauge,
+func TestTextFileCollectorTimestamps(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{
+		"a.prom": "# TYPE job_last_run gauge\njob_last_run{job=\"a\"} 1 1600000000000\n",
+	})
+
+	for _, mode := range []string{textfile.TimestampsReject, textfile.TimestampsHonor, textfile.TimestampsStrip} {
+		c := &textFileCollector{logger: log.NewNopLogger(), sources: []string{dir}, timestamps: mode}
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(testCollector{c})
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var metric *dto.Metric
+		for _, mf := range families {
+			if mf.GetName() == "job_last_run" {
+				metric = mf.Metric[0]
+			}
+		}
+
+		switch mode {
+		case textfile.TimestampsReject:
+			if metric != nil {
+				t.Errorf("%s: expected file to be rejected", mode)
+			}
+		case textfile.TimestampsHonor:
+			if metric == nil || metric.GetTimestampMs() != 1600000000000 {
+				t.Errorf("%s: expected timestamp to be kept, got %v", mode, metric)
+			}
+		case textfile.TimestampsStrip:
+			if metric == nil || metric.TimestampMs != nil {
+				t.Errorf("%s: expected timestamp to be stripped, got %v", mode, metric)
+			}
+		}
 	}
+}
 
-	duplicate := dto.MetricFamily{
-		Name:   &metric_name,
-		Help:   &metric_help,
-		Type:   &metric_type,
-		Metric: []*dto.Metric{&metric1, &metric2},
+func TestTextFileCollectorStale(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{
+		"fresh.prom": "job_success{job=\"fresh\"} 1\n",
+		"stale.prom": "job_success{job=\"stale\"} 1\n",
+	})
+	now := time.Now()
+	old := now.Add(-2 * time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "stale.prom"), old, old); err != nil {
+		t.Fatal(err)
 	}
 
-	duplicateFamily := []*dto.MetricFamily{}
-	duplicateFamily = append(duplicateFamily, &duplicate)
+	for _, action := range []string{textFileStaleFlag, textFileStaleIgnore} {
+		c := &textFileCollector{
+			logger:      log.NewNopLogger(),
+			sources:     []string{dir},
+			maxAge:      time.Hour,
+			staleAction: action,
+			now:         func() time.Time { return now },
+		}
+		values := collectValues(t, c)
 
-	// Ensure detection for duplicate metrics
-	if !duplicateMetricEntry(duplicateFamily) {
-		t.Errorf("Duplicate not found in duplicateFamily")
+		if v := values["windows_textfile_stale{file="+filepath.Join(dir, "fresh.prom")+"}"]; v != 0 {
+			t.Errorf("%s: expected fresh file not to be stale, got %v", action, v)
+		}
+		if v := values["windows_textfile_stale{file="+filepath.Join(dir, "stale.prom")+"}"]; v != 1 {
+			t.Errorf("%s: expected stale file to be stale, got %v", action, v)
+		}
+		if _, ok := values["job_success{job=fresh}"]; !ok {
+			t.Errorf("%s: expected metrics of the fresh file", action)
+		}
+		_, ok := values["job_success{job=stale}"]
+		if action == textFileStaleFlag && !ok {
+			t.Errorf("%s: expected metrics of the stale file to be kept", action)
+		}
+		if action == textFileStaleIgnore && ok {
+			t.Errorf("%s: expected metrics of the stale file to be ignored", action)
+		}
+		if v := values["windows_textfile_scrape_error{file="+filepath.Join(dir, "stale.prom")+",reason=}"]; v != 0 {
+			t.Errorf("%s: stale files must not be reported as errors, got %v", action, v)
+		}
 	}
+}
+
+func TestTextFileCollectorFormats(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{
+		"a.prom": "# TYPE job_success gauge\njob_success{job=\"a\"} 1\n",
+		"b.om":   "# TYPE job_success gauge\njob_success{job=\"b\"} 1\n# EOF\n",
+		"c.json": `[{"name": "job_success", "type": "gauge", "labels": {"job": "c"}, "value": 1}]`,
+		"d.json": `[{"name": "job_success", "type": "gauge", "labels": {"job": "a"}, "value": 0}]`,
+		"e.txt":  "job_success{job=\"e\"} 1\n",
+	})
 
-	label3_name := "test"
-	label3_value := "1.0"
+	c := &textFileCollector{logger: log.NewNopLogger(), sources: []string{dir}}
+	values := collectValues(t, c)
 
-	label3 := dto.LabelPair{
-		Name:  &label3_name,
-		Value: &label3_value,
+	expected := map[string]float64{
+		`job_success{job=a}`: 1,
+		`job_success{job=b}`: 1,
+		`job_success{job=c}`: 1,
+		"windows_textfile_scrape_error{file=" + filepath.Join(dir, "d.json") + ",reason=duplicate}": 1,
 	}
-	metric3 := dto.Metric{
-		Label: []*dto.LabelPair{&label1, &label2, &label3},
-		Gauge: &gauge,
+	for key, value := range expected {
+		if v, ok := values[key]; !ok || v != value {
+			t.Errorf("Expected %s to be %v, got %v (present: %v)", key, value, v, ok)
+		}
 	}
-
-	differentLabels := dto.MetricFamily{
-		Name:   &metric_name,
-		Help:   &metric_help,
-		Type:   &metric_type,
-		Metric: []*dto.Metric{&metric1, &metric3},
+	if _, ok := values[`job_success{job=e}`]; ok {
+		t.Error("Files with other extensions must not be read")
 	}
+}
 
-	duplicateFamily = []*dto.MetricFamily{}
-	duplicateFamily = append(duplicateFamily, &differentLabels)
+func TestTextFileCollectorCache(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{
+		"a.prom": "job_success{job=\"a\"} 1\n",
+	})
 
-	// Additional label on second metric should not be cause for duplicate detection
-	if duplicateMetricEntry(duplicateFamily) {
-		t.Errorf("Unexpected duplicate found in differentLabels")
+	c := &textFileCollector{logger: log.NewNopLogger(), sources: []string{dir}}
+	collectValues(t, c)
+	values := collectValues(t, c)
+	if hits, misses := values["windows_textfile_cache_hits_total{}"], values["windows_textfile_cache_misses_total{}"]; hits != 1 || misses != 1 {
+		t.Errorf("Expected 1 cache hit and 1 miss, got %v and %v", hits, misses)
 	}
 
-	label4_value := "2.0"
-
-	label4 := dto.LabelPair{
-		Name:  &label3_name,
-		Value: &label4_value,
+	// Changing the file invalidates its cache entry.
+	writeTextFiles(t, dir, map[string]string{
+		"a.prom": "job_success{job=\"a\"} 0\njob_success{job=\"b\"} 1\n",
+	})
+	values = collectValues(t, c)
+	if v := values["job_success{job=a}"]; v != 0 {
+		t.Errorf("Expected the changed value to be read, got %v", v)
 	}
-	metric4 := dto.Metric{
-		Label: []*dto.LabelPair{&label1, &label2, &label4},
-		Gauge: &gauge,
+	if misses := values["windows_textfile_cache_misses_total{}"]; misses != 2 {
+		t.Errorf("Expected 2 cache misses, got %v", misses)
 	}
+}
 
-	differentValues := dto.MetricFamily{
-		Name:   &metric_name,
-		Help:   &metric_help,
-		Type:   &metric_type,
-		Metric: []*dto.Metric{&metric3, &metric4},
-	}
-	duplicateFamily = []*dto.MetricFamily{}
-	duplicateFamily = append(duplicateFamily, &differentValues)
+func TestTextFileCollectorLimits(t *testing.T) {
+	dir := t.TempDir()
+	writeTextFiles(t, dir, map[string]string{
+		"large.prom":     "# HELP job_success " + strings.Repeat("x", 100) + "\njob_success{job=\"large\"} 1\n",
+		"histogram.prom": "# TYPE latency histogram\nlatency_bucket{le=\"1\"} 1\nlatency_sum 1\nlatency_count 1\n",
+		"small.prom":     "job_success{job=\"small\"} 1\n",
+	})
 
-	// Additional label with different values metric should not be cause for duplicate detection
-	if duplicateMetricEntry(duplicateFamily) {
-		t.Errorf("Unexpected duplicate found in differentValues")
+	c := &textFileCollector{logger: log.NewNopLogger(), sources: []string{dir}, maxFileSize: 100, maxSeries: 3}
+	values := collectValues(t, c)
+
+	expected := map[string]float64{
+		`job_success{job=small}`: 1,
+		"windows_textfile_scrape_error{file=" + filepath.Join(dir, "large.prom") + ",reason=size}":       1,
+		"windows_textfile_scrape_error{file=" + filepath.Join(dir, "histogram.prom") + ",reason=series}": 1,
+		"windows_textfile_rejected_files_total{reason=size}":                                             1,
+		"windows_textfile_rejected_files_total{reason=series}":                                           1,
+	}
+	for key, value := range expected {
+		if v, ok := values[key]; !ok || v != value {
+			t.Errorf("Expected %s to be %v, got %v (present: %v)", key, value, v, ok)
+		}
 	}
 }
@@ -12,6 +12,8 @@ import (
 
 // TimeCollector is a Prometheus collector for Perflib counter metrics
 type TimeCollector struct {
+	logger log.Logger
+
 	ClockFrequencyAdjustmentPPBTotal *prometheus.Desc
 	ComputedTimeOffset               *prometheus.Desc
 	NTPClientTimeSourceCount         *prometheus.Desc
@@ -28,6 +30,7 @@ func newTimeCollector() (Collector, error) {
 	const subsystem = "time"
 
 	return &TimeCollector{
+		logger: log.With(log.CollectorField, "time"),
 		ClockFrequencyAdjustmentPPBTotal: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "clock_frequency_adjustment_ppb_total"),
 			"Total adjustment made to the local system clock frequency by W32Time in Parts Per Billion (PPB) units.",
@@ -71,7 +74,7 @@ func newTimeCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *TimeCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collect(ctx, ch); err != nil {
-		log.Error("failed collecting time metrics:", desc, err)
+		c.logger.Error("failed collecting time metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -89,7 +92,7 @@ type windowsTime struct {
 
 func (c *TimeCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []windowsTime // Single-instance class, array is required but will have single entry.
-	if err := unmarshalObject(ctx.perfObjects["Windows Time Service"], &dst); err != nil {
+	if err := unmarshalObject(ctx.perfObjects["Windows Time Service"], &dst, c.logger); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,135 @@
+//go:build windows
+// +build windows
+
+package collector
+
+import (
+	"github.com/prometheus-community/windows_exporter/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// A UDPCollector is a Prometheus collector for WMI Win32_PerfRawData_Tcpip_UDPv{4,6} metrics
+type UDPCollector struct {
+	logger log.Logger
+
+	DatagramsTotal               *prometheus.Desc
+	DatagramsReceivedTotal       *prometheus.Desc
+	DatagramsReceivedErrorsTotal *prometheus.Desc
+	DatagramsNoPortTotal         *prometheus.Desc
+	DatagramsSentTotal           *prometheus.Desc
+}
+
+// newUDPCollector ...
+func newUDPCollector() (Collector, error) {
+	const subsystem = "udp"
+
+	return &UDPCollector{
+		logger: log.With(log.CollectorField, "udp"),
+		DatagramsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_total"),
+			"Datagrams sent or received (UDP.Datagrams)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsReceivedTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_received_total"),
+			"Datagrams delivered to UDP users (UDP.DatagramsReceived)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsReceivedErrorsTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_received_errors_total"),
+			"Datagrams received that could not be delivered for reasons other than the lack of an application at the destination port (UDP.DatagramsReceivedErrors)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsNoPortTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_no_port_total"),
+			"Datagrams received for which there was no application at the destination port (UDP.DatagramsNoPort)",
+			[]string{"af"},
+			nil,
+		),
+		DatagramsSentTotal: prometheus.NewDesc(
+			prometheus.BuildFQName(Namespace, subsystem, "datagrams_sent_total"),
+			"Datagrams sent (UDP.DatagramsSent)",
+			[]string{"af"},
+			nil,
+		),
+	}, nil
+}
+
+// Collect sends the metric values for each metric
+// to the provided prometheus Metric channel.
+func (c *UDPCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
+	if desc, err := c.collect(ctx, ch); err != nil {
+		c.logger.Error("failed collecting udp metrics:", desc, err)
+		return err
+	}
+	return nil
+}
+
+// Win32_PerfRawData_Tcpip_UDPv4 docs
+// - https://learn.microsoft.com/en-us/previous-versions/aa394345(v=vs.85)
+// The UDPv6 performance object uses the same fields.
+type udp struct {
+	DatagramsPersec         float64 `perflib:"Datagrams/sec"`
+	DatagramsReceivedPersec float64 `perflib:"Datagrams Received/sec"`
+	DatagramsReceivedErrors float64 `perflib:"Datagrams Received Errors"`
+	DatagramsNoPortPersec   float64 `perflib:"Datagrams No Port/sec"`
+	DatagramsSentPersec     float64 `perflib:"Datagrams Sent/sec"`
+}
+
+func writeUDPCounters(metrics udp, labels []string, c *UDPCollector, ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsReceivedTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsReceivedPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsReceivedErrorsTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsReceivedErrors,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsNoPortTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsNoPortPersec,
+		labels...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		c.DatagramsSentTotal,
+		prometheus.CounterValue,
+		metrics.DatagramsSentPersec,
+		labels...,
+	)
+}
+
+func (c *UDPCollector) collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
+	var dst []udp
+
+	// UDPv4 counters
+	if err := unmarshalObject(ctx.perfObjects["UDPv4"], &dst, c.logger); err != nil {
+		return nil, err
+	}
+	if len(dst) != 0 {
+		writeUDPCounters(dst[0], []string{"ipv4"}, c, ch)
+	}
+
+	// UDPv6 counters
+	if err := unmarshalObject(ctx.perfObjects["UDPv6"], &dst, c.logger); err != nil {
+		return nil, err
+	}
+	if len(dst) != 0 {
+		writeUDPCounters(dst[0], []string{"ipv6"}, c, ch)
+	}
+
+	return nil, nil
+}
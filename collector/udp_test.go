@@ -0,0 +1,9 @@
+package collector
+
+import (
+	"testing"
+)
+
+func BenchmarkUDPCollector(b *testing.B) {
+	benchmarkCollector(b, "udp", newUDPCollector)
+}
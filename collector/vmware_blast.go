@@ -6,7 +6,6 @@ package collector
 import (
 	"github.com/prometheus-community/windows_exporter/log"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/yusufpapurcu/wmi"
 )
 
 // A vmwareBlastCollector is a Prometheus collector for WMI metrics:
@@ -24,6 +23,8 @@ import (
 // win32_PerfRawData_Counters_VMwareBlastWindowsMediaMMRCounters
 
 type vmwareBlastCollector struct {
+	logger log.Logger
+
 	AudioReceivedBytes      *prometheus.Desc
 	AudioReceivedPackets    *prometheus.Desc
 	AudioTransmittedBytes   *prometheus.Desc
@@ -110,6 +111,7 @@ type vmwareBlastCollector struct {
 func newVmwareBlastCollector() (Collector, error) {
 	const subsystem = "vmware_blast"
 	return &vmwareBlastCollector{
+		logger: log.With(log.CollectorField, "vmware_blast"),
 		AudioReceivedBytes: prometheus.NewDesc(
 			prometheus.BuildFQName(Namespace, subsystem, "audio_received_bytes_total"),
 			"(AudioReceivedBytes)",
@@ -542,51 +544,51 @@ func newVmwareBlastCollector() (Collector, error) {
 // to the provided prometheus Metric channel.
 func (c *vmwareBlastCollector) Collect(ctx *ScrapeContext, ch chan<- prometheus.Metric) error {
 	if desc, err := c.collectAudio(ch); err != nil {
-		log.Error("failed collecting vmware blast audio metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast audio metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectCdr(ch); err != nil {
-		log.Error("failed collecting vmware blast CDR metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast CDR metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectClipboard(ch); err != nil {
-		log.Error("failed collecting vmware blast clipboard metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast clipboard metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectHtml5Mmr(ch); err != nil {
-		log.Error("failed collecting vmware blast HTML5 MMR metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast HTML5 MMR metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectImaging(ch); err != nil {
-		log.Error("failed collecting vmware blast imaging metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast imaging metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectRtav(ch); err != nil {
-		log.Error("failed collecting vmware blast RTAV metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast RTAV metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectSerialPortandScanner(ch); err != nil {
-		log.Error("failed collecting vmware blast serial port and scanner metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast serial port and scanner metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectSession(ch); err != nil {
-		log.Error("failed collecting vmware blast metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectSkypeforBusinessControl(ch); err != nil {
-		log.Error("failed collecting vmware blast skype for business control metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast skype for business control metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectThinPrint(ch); err != nil {
-		log.Error("failed collecting vmware blast thin print metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast thin print metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectUsb(ch); err != nil {
-		log.Error("failed collecting vmware blast USB metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast USB metrics:", desc, err)
 		return err
 	}
 	if desc, err := c.collectWindowsMediaMmr(ch); err != nil {
-		log.Error("failed collecting vmware blast windows media MMR metrics:", desc, err)
+		c.logger.Error("failed collecting vmware blast windows media MMR metrics:", desc, err)
 		return err
 	}
 	return nil
@@ -699,8 +701,8 @@ type win32_PerfRawData_Counters_VMwareBlastWindowsMediaMMRCounters struct {
 
 func (c *vmwareBlastCollector) collectAudio(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastAudioCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -738,8 +740,8 @@ func (c *vmwareBlastCollector) collectAudio(ch chan<- prometheus.Metric) (*prome
 
 func (c *vmwareBlastCollector) collectCdr(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastCDRCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -777,8 +779,8 @@ func (c *vmwareBlastCollector) collectCdr(ch chan<- prometheus.Metric) (*prometh
 
 func (c *vmwareBlastCollector) collectClipboard(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastClipboardCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -816,8 +818,8 @@ func (c *vmwareBlastCollector) collectClipboard(ch chan<- prometheus.Metric) (*p
 
 func (c *vmwareBlastCollector) collectHtml5Mmr(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastHTML5MMRcounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -855,8 +857,8 @@ func (c *vmwareBlastCollector) collectHtml5Mmr(ch chan<- prometheus.Metric) (*pr
 
 func (c *vmwareBlastCollector) collectImaging(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastImagingCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -942,8 +944,8 @@ func (c *vmwareBlastCollector) collectImaging(ch chan<- prometheus.Metric) (*pro
 
 func (c *vmwareBlastCollector) collectRtav(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastRTAVCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -981,8 +983,8 @@ func (c *vmwareBlastCollector) collectRtav(ch chan<- prometheus.Metric) (*promet
 
 func (c *vmwareBlastCollector) collectSerialPortandScanner(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastSerialPortandScannerCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1020,8 +1022,8 @@ func (c *vmwareBlastCollector) collectSerialPortandScanner(ch chan<- prometheus.
 
 func (c *vmwareBlastCollector) collectSession(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastSessionCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1137,8 +1139,8 @@ func (c *vmwareBlastCollector) collectSession(ch chan<- prometheus.Metric) (*pro
 
 func (c *vmwareBlastCollector) collectSkypeforBusinessControl(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastSkypeforBusinessControlCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1176,8 +1178,8 @@ func (c *vmwareBlastCollector) collectSkypeforBusinessControl(ch chan<- promethe
 
 func (c *vmwareBlastCollector) collectThinPrint(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastThinPrintCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1215,8 +1217,8 @@ func (c *vmwareBlastCollector) collectThinPrint(ch chan<- prometheus.Metric) (*p
 
 func (c *vmwareBlastCollector) collectUsb(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastUSBCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -1254,8 +1256,8 @@ func (c *vmwareBlastCollector) collectUsb(ch chan<- prometheus.Metric) (*prometh
 
 func (c *vmwareBlastCollector) collectWindowsMediaMmr(ch chan<- prometheus.Metric) (*prometheus.Desc, error) {
 	var dst []win32_PerfRawData_Counters_VMwareBlastWindowsMediaMMRCounters
-	q := queryAll(&dst)
-	if err := wmi.Query(q, &dst); err != nil {
+	q := queryAll(&dst, c.logger)
+	if err := wmiQuery(q, &dst); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,534 @@
+//go:build windows
+// +build windows
+
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	ole "github.com/go-ole/go-ole"
+	"github.com/prometheus-community/windows_exporter/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/yusufpapurcu/wmi"
+)
+
+const (
+	FlagWMIQueryTimeout     = "wmi.query-timeout"
+	FlagWMIBreakerThreshold = "wmi.circuit-breaker.threshold"
+	FlagWMIBreakerBackoff   = "wmi.circuit-breaker.backoff"
+)
+
+const (
+	defaultWMIQueryTimeout     = 5 * time.Second
+	defaultWMIBreakerThreshold = 3
+	defaultWMIBreakerBackoff   = 30 * time.Second
+	wmiBreakerMaxBackoffFactor = 16
+	// wmiQueueSize is the number of queries waiting for the worker of a
+	// client, beyond which queries fail immediately.
+	wmiQueueSize = 64
+
+	// Label values of windows_exporter_wmi_query_errors_total for failures
+	// without an HRESULT.
+	wmiErrorCodeTimeout       = "timeout"
+	wmiErrorCodeCanceled      = "canceled"
+	wmiErrorCodeBusy          = "busy"
+	wmiErrorCodeUnknown       = "unknown"
+	wmiErrorCodeInvalidEntity = "invalid_entity"
+	wmiErrorCodeFieldMismatch = "field_mismatch"
+
+	wmiQueryClassUnknown = "unknown"
+)
+
+var (
+	wmiQueryTimeout     *time.Duration
+	wmiBreakerThreshold *int
+	wmiBreakerBackoff   *time.Duration
+
+	errWMIQueryTimeout = errors.New("WMI query timed out")
+	errWMICircuitOpen  = errors.New("WMI circuit breaker open")
+	errWMIBusy         = errors.New("WMI query not started")
+	errWMIClientClosed = errors.New("WMI client closed")
+
+	// defaultWMIClient is used by all collectors querying WMI on the local machine.
+	defaultWMIClient = newWMIClient(
+		wmiQuerierFunc(wmi.Query),
+		defaultWMIQueryTimeout,
+		defaultWMIBreakerThreshold,
+		defaultWMIBreakerBackoff,
+	)
+)
+
+// newWMIFlags registers the flags controlling the WMI access layer.
+func newWMIFlags(app *kingpin.Application) {
+	wmiQueryTimeout = app.Flag(
+		FlagWMIQueryTimeout,
+		"Maximum duration of a single WMI query. The queries run one at a time, and fail immediately while a timed out query is still running. 0 to disable.",
+	).Default(defaultWMIQueryTimeout.String()).Duration()
+	wmiBreakerThreshold = app.Flag(
+		FlagWMIBreakerThreshold,
+		"Number of consecutive failed queries after which a WMI class is no longer queried for a backoff period. 0 to disable.",
+	).Default(fmt.Sprint(defaultWMIBreakerThreshold)).Int()
+	wmiBreakerBackoff = app.Flag(
+		FlagWMIBreakerBackoff,
+		"Initial backoff period for a failing WMI class. Doubles on every consecutive failure.",
+	).Default(defaultWMIBreakerBackoff.String()).Duration()
+}
+
+// wmiQuerier is the interface implemented by anything able to run a WQL query,
+// such as wmi.Client or wmi.SWbemServices.
+type wmiQuerier interface {
+	Query(query string, dst interface{}, connectServerArgs ...interface{}) error
+}
+
+// wmiQuerierFunc adapts a function to the wmiQuerier interface.
+type wmiQuerierFunc func(query string, dst interface{}, connectServerArgs ...interface{}) error
+
+func (f wmiQuerierFunc) Query(query string, dst interface{}, connectServerArgs ...interface{}) error {
+	return f(query, dst, connectServerArgs...)
+}
+
+// wmiBreaker tracks the failure state of a single WMI class.
+type wmiBreaker struct {
+	failures  int
+	backoff   time.Duration
+	openUntil time.Time
+	probing   bool
+}
+
+// wmiJob is a query waiting for, or run by, the worker of a wmiClient.
+type wmiJob struct {
+	query             string
+	dst               interface{}
+	connectServerArgs []interface{}
+	// started is closed by the worker once it starts the query.
+	started chan struct{}
+	done    chan error
+
+	mu        sync.Mutex
+	startTime time.Time
+	abandoned bool
+	// finished is guarded by the mutex of the client.
+	finished bool
+}
+
+// start marks the job as started, unless it was abandoned while queued.
+func (j *wmiJob) start() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.abandoned {
+		return false
+	}
+	j.startTime = time.Now()
+	close(j.started)
+	return true
+}
+
+// abandon gives up a queued job, returning false if it already started.
+func (j *wmiJob) abandon() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	select {
+	case <-j.started:
+		return false
+	default:
+	}
+	j.abandoned = true
+	return true
+}
+
+// wmiClient wraps a wmiQuerier with per-query deadlines, per-class metrics and
+// a circuit breaker backing off from repeatedly failing classes.
+//
+// The queries run one at a time on a single worker goroutine, as
+// wmi.Query serializes them anyway. A query timing out keeps the worker busy
+// until it returns, during which the other queries fail immediately instead
+// of waiting for it and timing out in turn.
+type wmiClient struct {
+	querier          wmiQuerier
+	timeout          time.Duration
+	breakerThreshold int
+	breakerBackoff   time.Duration
+
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+	breakerOpen   *prometheus.GaugeVec
+
+	jobs      chan *wmiJob
+	startOnce sync.Once
+	quit      chan struct{}
+	closeOnce sync.Once
+
+	mu       sync.Mutex
+	breakers map[string]*wmiBreaker
+	// stuck is the running query which timed out, if any.
+	stuck *wmiJob
+	now   func() time.Time
+}
+
+func newWMIClient(querier wmiQuerier, timeout time.Duration, breakerThreshold int, breakerBackoff time.Duration) *wmiClient {
+	return &wmiClient{
+		querier:          querier,
+		timeout:          timeout,
+		breakerThreshold: breakerThreshold,
+		breakerBackoff:   breakerBackoff,
+		queryDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: "exporter",
+				Name:      "wmi_query_duration_seconds",
+				Help:      "windows_exporter: Duration of WMI queries.",
+				Buckets:   prometheus.DefBuckets,
+			},
+			[]string{"class"},
+		),
+		queryErrors: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: "exporter",
+				Name:      "wmi_query_errors_total",
+				Help:      "windows_exporter: Number of failed WMI queries, by HRESULT.",
+			},
+			[]string{"class", "hresult"},
+		),
+		breakerOpen: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: "exporter",
+				Name:      "wmi_circuit_breaker_open",
+				Help:      "windows_exporter: Whether queries for the WMI class are currently suspended after repeated failures.",
+			},
+			[]string{"class"},
+		),
+		jobs:     make(chan *wmiJob, wmiQueueSize),
+		quit:     make(chan struct{}),
+		breakers: make(map[string]*wmiBreaker),
+		now:      time.Now,
+	}
+}
+
+// configure applies the flag values to the client.
+func (c *wmiClient) configure(timeout time.Duration, breakerThreshold int, breakerBackoff time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timeout = timeout
+	c.breakerThreshold = breakerThreshold
+	c.breakerBackoff = breakerBackoff
+}
+
+// Describe implements prometheus.Collector.
+func (c *wmiClient) Describe(ch chan<- *prometheus.Desc) {
+	c.queryDuration.Describe(ch)
+	c.queryErrors.Describe(ch)
+	c.breakerOpen.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *wmiClient) Collect(ch chan<- prometheus.Metric) {
+	c.queryDuration.Collect(ch)
+	c.queryErrors.Collect(ch)
+	c.breakerOpen.Collect(ch)
+}
+
+// Query runs the WQL query with the configured deadline. Queries for a class
+// whose circuit breaker is open fail immediately.
+func (c *wmiClient) Query(query string, dst interface{}, connectServerArgs ...interface{}) error {
+	return c.QueryContext(context.Background(), query, dst, connectServerArgs...)
+}
+
+// QueryContext is like Query, but gives up waiting for the query once ctx is
+// done. Queries given up on, or which didn't start, don't count as failures
+// of the class.
+func (c *wmiClient) QueryContext(ctx context.Context, query string, dst interface{}, connectServerArgs ...interface{}) error {
+	class := wmiQueryClass(query)
+	if err := c.allow(class); err != nil {
+		return err
+	}
+
+	start, err := c.queryWithTimeout(ctx, query, dst, connectServerArgs...)
+	if !start.IsZero() {
+		c.queryDuration.WithLabelValues(class).Observe(time.Since(start).Seconds())
+	}
+	if err != nil {
+		c.queryErrors.WithLabelValues(class, wmiErrorCode(err)).Inc()
+	}
+	if start.IsZero() || errors.Is(err, context.Canceled) {
+		// Release a probing query without judging the class.
+		c.mu.Lock()
+		if b, ok := c.breakers[class]; ok {
+			b.probing = false
+		}
+		c.mu.Unlock()
+		return err
+	}
+	c.record(class, err)
+
+	return err
+}
+
+// Close stops the worker once its running query, if any, returns, and then
+// closes the querier if it holds resources. The queries not started yet fail.
+func (c *wmiClient) Close() error {
+	c.startOnce.Do(func() { go c.run() })
+	c.closeOnce.Do(func() { close(c.quit) })
+	return nil
+}
+
+// run runs the queued queries one at a time, until the client is closed.
+func (c *wmiClient) run() {
+	for {
+		var job *wmiJob
+		select {
+		case job = <-c.jobs:
+		case <-c.quit:
+			if closer, ok := c.querier.(io.Closer); ok {
+				if err := closer.Close(); err != nil {
+					log.WithError(err).Debug("Failed to close WMI querier")
+				}
+			}
+			return
+		}
+		if !job.start() {
+			continue
+		}
+		err := c.querier.Query(job.query, job.dst, job.connectServerArgs...)
+
+		c.mu.Lock()
+		job.finished = true
+		if c.stuck == job {
+			c.stuck = nil
+			log.With(log.ClassField, wmiQueryClass(job.query)).Infof("Timed out WMI query completed after %s, resuming queries", time.Since(job.startTime))
+		}
+		c.mu.Unlock()
+		job.done <- err
+	}
+}
+
+// queryWithTimeout queues the query for the worker, and waits for it to start
+// and then to complete. It returns the start time of the query, zero if it
+// didn't start.
+func (c *wmiClient) queryWithTimeout(ctx context.Context, query string, dst interface{}, connectServerArgs ...interface{}) (time.Time, error) {
+	c.mu.Lock()
+	timeout := c.timeout
+	stuck := c.stuck != nil
+	c.mu.Unlock()
+	if stuck {
+		return time.Time{}, fmt.Errorf("%w, a timed out query is still running: %s", errWMIBusy, query)
+	}
+	select {
+	case <-c.quit:
+		return time.Time{}, fmt.Errorf("%w: %s", errWMIClientClosed, query)
+	default:
+	}
+
+	dv := reflect.ValueOf(dst)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() || dv.Elem().Kind() != reflect.Slice {
+		return time.Time{}, wmi.ErrInvalidEntityType
+	}
+
+	// The query keeps running in the background if it times out, so it must
+	// not write to the caller's destination.
+	tmp := reflect.New(dv.Elem().Type())
+	job := &wmiJob{
+		query:             query,
+		dst:               tmp.Interface(),
+		connectServerArgs: connectServerArgs,
+		started:           make(chan struct{}),
+		done:              make(chan error, 1),
+	}
+	c.startOnce.Do(func() { go c.run() })
+	select {
+	case c.jobs <- job:
+	default:
+		return time.Time{}, fmt.Errorf("%w, too many queued queries: %s", errWMIBusy, query)
+	}
+
+	// The timeout applies to the wait for the worker as well, but the query
+	// is only given up on while it is still queued.
+	var queuedCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		queuedCh = timer.C
+	}
+	select {
+	case <-job.started:
+	case <-queuedCh:
+		if job.abandon() {
+			return time.Time{}, fmt.Errorf("%w after %s: %s", errWMIBusy, timeout, query)
+		}
+	case <-ctx.Done():
+		if job.abandon() {
+			return time.Time{}, fmt.Errorf("%w: %s", ctx.Err(), query)
+		}
+	case <-c.quit:
+		if job.abandon() {
+			return time.Time{}, fmt.Errorf("%w: %s", errWMIClientClosed, query)
+		}
+	}
+	<-job.started
+
+	var timeoutCh <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout - time.Since(job.startTime))
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
+	select {
+	case err := <-job.done:
+		dv.Elem().Set(reflect.AppendSlice(dv.Elem(), tmp.Elem()))
+		return job.startTime, err
+	case <-timeoutCh:
+		c.mu.Lock()
+		if !job.finished {
+			c.stuck = job
+		}
+		c.mu.Unlock()
+		return job.startTime, fmt.Errorf("%w after %s: %s", errWMIQueryTimeout, timeout, query)
+	case <-ctx.Done():
+		return job.startTime, fmt.Errorf("%w: %s", ctx.Err(), query)
+	}
+}
+
+// allow reports whether a query for the class may run.
+func (c *wmiClient) allow(class string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[class]
+	if !ok || c.breakerThreshold <= 0 || b.failures < c.breakerThreshold {
+		return nil
+	}
+	if c.now().Before(b.openUntil) || b.probing {
+		return fmt.Errorf("%w for class %s", errWMICircuitOpen, class)
+	}
+	// Backoff expired, let a single query through to probe the class.
+	b.probing = true
+	return nil
+}
+
+// record updates the circuit breaker of the class with the outcome of a query.
+func (c *wmiClient) record(class string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.breakers[class]
+	if !ok {
+		b = &wmiBreaker{}
+		c.breakers[class] = b
+	}
+	b.probing = false
+
+	if err == nil {
+		if b.failures >= c.breakerThreshold && c.breakerThreshold > 0 {
+			log.With(log.ClassField, class).Info("WMI class is responding again, resuming queries")
+		}
+		b.failures = 0
+		b.backoff = 0
+		c.breakerOpen.WithLabelValues(class).Set(0)
+		return
+	}
+
+	b.failures++
+	if c.breakerThreshold <= 0 || b.failures < c.breakerThreshold {
+		return
+	}
+
+	switch {
+	case b.backoff == 0:
+		b.backoff = c.breakerBackoff
+	case b.backoff < c.breakerBackoff*wmiBreakerMaxBackoffFactor:
+		b.backoff *= 2
+	}
+	b.openUntil = c.now().Add(b.backoff)
+	c.breakerOpen.WithLabelValues(class).Set(1)
+	log.WithFields(log.Fields{
+		log.ClassField: class,
+		"failures":     b.failures,
+		"backoff":      b.backoff.String(),
+	}).WithError(err).Warn("WMI class failed too many consecutive times, suspending queries")
+}
+
+// wmiQueryClass extracts the class name from a WQL query.
+func wmiQueryClass(query string) string {
+	fields := strings.Fields(query)
+	for i := 0; i < len(fields)-1; i++ {
+		if strings.EqualFold(fields[i], "FROM") {
+			return fields[i+1]
+		}
+	}
+	return wmiQueryClassUnknown
+}
+
+// wmiErrorCode returns a label value identifying the cause of a failed query,
+// preferably the HRESULT of the underlying COM error.
+func wmiErrorCode(err error) string {
+	if errors.Is(err, errWMIQueryTimeout) {
+		return wmiErrorCodeTimeout
+	}
+	if errors.Is(err, context.Canceled) {
+		return wmiErrorCodeCanceled
+	}
+	if errors.Is(err, errWMIBusy) {
+		return wmiErrorCodeBusy
+	}
+	if errors.Is(err, wmi.ErrInvalidEntityType) {
+		return wmiErrorCodeInvalidEntity
+	}
+	var mismatch *wmi.ErrFieldMismatch
+	if errors.As(err, &mismatch) {
+		return wmiErrorCodeFieldMismatch
+	}
+
+	var oleErr *ole.OleError
+	if errors.As(err, &oleErr) {
+		// DISP_E_EXCEPTION carries the WMI error code in the exception info.
+		switch excep := oleErr.SubError().(type) {
+		case ole.EXCEPINFO:
+			if excep.SCODE() != 0 {
+				return fmt.Sprintf("0x%08X", excep.SCODE())
+			}
+		case *ole.EXCEPINFO:
+			if excep != nil && excep.SCODE() != 0 {
+				return fmt.Sprintf("0x%08X", excep.SCODE())
+			}
+		}
+		return fmt.Sprintf("0x%08X", uint32(oleErr.Code()))
+	}
+
+	return wmiErrorCodeUnknown
+}
+
+// wmiQuery runs the query against the default namespace of the local machine.
+func wmiQuery(query string, dst interface{}) error {
+	return defaultWMIClient.Query(query, dst)
+}
+
+// wmiQueryNamespace runs the query against the given namespace of the local machine.
+func wmiQueryNamespace(query string, dst interface{}, namespace string) error {
+	return defaultWMIClient.Query(query, dst, nil, namespace)
+}
+
+// wmiQuery runs the query against the default namespace of the scrape target.
+func (ctx *ScrapeContext) wmiQuery(query string, dst interface{}) error {
+	if ctx.wmi == nil {
+		return wmiQuery(query, dst)
+	}
+	return ctx.wmi.QueryContext(ctx.context(), query, dst)
+}
+
+// wmiQueryNamespace runs the query against the given namespace of the scrape target.
+func (ctx *ScrapeContext) wmiQueryNamespace(query string, dst interface{}, namespace string) error {
+	if ctx.wmi == nil {
+		return wmiQueryNamespace(query, dst, namespace)
+	}
+	return ctx.wmi.QueryContext(ctx.context(), query, dst, nil, namespace)
+}
@@ -0,0 +1,281 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+type fakeWMIResult struct {
+	Name string
+}
+
+// wmiMetricValue returns the value of a counter, or the sample count of a
+// histogram.
+func wmiMetricValue(t *testing.T, m prometheus.Metric) float64 {
+	var pb dto.Metric
+	if err := m.Write(&pb); err != nil {
+		t.Fatal(err)
+	}
+	if pb.Histogram != nil {
+		return float64(pb.Histogram.GetSampleCount())
+	}
+	return pb.GetCounter().GetValue()
+}
+
+func TestWMIQueryClass(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM Win32_Service":                     "Win32_Service",
+		"SELECT * FROM Win32_Service WHERE Name='foo'":    "Win32_Service",
+		"select Name, State from win32_process where 1=1": "win32_process",
+		"SELECT *": wmiQueryClassUnknown,
+	}
+	for query, expected := range cases {
+		if class := wmiQueryClass(query); class != expected {
+			t.Errorf("wmiQueryClass(%q) = %q, expected %q", query, class, expected)
+		}
+	}
+}
+
+func TestWMIClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	querier := wmiQuerierFunc(func(query string, dst interface{}, connectServerArgs ...interface{}) error {
+		<-release
+		*dst.(*[]fakeWMIResult) = append(*dst.(*[]fakeWMIResult), fakeWMIResult{Name: "late"})
+		return nil
+	})
+	c := newWMIClient(querier, 10*time.Millisecond, 0, time.Minute)
+
+	var dst []fakeWMIResult
+	err := c.Query("SELECT * FROM Fake", &dst)
+	if !errors.Is(err, errWMIQueryTimeout) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if len(dst) != 0 {
+		t.Errorf("expected destination to be untouched after timeout, got %v", dst)
+	}
+	if code := wmiErrorCode(err); code != wmiErrorCodeTimeout {
+		t.Errorf("expected error code %q, got %q", wmiErrorCodeTimeout, code)
+	}
+}
+
+func TestWMIClientCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	querier := wmiQuerierFunc(func(query string, dst interface{}, connectServerArgs ...interface{}) error {
+		<-release
+		return nil
+	})
+	// Without query timeout, and with a breaker opening on the first failure.
+	c := newWMIClient(querier, 0, 1, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	var dst []fakeWMIResult
+	err := c.QueryContext(ctx, "SELECT * FROM Fake", &dst)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+	if code := wmiErrorCode(err); code != wmiErrorCodeCanceled {
+		t.Errorf("expected error code %q, got %q", wmiErrorCodeCanceled, code)
+	}
+	if err := c.allow("Fake"); err != nil {
+		t.Errorf("expected cancelled queries not to open the circuit breaker, got %v", err)
+	}
+}
+
+func TestWMIClientResults(t *testing.T) {
+	querier := wmiQuerierFunc(func(query string, dst interface{}, connectServerArgs ...interface{}) error {
+		*dst.(*[]fakeWMIResult) = append(*dst.(*[]fakeWMIResult), fakeWMIResult{Name: "a"}, fakeWMIResult{Name: "b"})
+		return nil
+	})
+	c := newWMIClient(querier, time.Second, 0, time.Minute)
+
+	var dst []fakeWMIResult
+	if err := c.Query("SELECT * FROM Fake", &dst); err != nil {
+		t.Fatal(err)
+	}
+	if len(dst) != 2 || dst[0].Name != "a" || dst[1].Name != "b" {
+		t.Errorf("unexpected results %v", dst)
+	}
+}
+
+func TestWMIClientCircuitBreaker(t *testing.T) {
+	calls := 0
+	failing := true
+	querier := wmiQuerierFunc(func(query string, dst interface{}, connectServerArgs ...interface{}) error {
+		calls++
+		if failing {
+			return errors.New("provider failure")
+		}
+		return nil
+	})
+	now := time.Unix(0, 0)
+	c := newWMIClient(querier, 0, 2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	var dst []fakeWMIResult
+	for i := 0; i < 2; i++ {
+		if err := c.Query("SELECT * FROM Fake", &dst); err == nil {
+			t.Fatal("expected query to fail")
+		}
+	}
+
+	// Threshold reached, the class must not be queried anymore.
+	if err := c.Query("SELECT * FROM Fake", &dst); !errors.Is(err, errWMICircuitOpen) {
+		t.Fatalf("expected circuit open error, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls to the querier, got %d", calls)
+	}
+
+	// Other classes are unaffected.
+	failing = false
+	if err := c.Query("SELECT * FROM Other", &dst); err != nil {
+		t.Errorf("unexpected error for other class: %v", err)
+	}
+
+	// A failed probe after the backoff doubles the backoff.
+	failing = true
+	now = now.Add(time.Minute)
+	if err := c.Query("SELECT * FROM Fake", &dst); err == nil || errors.Is(err, errWMICircuitOpen) {
+		t.Fatalf("expected probe query to run and fail, got %v", err)
+	}
+	now = now.Add(time.Minute)
+	if err := c.Query("SELECT * FROM Fake", &dst); !errors.Is(err, errWMICircuitOpen) {
+		t.Fatalf("expected circuit to stay open with doubled backoff, got %v", err)
+	}
+
+	// A successful probe closes the circuit.
+	failing = false
+	now = now.Add(time.Minute)
+	if err := c.Query("SELECT * FROM Fake", &dst); err != nil {
+		t.Fatalf("expected probe query to succeed, got %v", err)
+	}
+	if err := c.Query("SELECT * FROM Fake", &dst); err != nil {
+		t.Errorf("expected circuit to be closed, got %v", err)
+	}
+}
+
+func TestWMIClientHungClass(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	calls := map[string]int{}
+	querier := wmiQuerierFunc(func(query string, dst interface{}, connectServerArgs ...interface{}) error {
+		class := wmiQueryClass(query)
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		calls[class]++
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+		if class == "Win32_LogonSession" {
+			<-release
+		}
+		return nil
+	})
+	c := newWMIClient(querier, 20*time.Millisecond, 1, time.Minute)
+
+	var dst []fakeWMIResult
+	if err := c.Query("SELECT * FROM Win32_LogonSession", &dst); !errors.Is(err, errWMIQueryTimeout) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+
+	// The other classes don't start while the hung query runs, and aren't
+	// held against their circuit breaker.
+	for i := 0; i < 3; i++ {
+		if err := c.Query("SELECT * FROM Win32_Service", &dst); !errors.Is(err, errWMIBusy) {
+			t.Fatalf("expected busy error, got %v", err)
+		}
+	}
+	mu.Lock()
+	if calls["Win32_Service"] != 0 {
+		t.Errorf("expected Win32_Service not to be queried, got %d queries", calls["Win32_Service"])
+	}
+	mu.Unlock()
+	if v := wmiMetricValue(t, c.queryErrors.WithLabelValues("Win32_Service", wmiErrorCodeTimeout)); v != 0 {
+		t.Errorf("expected no Win32_Service timeouts, got %v", v)
+	}
+	if v := wmiMetricValue(t, c.queryErrors.WithLabelValues("Win32_Service", wmiErrorCodeBusy)); v != 3 {
+		t.Errorf("expected 3 busy Win32_Service queries, got %v", v)
+	}
+	if v := wmiMetricValue(t, c.queryDuration.WithLabelValues("Win32_Service").(prometheus.Metric)); v != 0 {
+		t.Errorf("expected no Win32_Service durations, got %v", v)
+	}
+	if err := c.allow("Win32_Service"); err != nil {
+		t.Errorf("expected the Win32_Service circuit breaker to be closed, got %v", err)
+	}
+	if err := c.allow("Win32_LogonSession"); !errors.Is(err, errWMICircuitOpen) {
+		t.Errorf("expected the Win32_LogonSession circuit breaker to be open, got %v", err)
+	}
+
+	// Queries resume once the hung query returns.
+	close(release)
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+		c.mu.Lock()
+		stuck := c.stuck != nil
+		c.mu.Unlock()
+		if !stuck {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the hung query to complete")
+		}
+	}
+	if err := c.Query("SELECT * FROM Win32_Service", &dst); err != nil {
+		t.Fatalf("expected Win32_Service query to succeed, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if maxRunning != 1 {
+		t.Errorf("expected queries to run one at a time, got %d concurrent queries", maxRunning)
+	}
+}
+
+func TestWMIClientQueuedDuration(t *testing.T) {
+	started := make(chan struct{})
+	querier := wmiQuerierFunc(func(query string, dst interface{}, connectServerArgs ...interface{}) error {
+		if wmiQueryClass(query) == "Slow" {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+		}
+		return nil
+	})
+	c := newWMIClient(querier, time.Second, 0, time.Minute)
+
+	slow := make(chan error, 1)
+	go func() {
+		var dst []fakeWMIResult
+		slow <- c.Query("SELECT * FROM Slow", &dst)
+	}()
+	<-started
+
+	// The query waits for the slow one, which isn't part of its duration.
+	var dst []fakeWMIResult
+	if err := c.Query("SELECT * FROM Fast", &dst); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+	var pb dto.Metric
+	if err := c.queryDuration.WithLabelValues("Fast").(prometheus.Metric).Write(&pb); err != nil {
+		t.Fatal(err)
+	}
+	if sum := pb.Histogram.GetSampleSum(); sum >= 0.05 {
+		t.Errorf("expected the duration to exclude the wait for the worker, got %vs", sum)
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProbeModule describes how a remote host is scraped on the /probe endpoint.
+type ProbeModule struct {
+	// Username and Password are the credentials used to connect to WMI on
+	// the target. Leave empty to connect as the exporter's own account.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Collectors lists the collectors to run against the target.
+	Collectors []string `yaml:"collectors"`
+}
+
+// ProbeConfig is the content of the probe modules configuration file.
+type ProbeConfig struct {
+	Modules map[string]ProbeModule `yaml:"modules"`
+}
+
+// LoadProbeConfig reads and validates the probe modules configuration file.
+func LoadProbeConfig(file string) (*ProbeConfig, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseProbeConfig(b)
+}
+
+func parseProbeConfig(b []byte) (*ProbeConfig, error) {
+	var c ProbeConfig
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	if len(c.Modules) == 0 {
+		return nil, fmt.Errorf("no probe modules defined")
+	}
+	for name, m := range c.Modules {
+		if len(m.Collectors) == 0 {
+			return nil, fmt.Errorf("probe module %q has no collectors", name)
+		}
+		if m.Password != "" && m.Username == "" {
+			return nil, fmt.Errorf("probe module %q has a password but no username", name)
+		}
+	}
+	return &c, nil
+}
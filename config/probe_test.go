@@ -0,0 +1,46 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProbeConfig(t *testing.T) {
+	goodYamlConfig := []byte(`---
+modules:
+  default:
+    collectors: [logon, service]
+  legacy:
+    username: DOMAIN\monitoring
+    password: secret
+    collectors:
+      - cpu_info`)
+
+	c, err := parseProbeConfig(goodYamlConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]ProbeModule{
+		"default": {Collectors: []string{"logon", "service"}},
+		"legacy":  {Username: `DOMAIN\monitoring`, Password: "secret", Collectors: []string{"cpu_info"}},
+	}
+	if !reflect.DeepEqual(expected, c.Modules) {
+		t.Errorf("Probe modules do not match!\nExpected result: %v\nActual result: %v", expected, c.Modules)
+	}
+}
+
+func TestParseProbeConfigInvalid(t *testing.T) {
+	cases := map[string]string{
+		"no modules":    `modules: {}`,
+		"no collectors": "modules:\n  default:\n    username: foo",
+		"no username":   "modules:\n  default:\n    password: foo\n    collectors: [logon]",
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseProbeConfig([]byte(c)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
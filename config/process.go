@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+var processGroupNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// ProcessGroup describes a group of processes reported together by the
+// process collector. A process belongs to the group if it matches all the
+// given regexps, which must match the whole value.
+type ProcessGroup struct {
+	// Name identifies the group in the group label of its metrics.
+	Name string `yaml:"name"`
+	// Image matches the name of the process, without extension, e.g. chrome.
+	Image string `yaml:"image"`
+	// Cmdline matches the command line of the process.
+	Cmdline string `yaml:"cmdline"`
+	// User matches the account running the process, as DOMAIN\user.
+	User string `yaml:"user"`
+
+	image   *regexp.Regexp
+	cmdline *regexp.Regexp
+	user    *regexp.Regexp
+}
+
+// Match returns whether the process belongs to the group, given its name,
+// command line and user. The command line and the user are only compared if
+// the group matches them.
+func (g *ProcessGroup) Match(image, cmdline, user string) bool {
+	return (g.image == nil || g.image.MatchString(image)) &&
+		(g.cmdline == nil || g.cmdline.MatchString(cmdline)) &&
+		(g.user == nil || g.user.MatchString(user))
+}
+
+// ProcessGroupConfig is the content of the process groups configuration file.
+type ProcessGroupConfig struct {
+	Groups []ProcessGroup `yaml:"groups"`
+}
+
+// UsesCmdline returns whether a group matches the command lines.
+func (c *ProcessGroupConfig) UsesCmdline() bool {
+	for _, g := range c.Groups {
+		if g.Cmdline != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesUser returns whether a group matches the users.
+func (c *ProcessGroupConfig) UsesUser() bool {
+	for _, g := range c.Groups {
+		if g.User != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadProcessGroupConfig reads and validates the process groups
+// configuration file.
+func LoadProcessGroupConfig(file string) (*ProcessGroupConfig, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseProcessGroupConfig(b)
+}
+
+func parseProcessGroupConfig(b []byte) (*ProcessGroupConfig, error) {
+	var c ProcessGroupConfig
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for i := range c.Groups {
+		g := &c.Groups[i]
+		if !processGroupNameRegexp.MatchString(g.Name) {
+			return nil, fmt.Errorf("process group %d has an invalid name %q", i, g.Name)
+		}
+		if names[g.Name] {
+			return nil, fmt.Errorf("process group %q is defined more than once", g.Name)
+		}
+		names[g.Name] = true
+		if g.Image == "" && g.Cmdline == "" && g.User == "" {
+			return nil, fmt.Errorf("process group %q has no image, cmdline or user", g.Name)
+		}
+
+		var err error
+		if g.image, err = compileProcessGroupRegexp(g.Image); err != nil {
+			return nil, fmt.Errorf("process group %q has an invalid image: %w", g.Name, err)
+		}
+		if g.cmdline, err = compileProcessGroupRegexp(g.Cmdline); err != nil {
+			return nil, fmt.Errorf("process group %q has an invalid cmdline: %w", g.Name, err)
+		}
+		if g.user, err = compileProcessGroupRegexp(g.User); err != nil {
+			return nil, fmt.Errorf("process group %q has an invalid user: %w", g.Name, err)
+		}
+	}
+	return &c, nil
+}
+
+func compileProcessGroupRegexp(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(fmt.Sprintf("^(?:%s)$", expr))
+}
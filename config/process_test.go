@@ -0,0 +1,81 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseProcessGroupConfig(t *testing.T) {
+	goodYamlConfig := []byte(`---
+groups:
+  - name: build
+    image: msbuild|cl|link
+  - name: agent
+    image: java
+    cmdline: '.*-jar .*agent\.jar.*'
+    user: 'CORP\\svc_agent'
+  - name: iis
+    user: 'IIS APPPOOL\\.+'`)
+
+	c, err := parseProcessGroupConfig(goodYamlConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(c.Groups))
+	}
+	if !c.UsesCmdline() || !c.UsesUser() {
+		t.Error("expected the groups to use the command lines and the users")
+	}
+
+	cases := []struct {
+		group                string
+		image, cmdline, user string
+		match                bool
+	}{
+		{"build", "msbuild", "", "", true},
+		{"build", "link", "", "", true},
+		// The regexps match the whole value.
+		{"build", "msbuildtaskhost", "", "", false},
+		{"agent", "java", `java.exe -jar C:\agent\agent.jar`, `CORP\svc_agent`, true},
+		{"agent", "java", `java.exe -jar C:\agent\agent.jar`, `CORP\someone`, false},
+		{"agent", "java", `java.exe -jar app.jar`, `CORP\svc_agent`, false},
+		{"iis", "w3wp", "", `IIS APPPOOL\DefaultAppPool`, true},
+		{"iis", "w3wp", "", `NT AUTHORITY\SYSTEM`, false},
+	}
+	for _, tc := range cases {
+		for i := range c.Groups {
+			g := &c.Groups[i]
+			if g.Name != tc.group {
+				continue
+			}
+			if match := g.Match(tc.image, tc.cmdline, tc.user); match != tc.match {
+				t.Errorf("expected %s (%q, %q) matching %s to be %v", tc.image, tc.cmdline, tc.user, tc.group, tc.match)
+			}
+		}
+	}
+
+	c, err = parseProcessGroupConfig([]byte("groups:\n  - name: build\n    image: msbuild"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.UsesCmdline() || c.UsesUser() {
+		t.Error("expected the groups not to use the command lines nor the users")
+	}
+}
+
+func TestParseProcessGroupConfigInvalid(t *testing.T) {
+	cases := map[string]string{
+		"no name":        "groups:\n  - image: foo",
+		"invalid name":   "groups:\n  - name: foo bar\n    image: foo",
+		"duplicate name": "groups:\n  - name: foo\n    image: foo\n  - name: foo\n    image: bar",
+		"no matcher":     "groups:\n  - name: foo",
+		"invalid regexp": "groups:\n  - name: foo\n    cmdline: '(foo'",
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseProcessGroupConfig([]byte(c)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+var scriptNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Script describes a command run by the script collector, whose standard
+// output holds metrics in the Prometheus text format.
+type Script struct {
+	// Name identifies the script in the script label of its metrics.
+	Name    string   `yaml:"name"`
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+	// WorkingDir is the directory the command runs in. Defaults to the
+	// exporter's working directory.
+	WorkingDir string `yaml:"working_dir"`
+	// Timeout after which the command is killed. Defaults to the
+	// collector.script.timeout flag.
+	Timeout time.Duration `yaml:"timeout"`
+	// Interval between two runs of the command. Zero runs the command on
+	// every scrape.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// ScriptConfig is the content of the script collector configuration file.
+type ScriptConfig struct {
+	Scripts []Script `yaml:"scripts"`
+}
+
+// LoadScriptConfig reads and validates the script collector configuration file.
+func LoadScriptConfig(file string) (*ScriptConfig, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseScriptConfig(b)
+}
+
+func parseScriptConfig(b []byte) (*ScriptConfig, error) {
+	var c ScriptConfig
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for i, s := range c.Scripts {
+		if !scriptNameRegexp.MatchString(s.Name) {
+			return nil, fmt.Errorf("script %d has an invalid name %q", i, s.Name)
+		}
+		if names[s.Name] {
+			return nil, fmt.Errorf("script %q is defined more than once", s.Name)
+		}
+		names[s.Name] = true
+		if s.Command == "" {
+			return nil, fmt.Errorf("script %q has no command", s.Name)
+		}
+		if s.Timeout < 0 || s.Interval < 0 {
+			return nil, fmt.Errorf("script %q has a negative timeout or interval", s.Name)
+		}
+		if s.Interval > 0 && s.Timeout > s.Interval {
+			return nil, fmt.Errorf("script %q has a timeout longer than its interval", s.Name)
+		}
+	}
+	return &c, nil
+}
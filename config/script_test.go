@@ -0,0 +1,57 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseScriptConfig(t *testing.T) {
+	goodYamlConfig := []byte(`---
+scripts:
+  - name: backup
+    command: powershell.exe
+    args: [-NoProfile, -File, 'C:\scripts\backup.ps1']
+    working_dir: C:\scripts
+    timeout: 30s
+    interval: 5m
+  - name: sessions
+    command: C:\scripts\sessions.exe`)
+
+	c, err := parseScriptConfig(goodYamlConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Script{
+		{
+			Name:       "backup",
+			Command:    "powershell.exe",
+			Args:       []string{"-NoProfile", "-File", `C:\scripts\backup.ps1`},
+			WorkingDir: `C:\scripts`,
+			Timeout:    30 * time.Second,
+			Interval:   5 * time.Minute,
+		},
+		{Name: "sessions", Command: `C:\scripts\sessions.exe`},
+	}
+	if !reflect.DeepEqual(expected, c.Scripts) {
+		t.Errorf("Scripts do not match!\nExpected result: %v\nActual result: %v", expected, c.Scripts)
+	}
+}
+
+func TestParseScriptConfigInvalid(t *testing.T) {
+	cases := map[string]string{
+		"no name":          "scripts:\n  - command: foo",
+		"invalid name":     "scripts:\n  - name: foo bar\n    command: foo",
+		"duplicate name":   "scripts:\n  - name: foo\n    command: foo\n  - name: foo\n    command: bar",
+		"no command":       "scripts:\n  - name: foo",
+		"timeout too long": "scripts:\n  - name: foo\n    command: foo\n    timeout: 2m\n    interval: 1m",
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := parseScriptConfig([]byte(c)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
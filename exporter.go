@@ -8,15 +8,20 @@ import (
 	"github.com/prometheus-community/windows_exporter/initiate"
 	"github.com/prometheus-community/windows_exporter/log"
 
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
 	"os/user"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/prometheus-community/windows_exporter/collector"
@@ -46,6 +51,10 @@ type prometheusVersion struct {
 const (
 	defaultCollectors            = "cpu,cs,logical_disk,net,os,service,system,textfile"
 	defaultCollectorsPlaceholder = "[defaults]"
+
+	// stopMargin is the time given to the exporter to stop once the scrapes
+	// in flight completed or were cancelled.
+	stopMargin = 10 * time.Second
 )
 
 func expandEnabledCollectors(enabled string) []string {
@@ -79,6 +88,107 @@ func loadCollectors(list string) (map[string]collector.Collector, error) {
 	return collectors, nil
 }
 
+// collectorSet holds the enabled collectors, which are rebuilt when the
+// configuration is reloaded.
+type collectorSet struct {
+	mu      sync.RWMutex
+	current *collectorGeneration
+	// closing tracks the previous generations waiting to be closed.
+	closing sync.WaitGroup
+}
+
+// collectorGeneration is a set of collectors, along with the scrapes using it.
+type collectorGeneration struct {
+	collectors map[string]collector.Collector
+	inFlight   sync.WaitGroup
+}
+
+func newCollectorSet(collectors map[string]collector.Collector) *collectorSet {
+	return &collectorSet{current: &collectorGeneration{collectors: collectors}}
+}
+
+// acquire returns the current collectors, which are kept open until release
+// is called.
+func (s *collectorSet) acquire() (collectors map[string]collector.Collector, release func()) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g := s.current
+	g.inFlight.Add(1)
+	return g.collectors, g.inFlight.Done
+}
+
+// replace sets the collectors of the next scrapes. The previous ones are
+// closed once the scrapes using them complete, or after gracePeriod.
+func (s *collectorSet) replace(collectors map[string]collector.Collector, gracePeriod time.Duration) {
+	s.mu.Lock()
+	previous := s.current
+	s.current = &collectorGeneration{collectors: collectors}
+	s.mu.Unlock()
+
+	// No scrape acquires the previous generation anymore.
+	s.closing.Add(1)
+	go func() {
+		defer s.closing.Done()
+		if !previous.wait(gracePeriod) {
+			log.Warnf("Scrapes using the previous collectors didn't complete within %s, closing them anyway", gracePeriod)
+		}
+		collector.Close(previous.collectors)
+	}()
+}
+
+// close closes the current collectors and waits for the previous ones to be
+// closed.
+func (s *collectorSet) close() {
+	s.mu.RLock()
+	collectors := s.current.collectors
+	s.mu.RUnlock()
+	collector.Close(collectors)
+	s.closing.Wait()
+}
+
+// wait waits for the scrapes in flight to complete, returning false if they
+// didn't within timeout.
+func (g *collectorGeneration) wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		g.inFlight.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// reloadConfig applies the configuration file again and rebuilds the
+// collectors. The listen addresses and the probe configuration aren't
+// reloaded.
+func reloadConfig(app *kingpin.Application, configFile string, webConfig *web.FlagConfig, enabledCollectors *string) (map[string]collector.Collector, error) {
+	if configFile != "" {
+		resolver, err := config.NewResolver(configFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load config file: %w", err)
+		}
+		if err := resolver.Bind(app, os.Args[1:]); err != nil {
+			return nil, err
+		}
+		// Parsing again appends to the values of slice flags.
+		listenAddresses := *webConfig.WebListenAddresses
+		_, err = app.Parse(os.Args[1:])
+		*webConfig.WebListenAddresses = listenAddresses
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	collector.RegisterCollectors()
+	return loadCollectors(*enabledCollectors)
+}
+
 func initWbem() {
 	// This initialization prevents a memory leak on WMF 5+. See
 	// https://github.com/prometheus-community/windows_exporter/issues/77 and
@@ -108,6 +218,10 @@ func main() {
 			"web.disable-exporter-metrics",
 			"Exclude metrics about the exporter itself (promhttp_*, process_*, go_*).",
 		).Bool()
+		shutdownGracePeriod = app.Flag(
+			"web.shutdown-grace-period",
+			"Time given to the scrapes in flight to complete when stopping, after which they are cancelled.",
+		).Default("20s").Duration()
 		maxRequests = app.Flag(
 			"telemetry.max-requests",
 			"Maximum number of concurrent requests. 0 to disable.",
@@ -124,6 +238,10 @@ func main() {
 			"scrape.timeout-margin",
 			"Seconds to subtract from the timeout allowed by the client. Tune to allow for overhead or high loads.",
 		).Default("0.5").Float64()
+		probeConfigFile = app.Flag(
+			"probe.config.file",
+			"YAML file defining the modules of the /probe endpoint, used to scrape remote hosts over WMI. The endpoint is disabled if empty.",
+		).Default("").String()
 	)
 	log.AddFlags(app)
 	app.Version(version.Print("windows_exporter"))
@@ -132,9 +250,18 @@ func main() {
 	// Initialize collectors before loading and parsing CLI arguments
 	collector.RegisterCollectorsFlags(app)
 
+	runCommand := app.Command("run", "Run the exporter.").Default()
+	services := addServiceCommands(app)
+
 	// Load values from configuration file(s). Executable flags must first be parsed, in order
 	// to load the specified file(s).
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+	if command != runCommand.FullCommand() {
+		if err := services.run(command, *configFile); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
 	log.Debug("Logging has Started")
 	if *configFile != "" {
 		resolver, err := config.NewResolver(*configFile)
@@ -190,11 +317,13 @@ func main() {
 	}
 
 	log.Infof("Enabled collectors: %v", strings.Join(keys(collectors), ", "))
+	enabled := newCollectorSet(collectors)
 
 	h := &metricsHandler{
 		timeoutMargin:          *timeoutMargin,
 		includeExporterMetrics: *disableExporterMetrics,
-		collectorFactory: func(timeout time.Duration, requestedCollectors []string) (error, *collector.Prometheus) {
+		collectorFactory: func(timeout time.Duration, requestedCollectors []string) (error, *collector.Prometheus, func()) {
+			collectors, release := enabled.acquire()
 			filteredCollectors := make(map[string]collector.Collector)
 			// scrape all enabled collectors if no collector is requested
 			if len(requestedCollectors) == 0 {
@@ -203,15 +332,27 @@ func main() {
 			for _, name := range requestedCollectors {
 				col, exists := collectors[name]
 				if !exists {
-					return fmt.Errorf("unavailable collector: %s", name), nil
+					release()
+					return fmt.Errorf("unavailable collector: %s", name), nil, nil
 				}
 				filteredCollectors[name] = col
 			}
-			return nil, collector.NewPrometheus(timeout, filteredCollectors)
+			return nil, collector.NewPrometheus(timeout, filteredCollectors), release
 		},
 	}
 
-	http.HandleFunc(*metricsPath, withConcurrencyLimit(*maxRequests, h.ServeHTTP))
+	http.HandleFunc(*metricsPath, withServiceState(withConcurrencyLimit(*maxRequests, h.ServeHTTP)))
+	if *probeConfigFile != "" {
+		probeConfig, err := config.LoadProbeConfig(*probeConfigFile)
+		if err != nil {
+			log.Fatalf("could not load probe config file: %v", err)
+		}
+		ph, err := newProbeHandler(probeConfig, *timeoutMargin)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		http.HandleFunc("/probe", withServiceState(withConcurrencyLimit(*maxRequests, ph.ServeHTTP)))
+	}
 	http.HandleFunc("/health", healthCheck)
 	http.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
 		// we can't use "version" directly as it is a package, and not an object that
@@ -258,18 +399,67 @@ func main() {
 	log.Infoln("Starting windows_exporter", version.Info())
 	log.Infoln("Build context", version.BuildContext())
 
+	initiate.SetStopTimeout(*shutdownGracePeriod + stopMargin)
+
+	// Cancelling the base context cancels the requests in flight, and the
+	// collectors they run.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server := &http.Server{
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
 	go func() {
-		server := &http.Server{}
-		if err := web.ListenAndServe(server, webConfig, log.NewToolkitAdapter()); err != nil {
+		if err := web.ListenAndServe(server, webConfig, log.NewToolkitAdapter()); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("cannot start windows_exporter: %s", err)
 		}
 	}()
 
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
 	for {
-		if <-initiate.StopCh {
-			log.Info("Shutting down windows_exporter")
-			break
+		select {
+		case <-initiate.StopCh:
+		case <-interrupt:
+		case <-initiate.ReloadCh:
+			collectors, err := reloadConfig(app, *configFile, webConfig, enabledCollectors)
+			if err != nil {
+				log.Errorf("Couldn't reload configuration, keeping the current one: %v", err)
+				continue
+			}
+			enabled.replace(collectors, *shutdownGracePeriod)
+			log.Infof("Configuration reloaded, enabled collectors: %v", strings.Join(keys(collectors), ", "))
+			continue
 		}
+		break
+	}
+
+	log.Info("Shutting down windows_exporter")
+	shutdown(server, *shutdownGracePeriod, cancelRequests)
+	enabled.close()
+	log.Info("windows_exporter stopped")
+	if err := log.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to close log file: %v\n", err)
+	}
+	initiate.Done()
+}
+
+// shutdown stops the server, giving the requests in flight the grace period
+// to complete before cancelling them.
+func shutdown(server *http.Server, gracePeriod time.Duration, cancelRequests context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	if err := server.Shutdown(ctx); err == nil {
+		return
+	}
+
+	log.Warnf("Scrapes in flight didn't complete within %s, cancelling them", gracePeriod)
+	cancelRequests()
+	ctx, cancel = context.WithTimeout(context.Background(), stopMargin/2)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Warnf("Closing remaining connections: %v", err)
+		server.Close()
 	}
 }
 
@@ -289,6 +479,18 @@ func keys(m map[string]collector.Collector) []string {
 	return ret
 }
 
+// withServiceState rejects requests while the service is paused.
+func withServiceState(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if initiate.Paused() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("windows_exporter service is paused"))
+			return
+		}
+		next(w, r)
+	}
+}
+
 func withConcurrencyLimit(n int, next http.HandlerFunc) http.HandlerFunc {
 	if n <= 0 {
 		return next
@@ -311,10 +513,14 @@ func withConcurrencyLimit(n int, next http.HandlerFunc) http.HandlerFunc {
 type metricsHandler struct {
 	timeoutMargin          float64
 	includeExporterMetrics bool
-	collectorFactory       func(timeout time.Duration, requestedCollectors []string) (error, *collector.Prometheus)
+	// collectorFactory returns the collectors of a scrape, and the function
+	// to call once the scrape completes.
+	collectorFactory func(timeout time.Duration, requestedCollectors []string) (error, *collector.Prometheus, func())
 }
 
-func (mh *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// scrapeTimeout returns the time allowed for a scrape, as requested by the
+// client minus the configured margin.
+func scrapeTimeout(r *http.Request, timeoutMargin float64) time.Duration {
 	const defaultTimeout = 10.0
 
 	var timeoutSeconds float64
@@ -328,22 +534,28 @@ func (mh *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if timeoutSeconds == 0 {
 		timeoutSeconds = defaultTimeout
 	}
-	timeoutSeconds = timeoutSeconds - mh.timeoutMargin
+	timeoutSeconds = timeoutSeconds - timeoutMargin
+
+	return time.Duration(timeoutSeconds * float64(time.Second))
+}
 
+func (mh *metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	reg := prometheus.NewRegistry()
-	err, wc := mh.collectorFactory(time.Duration(timeoutSeconds*float64(time.Second)), r.URL.Query()["collect[]"])
+	err, wc, release := mh.collectorFactory(scrapeTimeout(r, mh.timeoutMargin), r.URL.Query()["collect[]"])
 	if err != nil {
 		log.Warnln("Couldn't create filtered metrics handler: ", err)
 		w.WriteHeader(http.StatusBadRequest)
 		w.Write([]byte(fmt.Sprintf("Couldn't create filtered metrics handler: %s", err))) //nolint:errcheck
 		return
 	}
-	reg.MustRegister(wc)
+	defer release()
+	reg.MustRegister(wc.WithContext(r.Context()))
 	if !mh.includeExporterMetrics {
 		reg.MustRegister(
 			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 			collectors.NewGoCollector(),
 			version.NewCollector("windows_exporter"),
+			log.MessagesCollector(),
 		)
 	}
 
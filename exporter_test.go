@@ -6,7 +6,12 @@ package main
 import (
 	"sort"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/prometheus-community/windows_exporter/collector"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type expansionTestCase struct {
@@ -50,3 +55,61 @@ func TestExpandEnabled(t *testing.T) {
 		}
 	}
 }
+
+// closingCollector counts how many times it is closed.
+type closingCollector struct {
+	closed int32
+}
+
+func (c *closingCollector) Collect(*collector.ScrapeContext, chan<- prometheus.Metric) error {
+	return nil
+}
+
+func (c *closingCollector) Close() error {
+	atomic.AddInt32(&c.closed, 1)
+	return nil
+}
+
+func TestCollectorSetReplace(t *testing.T) {
+	previous, next := &closingCollector{}, &closingCollector{}
+	s := newCollectorSet(map[string]collector.Collector{"test": previous})
+
+	collectors, release := s.acquire()
+	if collectors["test"] != previous {
+		t.Fatalf("Expected the initial collectors, got %v", collectors)
+	}
+	s.replace(map[string]collector.Collector{"test": next}, time.Minute)
+	if collectors, release := s.acquire(); collectors["test"] != next {
+		t.Errorf("Expected the new collectors, got %v", collectors)
+	} else {
+		release()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&previous.closed); n != 0 {
+		t.Fatal("Expected the previous collectors to be kept open during the scrape")
+	}
+	release()
+	s.closing.Wait()
+	if n := atomic.LoadInt32(&previous.closed); n != 1 {
+		t.Errorf("Expected the previous collectors to be closed once, got %d", n)
+	}
+
+	s.close()
+	if n := atomic.LoadInt32(&next.closed); n != 1 {
+		t.Errorf("Expected the current collectors to be closed once, got %d", n)
+	}
+}
+
+func TestCollectorSetReplaceGracePeriod(t *testing.T) {
+	previous := &closingCollector{}
+	s := newCollectorSet(map[string]collector.Collector{"test": previous})
+
+	_, release := s.acquire()
+	defer release()
+	s.replace(map[string]collector.Collector{}, 50*time.Millisecond)
+	s.closing.Wait()
+	if n := atomic.LoadInt32(&previous.closed); n != 1 {
+		t.Errorf("Expected the previous collectors to be closed after the grace period, got %d", n)
+	}
+}
@@ -0,0 +1,227 @@
+package iphlpapi
+
+import (
+	"errors"
+	"net"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// MIB_TCP_STATE, the states of a TCP connection.
+// https://learn.microsoft.com/en-us/windows/win32/api/tcpmib/ne-tcpmib-mib_tcp_state
+const (
+	MIB_TCP_STATE_CLOSED     = 1
+	MIB_TCP_STATE_LISTEN     = 2
+	MIB_TCP_STATE_SYN_SENT   = 3
+	MIB_TCP_STATE_SYN_RCVD   = 4
+	MIB_TCP_STATE_ESTAB      = 5
+	MIB_TCP_STATE_FIN_WAIT1  = 6
+	MIB_TCP_STATE_FIN_WAIT2  = 7
+	MIB_TCP_STATE_CLOSE_WAIT = 8
+	MIB_TCP_STATE_CLOSING    = 9
+	MIB_TCP_STATE_LAST_ACK   = 10
+	MIB_TCP_STATE_TIME_WAIT  = 11
+	MIB_TCP_STATE_DELETE_TCB = 12
+)
+
+// TCPStates maps the states of a TCP connection to their names.
+var TCPStates = map[uint32]string{
+	MIB_TCP_STATE_CLOSED:     "closed",
+	MIB_TCP_STATE_LISTEN:     "listen",
+	MIB_TCP_STATE_SYN_SENT:   "syn_sent",
+	MIB_TCP_STATE_SYN_RCVD:   "syn_received",
+	MIB_TCP_STATE_ESTAB:      "established",
+	MIB_TCP_STATE_FIN_WAIT1:  "fin_wait1",
+	MIB_TCP_STATE_FIN_WAIT2:  "fin_wait2",
+	MIB_TCP_STATE_CLOSE_WAIT: "close_wait",
+	MIB_TCP_STATE_CLOSING:    "closing",
+	MIB_TCP_STATE_LAST_ACK:   "last_ack",
+	MIB_TCP_STATE_TIME_WAIT:  "time_wait",
+	MIB_TCP_STATE_DELETE_TCB: "delete_tcb",
+}
+
+const (
+	// TCP_TABLE_OWNER_PID_ALL
+	tcpTableOwnerPIDAll = 5
+	// UDP_TABLE_OWNER_PID
+	udpTableOwnerPID = 1
+)
+
+// mibTCPRowOwnerPID is a wrapper of MIB_TCPROW_OWNER_PID.
+// https://learn.microsoft.com/en-us/windows/win32/api/tcpmib/ns-tcpmib-mib_tcprow_owner_pid
+type mibTCPRowOwnerPID struct {
+	State      uint32
+	LocalAddr  [4]byte
+	LocalPort  uint32
+	RemoteAddr [4]byte
+	RemotePort uint32
+	OwningPID  uint32
+}
+
+// mibTCP6RowOwnerPID is a wrapper of MIB_TCP6ROW_OWNER_PID.
+// https://learn.microsoft.com/en-us/windows/win32/api/tcpmib/ns-tcpmib-mib_tcp6row_owner_pid
+type mibTCP6RowOwnerPID struct {
+	LocalAddr     [16]byte
+	LocalScopeID  uint32
+	LocalPort     uint32
+	RemoteAddr    [16]byte
+	RemoteScopeID uint32
+	RemotePort    uint32
+	State         uint32
+	OwningPID     uint32
+}
+
+// mibUDPRowOwnerPID is a wrapper of MIB_UDPROW_OWNER_PID.
+// https://learn.microsoft.com/en-us/windows/win32/api/udpmib/ns-udpmib-mib_udprow_owner_pid
+type mibUDPRowOwnerPID struct {
+	LocalAddr [4]byte
+	LocalPort uint32
+	OwningPID uint32
+}
+
+// mibUDP6RowOwnerPID is a wrapper of MIB_UDP6ROW_OWNER_PID.
+// https://learn.microsoft.com/en-us/windows/win32/api/udpmib/ns-udpmib-mib_udp6row_owner_pid
+type mibUDP6RowOwnerPID struct {
+	LocalAddr    [16]byte
+	LocalScopeID uint32
+	LocalPort    uint32
+	OwningPID    uint32
+}
+
+// TCPConnection is an idiomatic wrapper of the rows of the TCP tables.
+type TCPConnection struct {
+	State      uint32
+	LocalAddr  net.IP
+	LocalPort  uint16
+	RemoteAddr net.IP
+	RemotePort uint16
+	PID        uint32
+}
+
+// UDPEndpoint is an idiomatic wrapper of the rows of the UDP tables.
+type UDPEndpoint struct {
+	LocalAddr net.IP
+	LocalPort uint16
+	PID       uint32
+}
+
+var (
+	iphlpapi                = windows.NewLazySystemDLL("iphlpapi.dll")
+	procGetExtendedTcpTable = iphlpapi.NewProc("GetExtendedTcpTable")
+	procGetExtendedUdpTable = iphlpapi.NewProc("GetExtendedUdpTable")
+
+	errUnsupportedFamily = errors.New("unsupported address family")
+)
+
+// getTable returns the table read by GetExtendedTcpTable or
+// GetExtendedUdpTable, growing the buffer as long as the table grows.
+func getTable(proc *windows.LazyProc, family, class uint32) ([]byte, error) {
+	var size uint32
+	var buf []byte
+	for {
+		var bufPtr uintptr
+		if len(buf) > 0 {
+			bufPtr = uintptr(unsafe.Pointer(&buf[0]))
+		}
+		r1, _, _ := proc.Call(bufPtr, uintptr(unsafe.Pointer(&size)), 0, uintptr(family), uintptr(class), 0)
+		switch windows.Errno(r1) {
+		case windows.ERROR_SUCCESS:
+			return buf, nil
+		case windows.ERROR_INSUFFICIENT_BUFFER:
+			buf = make([]byte, size)
+		default:
+			return nil, windows.Errno(r1)
+		}
+	}
+}
+
+// rows returns the rows of a table, which follow its number of entries, a
+// DWORD.
+func rows[T any](table []byte) []T {
+	if len(table) < 4 {
+		return nil
+	}
+	n := *(*uint32)(unsafe.Pointer(&table[0]))
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(&table[4])), n)
+}
+
+// port converts a port from the network byte order.
+func port(p uint32) uint16 {
+	return uint16(p>>8&0xff | p&0xff<<8)
+}
+
+// GetTCPConnections returns the TCP connections and listeners of the family,
+// windows.AF_INET or windows.AF_INET6, with the processes owning them.
+// https://learn.microsoft.com/en-us/windows/win32/api/iphlpapi/nf-iphlpapi-getextendedtcptable
+func GetTCPConnections(family uint32) ([]TCPConnection, error) {
+	table, err := getTable(procGetExtendedTcpTable, family, tcpTableOwnerPIDAll)
+	if err != nil {
+		return nil, err
+	}
+
+	var connections []TCPConnection
+	switch family {
+	case windows.AF_INET:
+		for _, row := range rows[mibTCPRowOwnerPID](table) {
+			connections = append(connections, TCPConnection{
+				State:      row.State,
+				LocalAddr:  net.IP(append([]byte(nil), row.LocalAddr[:]...)),
+				LocalPort:  port(row.LocalPort),
+				RemoteAddr: net.IP(append([]byte(nil), row.RemoteAddr[:]...)),
+				RemotePort: port(row.RemotePort),
+				PID:        row.OwningPID,
+			})
+		}
+	case windows.AF_INET6:
+		for _, row := range rows[mibTCP6RowOwnerPID](table) {
+			connections = append(connections, TCPConnection{
+				State:      row.State,
+				LocalAddr:  net.IP(append([]byte(nil), row.LocalAddr[:]...)),
+				LocalPort:  port(row.LocalPort),
+				RemoteAddr: net.IP(append([]byte(nil), row.RemoteAddr[:]...)),
+				RemotePort: port(row.RemotePort),
+				PID:        row.OwningPID,
+			})
+		}
+	default:
+		return nil, errUnsupportedFamily
+	}
+	return connections, nil
+}
+
+// GetUDPEndpoints returns the UDP endpoints of the family, windows.AF_INET or
+// windows.AF_INET6, with the processes owning them.
+// https://learn.microsoft.com/en-us/windows/win32/api/iphlpapi/nf-iphlpapi-getextendedudptable
+func GetUDPEndpoints(family uint32) ([]UDPEndpoint, error) {
+	table, err := getTable(procGetExtendedUdpTable, family, udpTableOwnerPID)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []UDPEndpoint
+	switch family {
+	case windows.AF_INET:
+		for _, row := range rows[mibUDPRowOwnerPID](table) {
+			endpoints = append(endpoints, UDPEndpoint{
+				LocalAddr: net.IP(append([]byte(nil), row.LocalAddr[:]...)),
+				LocalPort: port(row.LocalPort),
+				PID:       row.OwningPID,
+			})
+		}
+	case windows.AF_INET6:
+		for _, row := range rows[mibUDP6RowOwnerPID](table) {
+			endpoints = append(endpoints, UDPEndpoint{
+				LocalAddr: net.IP(append([]byte(nil), row.LocalAddr[:]...)),
+				LocalPort: port(row.LocalPort),
+				PID:       row.OwningPID,
+			})
+		}
+	default:
+		return nil, errUnsupportedFamily
+	}
+	return endpoints, nil
+}
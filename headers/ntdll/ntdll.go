@@ -0,0 +1,138 @@
+package ntdll
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// OBJECT_INFORMATION_CLASS
+	objectTypesInformation = 3
+
+	// The buffers of the information classes are grown up to maxBufferSize.
+	initialBufferSize = 1 << 16
+	maxBufferSize     = 1 << 30
+)
+
+// systemHandleTableEntryInfoEx is a wrapper of SYSTEM_HANDLE_TABLE_ENTRY_INFO_EX.
+type systemHandleTableEntryInfoEx struct {
+	Object                uintptr
+	UniqueProcessID       uintptr
+	HandleValue           uintptr
+	GrantedAccess         uint32
+	CreatorBackTraceIndex uint16
+	ObjectTypeIndex       uint16
+	HandleAttributes      uint32
+	Reserved              uint32
+}
+
+// systemHandleInformationEx is a wrapper of SYSTEM_HANDLE_INFORMATION_EX,
+// followed by NumberOfHandles entries.
+type systemHandleInformationEx struct {
+	NumberOfHandles uintptr
+	Reserved        uintptr
+}
+
+// objectTypeInformation is a wrapper of OBJECT_TYPE_INFORMATION, followed by
+// the buffer of the type name.
+type objectTypeInformation struct {
+	TypeName                  windows.NTUnicodeString
+	Counters                  [12]uint32
+	InvalidAttributes         uint32
+	GenericMapping            [4]uint32
+	ValidAccessMask           uint32
+	SecurityRequired          bool
+	MaintainHandleCount       bool
+	TypeIndex                 uint8
+	ReservedByte              byte
+	PoolType                  uint32
+	DefaultPagedPoolCharge    uint32
+	DefaultNonPagedPoolCharge uint32
+}
+
+// Handle is an idiomatic wrapper of SYSTEM_HANDLE_TABLE_ENTRY_INFO_EX.
+type Handle struct {
+	ProcessID uint32
+	TypeIndex uint16
+}
+
+var (
+	ntdll             = windows.NewLazySystemDLL("ntdll.dll")
+	procNtQueryObject = ntdll.NewProc("NtQueryObject")
+)
+
+// query calls an NtQuery function, growing the buffer as long as it is too
+// small.
+func query(call func(buf []byte, retLen *uint32) error) ([]byte, error) {
+	buf := make([]byte, initialBufferSize)
+	for {
+		var retLen uint32
+		err := call(buf, &retLen)
+		if err == nil {
+			return buf, nil
+		}
+		if err != windows.STATUS_INFO_LENGTH_MISMATCH || len(buf) >= maxBufferSize {
+			return nil, err
+		}
+		size := 2 * len(buf)
+		if int(retLen) > size {
+			size = int(retLen)
+		}
+		buf = make([]byte, size)
+	}
+}
+
+// Handles returns the handles open by all the processes, using
+// NtQuerySystemInformation(SystemExtendedHandleInformation).
+func Handles() ([]Handle, error) {
+	buf, err := query(func(buf []byte, retLen *uint32) error {
+		return windows.NtQuerySystemInformation(windows.SystemExtendedHandleInformation, unsafe.Pointer(&buf[0]), uint32(len(buf)), retLen)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info := (*systemHandleInformationEx)(unsafe.Pointer(&buf[0]))
+	if info.NumberOfHandles == 0 {
+		return nil, nil
+	}
+	entries := unsafe.Slice((*systemHandleTableEntryInfoEx)(unsafe.Pointer(&buf[unsafe.Sizeof(*info)])), info.NumberOfHandles)
+	handles := make([]Handle, len(entries))
+	for i, entry := range entries {
+		handles[i] = Handle{ProcessID: uint32(entry.UniqueProcessID), TypeIndex: entry.ObjectTypeIndex}
+	}
+	return handles, nil
+}
+
+// ObjectTypeNames returns the names of the types of objects by index, using
+// NtQueryObject(ObjectTypesInformation). It needs Windows 8.1 or later, for
+// the index of the types.
+func ObjectTypeNames() (map[uint16]string, error) {
+	buf, err := query(func(buf []byte, retLen *uint32) error {
+		r1, _, _ := procNtQueryObject.Call(0, objectTypesInformation, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)), uintptr(unsafe.Pointer(retLen)))
+		if r1 != 0 {
+			return windows.NTStatus(r1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	align := func(n uintptr) uintptr {
+		const a = unsafe.Sizeof(uintptr(0))
+		return (n + a - 1) &^ (a - 1)
+	}
+	// OBJECT_TYPES_INFORMATION holds the number of types, followed by the
+	// types, each followed by its name.
+	n := *(*uint32)(unsafe.Pointer(&buf[0]))
+	names := make(map[uint16]string, n)
+	offset := align(unsafe.Sizeof(uint32(0)))
+	for i := uint32(0); i < n && offset+unsafe.Sizeof(objectTypeInformation{}) <= uintptr(len(buf)); i++ {
+		info := (*objectTypeInformation)(unsafe.Pointer(&buf[offset]))
+		names[uint16(info.TypeIndex)] = info.TypeName.String()
+		offset += align(unsafe.Sizeof(*info)) + align(uintptr(info.TypeName.MaximumLength))
+	}
+	return names, nil
+}
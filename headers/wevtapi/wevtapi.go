@@ -0,0 +1,124 @@
+package wevtapi
+
+import (
+	"errors"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// EvtQueryFlags ...
+// https://docs.microsoft.com/en-us/windows/win32/api/winevt/ne-winevt-evt_query_flags
+const (
+	EvtQueryChannelPath      = 0x1
+	EvtQueryFilePath         = 0x2
+	EvtQueryForwardDirection = 0x100
+	EvtQueryReverseDirection = 0x200
+)
+
+const (
+	evtRenderEventXml = 1
+	// Number of events read by a call to EvtNext.
+	evtNextBatch = 64
+)
+
+var (
+	wevtapi       = windows.NewLazySystemDLL("wevtapi.dll")
+	procEvtQuery  = wevtapi.NewProc("EvtQuery")
+	procEvtNext   = wevtapi.NewProc("EvtNext")
+	procEvtRender = wevtapi.NewProc("EvtRender")
+	procEvtClose  = wevtapi.NewProc("EvtClose")
+)
+
+// evtQuery runs a query on a channel or a log file.
+// https://docs.microsoft.com/en-us/windows/win32/api/winevt/nf-winevt-evtquery
+func evtQuery(path, query string, flags uint32) (windows.Handle, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	queryPtr, err := windows.UTF16PtrFromString(query)
+	if err != nil {
+		return 0, err
+	}
+	r1, _, err := procEvtQuery.Call(0, uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(queryPtr)), uintptr(flags))
+	if r1 == 0 {
+		return 0, err
+	}
+	return windows.Handle(r1), nil
+}
+
+// evtNext returns the next events of a query, none once all were read.
+// https://docs.microsoft.com/en-us/windows/win32/api/winevt/nf-winevt-evtnext
+func evtNext(results windows.Handle, events []windows.Handle) (int, error) {
+	var returned uint32
+	r1, _, err := procEvtNext.Call(uintptr(results), uintptr(len(events)), uintptr(unsafe.Pointer(&events[0])), uintptr(windows.INFINITE), 0, uintptr(unsafe.Pointer(&returned)))
+	if r1 == 0 {
+		if errors.Is(err, windows.ERROR_NO_MORE_ITEMS) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return int(returned), nil
+}
+
+// evtRenderXML renders an event as XML.
+// https://docs.microsoft.com/en-us/windows/win32/api/winevt/nf-winevt-evtrender
+func evtRenderXML(event windows.Handle, buf []uint16) (string, []uint16, error) {
+	for {
+		var used, count uint32
+		var bufPtr uintptr
+		if len(buf) > 0 {
+			bufPtr = uintptr(unsafe.Pointer(&buf[0]))
+		}
+		r1, _, err := procEvtRender.Call(0, uintptr(event), evtRenderEventXml, uintptr(len(buf)*2), bufPtr, uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&count)))
+		if r1 != 0 {
+			return windows.UTF16ToString(buf[:used/2]), buf, nil
+		}
+		if !errors.Is(err, windows.ERROR_INSUFFICIENT_BUFFER) {
+			return "", buf, err
+		}
+		buf = make([]uint16, used/2)
+	}
+}
+
+// evtClose closes a handle returned by the other functions.
+// https://docs.microsoft.com/en-us/windows/win32/api/winevt/nf-winevt-evtclose
+func evtClose(h windows.Handle) {
+	procEvtClose.Call(uintptr(h)) //nolint:errcheck
+}
+
+// QueryXML is an idiomatic wrapper of EvtQuery, returning the events of a
+// channel or log file matching the XPath query, rendered as XML.
+func QueryXML(path, query string, flags uint32) ([]string, error) {
+	results, err := evtQuery(path, query, flags)
+	if err != nil {
+		return nil, err
+	}
+	defer evtClose(results)
+
+	var xmls []string
+	var buf []uint16
+	events := make([]windows.Handle, evtNextBatch)
+	for {
+		n, err := evtNext(results, events)
+		if err != nil {
+			return xmls, err
+		}
+		if n == 0 {
+			return xmls, nil
+		}
+		for i, event := range events[:n] {
+			var xml string
+			xml, buf, err = evtRenderXML(event, buf)
+			evtClose(event)
+			if err != nil {
+				for _, event := range events[i+1 : n] {
+					evtClose(event)
+				}
+				return xmls, err
+			}
+			xmls = append(xmls, xml)
+		}
+	}
+}
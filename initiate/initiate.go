@@ -3,6 +3,9 @@ package initiate
 
 import (
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/prometheus-community/windows_exporter/log"
 	"golang.org/x/sys/windows/svc"
@@ -10,51 +13,123 @@ import (
 
 const (
 	serviceName = "windows_exporter"
+
+	cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown | svc.AcceptPauseAndContinue | svc.AcceptParamChange
+	// defaultStopTimeout is the time the exporter is given to drain the
+	// scrapes in flight once the service is asked to stop.
+	defaultStopTimeout = 30 * time.Second
 )
 
 type windowsExporterService struct {
-	stopCh chan<- bool
+	stopCh   chan<- bool
+	reloadCh chan<- struct{}
+	// doneCh is closed once the exporter stopped.
+	doneCh      <-chan struct{}
+	paused      *atomic.Bool
+	stopTimeout func() time.Duration
 }
 
 func (s *windowsExporterService) Execute(args []string, r <-chan svc.ChangeRequest, changes chan<- svc.Status) (ssec bool, errno uint32) {
-	const cmdsAccepted = svc.AcceptStop | svc.AcceptShutdown
 	changes <- svc.Status{State: svc.StartPending}
 	changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
 loop:
-	for {
-		select {
-		case c := <-r:
-			switch c.Cmd {
-			case svc.Interrogate:
-				changes <- c.CurrentStatus
-			case svc.Stop, svc.Shutdown:
-				log.Debug("Service Stop Received")
-				s.stopCh <- true
-				break loop
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			changes <- c.CurrentStatus
+		case svc.Pause:
+			log.Info("Service Pause Received, scrapes are rejected until continued")
+			s.paused.Store(true)
+			changes <- svc.Status{State: svc.Paused, Accepts: cmdsAccepted}
+		case svc.Continue:
+			log.Info("Service Continue Received")
+			s.paused.Store(false)
+			changes <- svc.Status{State: svc.Running, Accepts: cmdsAccepted}
+		case svc.ParamChange:
+			log.Info("Service Parameter Change Received, reloading configuration")
+			// A reload already pending covers this one.
+			select {
+			case s.reloadCh <- struct{}{}:
 			default:
-				log.Error(fmt.Sprintf("unexpected control request #%d", c))
 			}
+			changes <- c.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			log.Debug("Service Stop Received")
+			break loop
+		default:
+			log.Error(fmt.Sprintf("unexpected control request #%d", c))
 		}
 	}
-	changes <- svc.Status{State: svc.StopPending}
+
+	timeout := s.stopTimeout()
+	changes <- svc.Status{State: svc.StopPending, WaitHint: uint32(timeout / time.Millisecond)}
+	s.stopCh <- true
+	select {
+	case <-s.doneCh:
+	case <-time.After(timeout):
+		log.Warnf("Exporter didn't stop within %s, stopping the service anyway", timeout)
+	}
 	return
 }
 
-var StopCh = make(chan bool)
+var (
+	// StopCh receives a value when the service is asked to stop. The exporter
+	// calls Done once it stopped.
+	StopCh = make(chan bool)
+	// ReloadCh receives a value when the parameters of the service change.
+	ReloadCh = make(chan struct{}, 1)
+
+	paused      atomic.Bool
+	stopTimeout atomic.Int64
+	doneCh      = make(chan struct{})
+	doneOne     sync.Once
+	// runDone is closed once the service stopped, or immediately if not
+	// running as a service.
+	runDone = make(chan struct{})
+)
+
+// Paused returns whether the service is paused.
+func Paused() bool {
+	return paused.Load()
+}
+
+// SetStopTimeout sets the time the exporter is given to stop once the service
+// is asked to stop, after which the service is reported as stopped anyway.
+func SetStopTimeout(d time.Duration) {
+	stopTimeout.Store(int64(d))
+}
+
+// Done reports that the exporter stopped, waiting for the service to be
+// reported as stopped to the service control manager.
+func Done() {
+	doneOne.Do(func() { close(doneCh) })
+	<-runDone
+}
 
 func init() {
+	stopTimeout.Store(int64(defaultStopTimeout))
+
 	log.Debug("Checking if We are a service")
 	isService, err := svc.IsWindowsService()
 	if err != nil {
 		log.Fatal(err)
 	}
 	log.Debug("Attempting to start exporter service")
-	if isService {
-		go func() {
-			err = svc.Run(serviceName, &windowsExporterService{stopCh: StopCh})
-			if err != nil {
-				log.Errorf("Failed to start service: %v", err)
-			}
-		}()
+	if !isService {
+		close(runDone)
+		return
 	}
+	go func() {
+		defer close(runDone)
+		err = svc.Run(serviceName, &windowsExporterService{
+			stopCh:      StopCh,
+			reloadCh:    ReloadCh,
+			doneCh:      doneCh,
+			paused:      &paused,
+			stopTimeout: func() time.Duration { return time.Duration(stopTimeout.Load()) },
+		})
+		if err != nil {
+			log.Errorf("Failed to start service: %v", err)
+		}
+	}()
 }
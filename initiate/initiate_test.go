@@ -0,0 +1,138 @@
+package initiate
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+type fakeService struct {
+	svc      *windowsExporterService
+	requests chan svc.ChangeRequest
+	changes  chan svc.Status
+	stopCh   chan bool
+	reloadCh chan struct{}
+	doneCh   chan struct{}
+	exited   chan struct{}
+}
+
+func startFakeService(t *testing.T, stopTimeout time.Duration) *fakeService {
+	f := &fakeService{
+		requests: make(chan svc.ChangeRequest),
+		changes:  make(chan svc.Status, 10),
+		stopCh:   make(chan bool, 1),
+		reloadCh: make(chan struct{}, 1),
+		doneCh:   make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	f.svc = &windowsExporterService{
+		stopCh:      f.stopCh,
+		reloadCh:    f.reloadCh,
+		doneCh:      f.doneCh,
+		paused:      &atomic.Bool{},
+		stopTimeout: func() time.Duration { return stopTimeout },
+	}
+	go func() {
+		defer close(f.exited)
+		f.svc.Execute(nil, f.requests, f.changes)
+	}()
+	f.expectState(t, svc.StartPending)
+	f.expectState(t, svc.Running)
+	return f
+}
+
+func (f *fakeService) expectState(t *testing.T, state svc.State) svc.Status {
+	t.Helper()
+	select {
+	case s := <-f.changes:
+		if s.State != state {
+			t.Fatalf("expected state %d, got %d", state, s.State)
+		}
+		return s
+	case <-time.After(time.Second):
+		t.Fatalf("expected state %d, got nothing", state)
+	}
+	return svc.Status{}
+}
+
+func TestServicePauseContinue(t *testing.T) {
+	f := startFakeService(t, time.Second)
+
+	f.requests <- svc.ChangeRequest{Cmd: svc.Pause}
+	if s := f.expectState(t, svc.Paused); s.Accepts != cmdsAccepted {
+		t.Errorf("expected paused service to accept %d, got %d", cmdsAccepted, s.Accepts)
+	}
+	if !f.svc.paused.Load() {
+		t.Error("expected the service to be paused")
+	}
+
+	f.requests <- svc.ChangeRequest{Cmd: svc.Interrogate, CurrentStatus: svc.Status{State: svc.Paused}}
+	f.expectState(t, svc.Paused)
+
+	f.requests <- svc.ChangeRequest{Cmd: svc.Continue}
+	f.expectState(t, svc.Running)
+	if f.svc.paused.Load() {
+		t.Error("expected the service to be running")
+	}
+}
+
+func TestServiceParamChange(t *testing.T) {
+	f := startFakeService(t, time.Second)
+
+	// Changes while a reload is pending are coalesced.
+	for i := 0; i < 2; i++ {
+		f.requests <- svc.ChangeRequest{Cmd: svc.ParamChange, CurrentStatus: svc.Status{State: svc.Running}}
+		f.expectState(t, svc.Running)
+	}
+	select {
+	case <-f.reloadCh:
+	default:
+		t.Fatal("expected a reload")
+	}
+	select {
+	case <-f.reloadCh:
+		t.Fatal("expected a single reload")
+	default:
+	}
+}
+
+func TestServiceStop(t *testing.T) {
+	f := startFakeService(t, time.Second)
+
+	f.requests <- svc.ChangeRequest{Cmd: svc.Stop}
+	if s := f.expectState(t, svc.StopPending); s.WaitHint != 1000 {
+		t.Errorf("expected a wait hint of 1000ms, got %d", s.WaitHint)
+	}
+	select {
+	case <-f.stopCh:
+	case <-time.After(time.Second):
+		t.Fatal("expected the exporter to be stopped")
+	}
+
+	// The service waits for the exporter to drain the scrapes in flight.
+	select {
+	case <-f.exited:
+		t.Fatal("expected the service to wait for the exporter")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(f.doneCh)
+	select {
+	case <-f.exited:
+	case <-time.After(time.Second):
+		t.Fatal("expected the service to stop")
+	}
+}
+
+func TestServiceStopTimeout(t *testing.T) {
+	f := startFakeService(t, 10*time.Millisecond)
+
+	f.requests <- svc.ChangeRequest{Cmd: svc.Shutdown}
+	f.expectState(t, svc.StopPending)
+	select {
+	case <-f.exited:
+	case <-time.After(time.Second):
+		t.Fatal("expected the service to stop once the timeout expired")
+	}
+}
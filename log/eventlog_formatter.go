@@ -65,23 +65,24 @@ func (s *eventlogger) Format(e *logrus.Entry) ([]byte, error) {
 		return data, err
 	}
 
+	msg := messageWithFields(e)
 	switch e.Level {
 	case logrus.PanicLevel:
 		fallthrough
 	case logrus.FatalLevel:
 		fallthrough
 	case logrus.ErrorLevel:
-		err = s.log.Error(102, e.Message)
+		err = s.log.Error(102, msg)
 	case logrus.WarnLevel:
-		err = s.log.Warning(101, e.Message)
+		err = s.log.Warning(101, msg)
 	case logrus.InfoLevel:
-		err = s.log.Info(100, e.Message)
+		err = s.log.Info(100, msg)
 	case logrus.DebugLevel:
 		if s.debugAsInfo {
-			err = s.log.Info(100, e.Message)
+			err = s.log.Info(100, msg)
 		}
 	default:
-		err = s.log.Info(100, e.Message)
+		err = s.log.Info(100, msg)
 	}
 
 	if err != nil {
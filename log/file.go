@@ -0,0 +1,261 @@
+package log
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// backupTimeFormat is the timestamp in the names of rotated files. It
+	// holds no colon, which is invalid in Windows file names.
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+	compressSuffix   = ".gz"
+	megabyte         = 1024 * 1024
+
+	defaultFileMaxSize = 100 * megabyte
+)
+
+// rotatingFile is a log file which is rotated once it exceeds its maximum
+// size. Rotated files are renamed after the time of their rotation, e.g.
+// windows_exporter-2006-01-02T15-04-05.000.log, optionally compressed, and
+// removed once there are too many or they are too old.
+type rotatingFile struct {
+	path string
+	// Maximum size of the file in bytes before it is rotated, 0 to disable.
+	maxSize int64
+	// Maximum number of rotated files to keep, 0 to keep all.
+	maxBackups int
+	// Maximum age of the rotated files to keep, 0 to keep all.
+	maxAge   time.Duration
+	compress bool
+	now      func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+
+	// millMu serializes the cleanup of rotated files.
+	millMu sync.Mutex
+}
+
+// newRotatingFile returns the rotating file described by the query of a
+// logger:file URL.
+func newRotatingFile(q url.Values) (*rotatingFile, error) {
+	f := &rotatingFile{
+		path:    q.Get("path"),
+		maxSize: defaultFileMaxSize,
+		now:     time.Now,
+	}
+	if f.path == "" {
+		return nil, fmt.Errorf("missing path parameter")
+	}
+	if v := q.Get("max_size"); v != "" {
+		mb, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || mb < 0 {
+			return nil, fmt.Errorf("invalid max_size %q, must be a number of megabytes", v)
+		}
+		f.maxSize = mb * megabyte
+	}
+	if v := q.Get("max_backups"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid max_backups %q", v)
+		}
+		f.maxBackups = n
+	}
+	if v := q.Get("max_age"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid max_age %q, must be a duration such as 168h", v)
+		}
+		f.maxAge = d
+	}
+	if v := q.Get("compress"); v != "" {
+		compress, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compress %q", v)
+		}
+		f.compress = compress
+	}
+	return f, nil
+}
+
+// Write implements io.Writer, rotating the file beforehand if the write
+// would exceed its maximum size.
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the file.
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+func (f *rotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file and opens a new one.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	if err := os.Rename(f.path, f.backupName(f.now())); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	go f.mill()
+	return nil
+}
+
+func (f *rotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(f.path, ext)
+	return fmt.Sprintf("%s-%s%s", prefix, t.UTC().Format(backupTimeFormat), ext)
+}
+
+type logBackup struct {
+	path string
+	time time.Time
+}
+
+// backups returns the rotated files, newest first.
+func (f *rotatingFile) backups() ([]logBackup, error) {
+	dir := filepath.Dir(f.path)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+	var backups []logBackup
+	for _, e := range entries {
+		name := strings.TrimSuffix(e.Name(), compressSuffix)
+		if e.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ext) {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{path: filepath.Join(dir, e.Name()), time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+	return backups, nil
+}
+
+// mill removes the rotated files exceeding the maximum count or age, and
+// compresses the others.
+func (f *rotatingFile) mill() {
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+
+	backups, err := f.backups()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to list rotated log files: %v\n", err)
+		return
+	}
+
+	for i, b := range backups {
+		if (f.maxBackups > 0 && i >= f.maxBackups) || (f.maxAge > 0 && f.now().Sub(b.time) > f.maxAge) {
+			if err := os.Remove(b.path); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to remove rotated log file: %v\n", err)
+			}
+			continue
+		}
+		if f.compress && !strings.HasSuffix(b.path, compressSuffix) {
+			if err := compressFile(b.path); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to compress rotated log file: %v\n", err)
+			}
+		}
+	}
+}
+
+// compressFile gzips the file, replacing it.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(path + compressSuffix)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
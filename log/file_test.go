@@ -0,0 +1,144 @@
+package log
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listDir(t *testing.T, dir string) []string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestNewRotatingFile(t *testing.T) {
+	q, _ := url.ParseQuery("path=exporter.log&max_size=10&max_backups=3&max_age=168h&compress=true")
+	f, err := newRotatingFile(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.path != "exporter.log" || f.maxSize != 10*megabyte || f.maxBackups != 3 || f.maxAge != 168*time.Hour || !f.compress {
+		t.Errorf("Unexpected rotating file %+v", f)
+	}
+
+	for _, query := range []string{"", "path=a.log&max_size=ten", "path=a.log&max_backups=-1", "path=a.log&max_age=7", "path=a.log&compress=maybe"} {
+		q, _ := url.ParseQuery(query)
+		if _, err := newRotatingFile(q); err == nil {
+			t.Errorf("Expected an error for %q", query)
+		}
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := &rotatingFile{
+		path:       filepath.Join(dir, "exporter.log"),
+		maxSize:    10,
+		maxBackups: 2,
+		compress:   true,
+		now:        func() time.Time { return now },
+	}
+	defer f.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := f.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Second)
+	}
+	f.mill()
+
+	expected := []string{
+		"exporter-2023-05-01T12-00-02.000.log.gz",
+		"exporter-2023-05-01T12-00-03.000.log.gz",
+		"exporter.log",
+	}
+	if names := listDir(t, dir); !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected files %v, got %v", expected, names)
+	}
+
+	gzFile, err := os.Open(filepath.Join(dir, expected[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gzFile.Close()
+	r, err := gzip.NewReader(gzFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := ioutil.ReadAll(r); err != nil || string(b) != "0123456789" {
+		t.Errorf("Unexpected content of the compressed file %q: %v", b, err)
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := &rotatingFile{
+		path:   filepath.Join(dir, "exporter.log"),
+		maxAge: 24 * time.Hour,
+		now:    func() time.Time { return now },
+	}
+
+	for _, name := range []string{
+		"exporter-2023-04-29T12-00-00.000.log.gz",
+		"exporter-2023-04-30T18-00-00.000.log",
+		"exporter-invalid.log",
+		"other-2023-04-29T12-00-00.000.log",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.mill()
+
+	expected := []string{
+		"exporter-2023-04-30T18-00-00.000.log",
+		"exporter-invalid.log",
+		"other-2023-04-29T12-00-00.000.log",
+	}
+	if names := listDir(t, dir); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected files %v, got %v", expected, names)
+	}
+}
+
+func TestClose(t *testing.T) {
+	out := origLogger.Out
+	defer origLogger.SetOutput(out)
+
+	path := filepath.Join(t.TempDir(), "windows_exporter.log")
+	if err := baseLogger.SetFormat("logger:file?path=" + path); err != nil {
+		t.Fatal(err)
+	}
+	Error("Before closing")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	if origLogger.Out != os.Stderr {
+		t.Error("Expected messages to go to stderr once closed")
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "Before closing") {
+		t.Errorf("Expected the message in the log file, got %q", b)
+	}
+}
@@ -1,6 +1,8 @@
 package log
 
 import (
+	"fmt"
+
 	"github.com/go-kit/log/level"
 )
 
@@ -15,7 +17,8 @@ type Adapter struct{}
 func (*Adapter) Log(keyvals ...interface{}) error {
 	var lvl level.Value
 	var msg string
-	for i := 0; i < len(keyvals); i += 2 {
+	fields := Fields{}
+	for i := 0; i+1 < len(keyvals); i += 2 {
 		switch keyvals[i] {
 		case "level":
 			tlvl, ok := keyvals[i+1].(level.Value)
@@ -25,21 +28,24 @@ func (*Adapter) Log(keyvals ...interface{}) error {
 				lvl = tlvl
 			}
 		case "msg":
-			msg = keyvals[i+1].(string)
+			msg = fmt.Sprint(keyvals[i+1])
+		default:
+			fields[fmt.Sprint(keyvals[i])] = keyvals[i+1]
 		}
 	}
 
+	l := WithFields(fields)
 	switch lvl {
 	case level.ErrorValue():
-		Errorln(msg)
+		l.Errorln(msg)
 	case level.WarnValue():
-		Warnln(msg)
+		l.Warnln(msg)
 	case level.InfoValue():
-		Infoln(msg)
+		l.Infoln(msg)
 	case level.DebugValue():
-		Debugln(msg)
+		l.Debugln(msg)
 	default:
-		Warnf("Unmatched log level: '%v' for message %q", lvl, msg)
+		l.Warnf("Unmatched log level: '%v' for message %q", lvl, msg)
 	}
 
 	return nil
@@ -23,8 +23,10 @@ import (
 	"net/url"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/sirupsen/logrus"
@@ -36,8 +38,37 @@ var setSyslogFormatter func(logger, string, string) error
 // setEventlogFormatter is nil if the target OS does not support Eventlog (i.e., is not Windows).
 var setEventlogFormatter func(logger, string, bool) error
 
-func setJSONFormatter() {
-	origLogger.Formatter = &logrus.JSONFormatter{}
+// Names of the fields shared by the log messages of the exporter.
+const (
+	CollectorField = "collector"
+	DurationField  = "duration"
+	ClassField     = "class"
+	InstanceField  = "instance"
+	ErrorField     = "error"
+)
+
+// Fields are key/value pairs added to log messages.
+type Fields map[string]interface{}
+
+// setFormatter sets the formatter of the output format named in the
+// format query parameter of the log.format flag.
+func setFormatter(l logger, format string) error {
+	switch format {
+	case "", "text":
+	case "logfmt":
+		// logrus' text formatter outputs logfmt, unless it colors its
+		// output on terminals.
+		l.entry.Logger.Formatter = &logrus.TextFormatter{
+			DisableColors:    true,
+			FullTimestamp:    true,
+			QuoteEmptyFields: true,
+		}
+	case "json":
+		l.entry.Logger.Formatter = &logrus.JSONFormatter{}
+	default:
+		return fmt.Errorf("unsupported log format %q", format)
+	}
+	return nil
 }
 
 type loggerSettings struct {
@@ -62,12 +93,25 @@ func AddFlags(a *kingpin.Application) {
 		Default(origLogger.Level.String()).
 		StringVar(&s.level)
 	defaultFormat := url.URL{Scheme: "logger", Opaque: "stderr"}
-	a.Flag("log.format", `Set the log target and format. Example: "logger:syslog?appname=bob&local=7" or "logger:stdout?json=true"`).
+	a.Flag("log.format", `Set the log target and format. Example: "logger:syslog?appname=bob&local=7", "logger:stdout?format=logfmt" or "logger:file?path=C:\logs\windows_exporter.log&max_size=10&max_backups=5". Valid formats: [text, logfmt, json]`).
 		Default(defaultFormat.String()).
 		StringVar(&s.format)
 	a.Action(s.apply)
 }
 
+// AddCollectorFlags adds a log.level.collector.<name> flag for each of the
+// collectors, overriding the log level of their messages.
+func AddCollectorFlags(a *kingpin.Application, collectors []string) {
+	for _, name := range collectors {
+		name, level := name, new(string)
+		a.Flag("log.level.collector."+name, "Only log messages of the "+name+" collector with the given severity or above. Defaults to --log.level.").
+			Action(func(*kingpin.ParseContext) error {
+				return SetCollectorLevel(name, *level)
+			}).
+			StringVar(level)
+	}
+}
+
 // Logger is the interface for loggers used in the Prometheus components.
 type Logger interface {
 	Debug(...interface{})
@@ -91,92 +135,186 @@ type Logger interface {
 	Fatalf(string, ...interface{})
 
 	With(key string, value interface{}) Logger
+	WithFields(fields Fields) Logger
+	WithError(err error) Logger
 
 	SetFormat(string) error
 	SetLevel(string) error
+	SetCollectorLevel(collector, level string) error
+}
+
+// levelFilter holds the log level of the messages of each collector. The
+// level of the underlying logrus logger is the most verbose of them, the
+// messages of the other collectors being filtered beforehand.
+type levelFilter struct {
+	mu         sync.RWMutex
+	level      logrus.Level
+	collectors map[string]logrus.Level
+}
+
+func newLevelFilter(level logrus.Level) *levelFilter {
+	return &levelFilter{level: level, collectors: map[string]logrus.Level{}}
+}
+
+// enabled returns whether messages of the level are logged for the collector,
+// which is empty for messages not logged by a collector. Fatal and panic
+// messages are always logged, so that they still exit or panic.
+func (f *levelFilter) enabled(collector string, level logrus.Level) bool {
+	if level <= logrus.FatalLevel {
+		return true
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	max, ok := f.collectors[collector]
+	if !ok {
+		max = f.level
+	}
+	return level <= max
+}
+
+// maxLevel returns the most verbose of the levels, at least fatal.
+func (f *levelFilter) maxLevel() logrus.Level {
+	max := f.level
+	if max < logrus.FatalLevel {
+		max = logrus.FatalLevel
+	}
+	for _, level := range f.collectors {
+		if level > max {
+			max = level
+		}
+	}
+	return max
 }
 
 type logger struct {
-	entry *logrus.Entry
+	entry  *logrus.Entry
+	levels *levelFilter
 }
 
 func (l logger) With(key string, value interface{}) Logger {
-	return logger{l.entry.WithField(key, value)}
+	return logger{l.entry.WithField(key, value), l.levels}
+}
+
+func (l logger) WithFields(fields Fields) Logger {
+	return logger{l.entry.WithFields(logrus.Fields(fields)), l.levels}
+}
+
+func (l logger) WithError(err error) Logger {
+	return logger{l.entry.WithError(err), l.levels}
+}
+
+// enabled returns whether messages of the level are logged, according to the
+// level of the collector named in the collector field.
+func (l logger) enabled(level logrus.Level) bool {
+	collector, _ := l.entry.Data[CollectorField].(string)
+	return l.levels.enabled(collector, level)
 }
 
 // Debug logs a message at level Debug on the standard logger.
 func (l logger) Debug(args ...interface{}) {
-	l.sourced().Debug(args...)
+	if l.enabled(logrus.DebugLevel) {
+		l.sourced().Debug(args...)
+	}
 }
 
 // Debug logs a message at level Debug on the standard logger.
 func (l logger) Debugln(args ...interface{}) {
-	l.sourced().Debugln(args...)
+	if l.enabled(logrus.DebugLevel) {
+		l.sourced().Debugln(args...)
+	}
 }
 
 // Debugf logs a message at level Debug on the standard logger.
 func (l logger) Debugf(format string, args ...interface{}) {
-	l.sourced().Debugf(format, args...)
+	if l.enabled(logrus.DebugLevel) {
+		l.sourced().Debugf(format, args...)
+	}
 }
 
 // Info logs a message at level Info on the standard logger.
 func (l logger) Info(args ...interface{}) {
-	l.sourced().Info(args...)
+	if l.enabled(logrus.InfoLevel) {
+		l.sourced().Info(args...)
+	}
 }
 
 // Info logs a message at level Info on the standard logger.
 func (l logger) Infoln(args ...interface{}) {
-	l.sourced().Infoln(args...)
+	if l.enabled(logrus.InfoLevel) {
+		l.sourced().Infoln(args...)
+	}
 }
 
 // Infof logs a message at level Info on the standard logger.
 func (l logger) Infof(format string, args ...interface{}) {
-	l.sourced().Infof(format, args...)
+	if l.enabled(logrus.InfoLevel) {
+		l.sourced().Infof(format, args...)
+	}
 }
 
 // Warn logs a message at level Warn on the standard logger.
 func (l logger) Warn(args ...interface{}) {
-	l.sourced().Warn(args...)
+	if l.enabled(logrus.WarnLevel) {
+		l.sourced().Warn(args...)
+	}
 }
 
 // Warn logs a message at level Warn on the standard logger.
 func (l logger) Warnln(args ...interface{}) {
-	l.sourced().Warnln(args...)
+	if l.enabled(logrus.WarnLevel) {
+		l.sourced().Warnln(args...)
+	}
 }
 
 // Warnf logs a message at level Warn on the standard logger.
 func (l logger) Warnf(format string, args ...interface{}) {
-	l.sourced().Warnf(format, args...)
+	if l.enabled(logrus.WarnLevel) {
+		l.sourced().Warnf(format, args...)
+	}
 }
 
 // Error logs a message at level Error on the standard logger.
 func (l logger) Error(args ...interface{}) {
-	l.sourced().Error(args...)
+	if l.enabled(logrus.ErrorLevel) {
+		l.sourced().Error(args...)
+	}
 }
 
 // Error logs a message at level Error on the standard logger.
 func (l logger) Errorln(args ...interface{}) {
-	l.sourced().Errorln(args...)
+	if l.enabled(logrus.ErrorLevel) {
+		l.sourced().Errorln(args...)
+	}
 }
 
 // Errorf logs a message at level Error on the standard logger.
 func (l logger) Errorf(format string, args ...interface{}) {
-	l.sourced().Errorf(format, args...)
+	if l.enabled(logrus.ErrorLevel) {
+		l.sourced().Errorf(format, args...)
+	}
 }
 
 // Fatal logs a message at level Fatal on the standard logger.
 func (l logger) Fatal(args ...interface{}) {
-	l.sourced().Fatal(args...)
+	if l.enabled(logrus.FatalLevel) {
+		l.sourced().Fatal(args...)
+	}
 }
 
 // Fatal logs a message at level Fatal on the standard logger.
 func (l logger) Fatalln(args ...interface{}) {
-	l.sourced().Fatalln(args...)
+	if l.enabled(logrus.FatalLevel) {
+		l.sourced().Fatalln(args...)
+	}
 }
 
 // Fatalf logs a message at level Fatal on the standard logger.
 func (l logger) Fatalf(format string, args ...interface{}) {
-	l.sourced().Fatalf(format, args...)
+	if l.enabled(logrus.FatalLevel) {
+		l.sourced().Fatalf(format, args...)
+	}
 }
 
 func (l logger) SetLevel(level string) error {
@@ -185,7 +323,24 @@ func (l logger) SetLevel(level string) error {
 		return err
 	}
 
-	l.entry.Logger.Level = lvl
+	l.levels.mu.Lock()
+	defer l.levels.mu.Unlock()
+	l.levels.level = lvl
+	l.entry.Logger.SetLevel(l.levels.maxLevel())
+	return nil
+}
+
+// SetCollectorLevel overrides the log level of the messages of a collector.
+func (l logger) SetCollectorLevel(collector, level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level of collector %s: %w", collector, err)
+	}
+
+	l.levels.mu.Lock()
+	defer l.levels.mu.Unlock()
+	l.levels.collectors[collector] = lvl
+	l.entry.Logger.SetLevel(l.levels.maxLevel())
 	return nil
 }
 
@@ -197,9 +352,13 @@ func (l logger) SetFormat(format string) error {
 	if u.Scheme != "logger" {
 		return fmt.Errorf("invalid scheme %s", u.Scheme)
 	}
-	jsonq := u.Query().Get("json")
-	if jsonq == "true" {
-		setJSONFormatter()
+	outputFormat := u.Query().Get("format")
+	// json=true predates the format parameter.
+	if u.Query().Get("json") == "true" {
+		outputFormat = "json"
+	}
+	if err := setFormatter(l, outputFormat); err != nil {
+		return err
 	}
 
 	switch u.Opaque {
@@ -221,16 +380,31 @@ func (l logger) SetFormat(format string) error {
 			debugAsInfo = parsedDebugAsInfo
 		}
 		return setEventlogFormatter(l, name, debugAsInfo)
+	case "file":
+		f, err := newRotatingFile(u.Query())
+		if err != nil {
+			return err
+		}
+		l.setOut(f)
 	case "stdout":
-		l.entry.Logger.Out = os.Stdout
+		l.setOut(os.Stdout)
 	case "stderr":
-		l.entry.Logger.Out = os.Stderr
+		l.setOut(os.Stderr)
 	default:
 		return fmt.Errorf("unsupported logger %q", u.Opaque)
 	}
 	return nil
 }
 
+// setOut sets the output of the logger, closing the previous log file.
+func (l logger) setOut(w io.Writer) {
+	prev := l.entry.Logger.Out
+	l.entry.Logger.SetOutput(w)
+	if f, ok := prev.(*rotatingFile); ok && f != w {
+		f.Close()
+	}
+}
+
 // sourced adds a source field to the logger that contains
 // the file name and line where the logging happened.
 func (l logger) sourced() *logrus.Entry {
@@ -246,7 +420,7 @@ func (l logger) sourced() *logrus.Entry {
 }
 
 var origLogger = logrus.New()
-var baseLogger = logger{entry: logrus.NewEntry(origLogger)}
+var baseLogger = logger{entry: logrus.NewEntry(origLogger), levels: newLevelFilter(origLogger.Level)}
 
 // Base returns the default Logger logging to
 func Base() Logger {
@@ -257,14 +431,14 @@ func Base() Logger {
 func NewLogger(w io.Writer) Logger {
 	l := logrus.New()
 	l.Out = w
-	return logger{entry: logrus.NewEntry(l)}
+	return logger{entry: logrus.NewEntry(l), levels: newLevelFilter(l.Level)}
 }
 
 // NewNopLogger returns a logger that discards all log messages.
 func NewNopLogger() Logger {
 	l := logrus.New()
 	l.Out = ioutil.Discard
-	return logger{entry: logrus.NewEntry(l)}
+	return logger{entry: logrus.NewEntry(l), levels: newLevelFilter(l.Level)}
 }
 
 // With adds a field to the logger.
@@ -272,79 +446,156 @@ func With(key string, value interface{}) Logger {
 	return baseLogger.With(key, value)
 }
 
+// WithFields adds fields to the logger.
+func WithFields(fields Fields) Logger {
+	return baseLogger.WithFields(fields)
+}
+
+// WithError adds an error field to the logger.
+func WithError(err error) Logger {
+	return baseLogger.WithError(err)
+}
+
+// SetCollectorLevel overrides the log level of the messages of a collector
+// on the standard logger.
+func SetCollectorLevel(collector, level string) error {
+	return baseLogger.SetCollectorLevel(collector, level)
+}
+
 // Debug logs a message at level Debug on the standard logger.
 func Debug(args ...interface{}) {
-	baseLogger.sourced().Debug(args...)
+	if baseLogger.enabled(logrus.DebugLevel) {
+		baseLogger.sourced().Debug(args...)
+	}
 }
 
 // Debugln logs a message at level Debug on the standard logger.
 func Debugln(args ...interface{}) {
-	baseLogger.sourced().Debugln(args...)
+	if baseLogger.enabled(logrus.DebugLevel) {
+		baseLogger.sourced().Debugln(args...)
+	}
 }
 
 // Debugf logs a message at level Debug on the standard logger.
 func Debugf(format string, args ...interface{}) {
-	baseLogger.sourced().Debugf(format, args...)
+	if baseLogger.enabled(logrus.DebugLevel) {
+		baseLogger.sourced().Debugf(format, args...)
+	}
 }
 
 // Info logs a message at level Info on the standard logger.
 func Info(args ...interface{}) {
-	baseLogger.sourced().Info(args...)
+	if baseLogger.enabled(logrus.InfoLevel) {
+		baseLogger.sourced().Info(args...)
+	}
 }
 
 // Infoln logs a message at level Info on the standard logger.
 func Infoln(args ...interface{}) {
-	baseLogger.sourced().Infoln(args...)
+	if baseLogger.enabled(logrus.InfoLevel) {
+		baseLogger.sourced().Infoln(args...)
+	}
 }
 
 // Infof logs a message at level Info on the standard logger.
 func Infof(format string, args ...interface{}) {
-	baseLogger.sourced().Infof(format, args...)
+	if baseLogger.enabled(logrus.InfoLevel) {
+		baseLogger.sourced().Infof(format, args...)
+	}
 }
 
 // Warn logs a message at level Warn on the standard logger.
 func Warn(args ...interface{}) {
-	baseLogger.sourced().Warn(args...)
+	if baseLogger.enabled(logrus.WarnLevel) {
+		baseLogger.sourced().Warn(args...)
+	}
 }
 
 // Warnln logs a message at level Warn on the standard logger.
 func Warnln(args ...interface{}) {
-	baseLogger.sourced().Warnln(args...)
+	if baseLogger.enabled(logrus.WarnLevel) {
+		baseLogger.sourced().Warnln(args...)
+	}
 }
 
 // Warnf logs a message at level Warn on the standard logger.
 func Warnf(format string, args ...interface{}) {
-	baseLogger.sourced().Warnf(format, args...)
+	if baseLogger.enabled(logrus.WarnLevel) {
+		baseLogger.sourced().Warnf(format, args...)
+	}
 }
 
 // Error logs a message at level Error on the standard logger.
 func Error(args ...interface{}) {
-	baseLogger.sourced().Error(args...)
+	if baseLogger.enabled(logrus.ErrorLevel) {
+		baseLogger.sourced().Error(args...)
+	}
 }
 
 // Errorln logs a message at level Error on the standard logger.
 func Errorln(args ...interface{}) {
-	baseLogger.sourced().Errorln(args...)
+	if baseLogger.enabled(logrus.ErrorLevel) {
+		baseLogger.sourced().Errorln(args...)
+	}
 }
 
 // Errorf logs a message at level Error on the standard logger.
 func Errorf(format string, args ...interface{}) {
-	baseLogger.sourced().Errorf(format, args...)
+	if baseLogger.enabled(logrus.ErrorLevel) {
+		baseLogger.sourced().Errorf(format, args...)
+	}
 }
 
 // Fatal logs a message at level Fatal on the standard logger.
 func Fatal(args ...interface{}) {
-	baseLogger.sourced().Fatal(args...)
+	if baseLogger.enabled(logrus.FatalLevel) {
+		baseLogger.sourced().Fatal(args...)
+	}
 }
 
 // Fatalln logs a message at level Fatal on the standard logger.
 func Fatalln(args ...interface{}) {
-	baseLogger.sourced().Fatalln(args...)
+	if baseLogger.enabled(logrus.FatalLevel) {
+		baseLogger.sourced().Fatalln(args...)
+	}
 }
 
 // Fatalf logs a message at level Fatal on the standard logger.
 func Fatalf(format string, args ...interface{}) {
-	baseLogger.sourced().Fatalf(format, args...)
+	if baseLogger.enabled(logrus.FatalLevel) {
+		baseLogger.sourced().Fatalf(format, args...)
+	}
+}
+
+// messageWithFields returns the message of the entry followed by its fields
+// in logfmt, for targets only taking a message.
+func messageWithFields(e *logrus.Entry) string {
+	keys := make([]string, 0, len(e.Data))
+	for k := range e.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(e.Message)
+	for _, k := range keys {
+		v := fmt.Sprint(e.Data[k])
+		if strings.ContainsAny(v, " =\"\t\n") || v == "" {
+			v = strconv.Quote(v)
+		}
+		fmt.Fprintf(&b, " %s=%s", k, v)
+	}
+	return b.String()
+}
+
+// Close closes the log file of the standard logger, if any. Messages logged
+// afterwards go to stderr.
+func Close() error {
+	if f, ok := origLogger.Out.(*rotatingFile); ok {
+		origLogger.SetOutput(os.Stderr)
+		return f.Close()
+	}
+	return nil
 }
 
 // AddHook adds hook to Prometheus' original logger.
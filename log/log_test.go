@@ -0,0 +1,138 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLogger(t *testing.T, format string) (logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	l := NewLogger(&buf).(logger)
+	if err := setFormatter(l, format); err != nil {
+		t.Fatal(err)
+	}
+	return l, &buf
+}
+
+func TestLogfmtFormat(t *testing.T) {
+	l, buf := newTestLogger(t, "logfmt")
+	l.WithFields(Fields{CollectorField: "cpu", DurationField: 0.5}).WithError(errors.New("access denied")).Error("Collector failed")
+
+	out := buf.String()
+	for _, expected := range []string{`level=error`, `msg="Collector failed"`, `collector=cpu`, `duration=0.5`, `error="access denied"`, `source="log_test.go:`} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in %q", expected, out)
+		}
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	l, buf := newTestLogger(t, "json")
+	l.With(ClassField, "Win32_Service").Warn("WMI class failed")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", buf.String(), err)
+	}
+	if entry["class"] != "Win32_Service" || entry["msg"] != "WMI class failed" || entry["level"] != "warning" {
+		t.Errorf("Unexpected entry %v", entry)
+	}
+}
+
+func TestSetFormatInvalid(t *testing.T) {
+	l := NewNopLogger()
+	for _, format := range []string{"logger:stderr?format=xml", "file:stderr", "logger:nowhere"} {
+		if err := l.SetFormat(format); err == nil {
+			t.Errorf("Expected an error for %q", format)
+		}
+	}
+}
+
+func TestMessageWithFields(t *testing.T) {
+	e := logrus.NewEntry(logrus.New()).WithFields(logrus.Fields{"collector": "cpu", "error": "access denied", "empty": ""})
+	e.Message = "Collector failed"
+
+	expected := `Collector failed collector=cpu empty="" error="access denied"`
+	if msg := messageWithFields(e); msg != expected {
+		t.Errorf("Expected %q, got %q", expected, msg)
+	}
+}
+
+func TestCollectorLevel(t *testing.T) {
+	l, buf := newTestLogger(t, "logfmt")
+	if err := l.SetLevel("info"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetCollectorLevel("mssql", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.SetCollectorLevel("cpu", "error"); err != nil {
+		t.Fatal(err)
+	}
+
+	l.Debug("global debug")
+	l.Info("global info")
+	l.With(CollectorField, "mssql").Debug("mssql debug")
+	l.With(CollectorField, "cpu").Warn("cpu warn")
+	l.With(CollectorField, "cpu").Error("cpu error")
+	l.With(CollectorField, "os").Debugf("os %s", "debug")
+
+	out := buf.String()
+	for _, expected := range []string{"global info", "mssql debug", "cpu error"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in %q", expected, out)
+		}
+	}
+	for _, unexpected := range []string{"global debug", "cpu warn", "os debug"} {
+		if strings.Contains(out, unexpected) {
+			t.Errorf("Unexpected %q in %q", unexpected, out)
+		}
+	}
+
+	if err := l.SetCollectorLevel("mssql", "verbose"); err == nil {
+		t.Error("Expected an error for an invalid level")
+	}
+}
+
+func TestAddCollectorFlags(t *testing.T) {
+	level := origLogger.Level
+	defer func() {
+		baseLogger.levels = newLevelFilter(level)
+		origLogger.SetLevel(level)
+	}()
+
+	app := kingpin.New("test", "")
+	AddCollectorFlags(app, []string{"mssql", "cpu"})
+	if _, err := app.Parse([]string{"--log.level.collector.mssql=debug"}); err != nil {
+		t.Fatal(err)
+	}
+	if !baseLogger.levels.enabled("mssql", logrus.DebugLevel) {
+		t.Error("Expected debug messages of mssql to be enabled")
+	}
+	if baseLogger.levels.enabled("cpu", logrus.DebugLevel) {
+		t.Error("Expected debug messages of cpu to be disabled")
+	}
+
+	if _, err := app.Parse([]string{"--log.level.collector.cpu=loud"}); err == nil {
+		t.Error("Expected an error for an invalid level")
+	}
+
+	if _, err := app.Parse([]string{"--log.level.collector.cpu=panic"}); err != nil {
+		t.Fatal(err)
+	}
+	if !baseLogger.levels.enabled("cpu", logrus.FatalLevel) {
+		t.Error("Expected fatal messages of cpu to be enabled at level panic")
+	}
+	if baseLogger.levels.enabled("cpu", logrus.ErrorLevel) {
+		t.Error("Expected error messages of cpu to be disabled at level panic")
+	}
+	if level := newLevelFilter(logrus.PanicLevel).maxLevel(); level != logrus.FatalLevel {
+		t.Errorf("Expected fatal messages to reach the logger, its level is %s", level)
+	}
+}
@@ -0,0 +1,38 @@
+package log
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+var messagesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "windows_exporter_log_messages_total",
+		Help: "windows_exporter: Number of log messages, by level and collector.",
+	},
+	[]string{"level", "collector"},
+)
+
+func init() {
+	origLogger.Hooks.Add(messageCounter{})
+}
+
+// messageCounter is a logrus hook counting the messages logged. Messages
+// filtered out by the log level aren't counted.
+type messageCounter struct{}
+
+func (messageCounter) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (messageCounter) Fire(e *logrus.Entry) error {
+	collector, _ := e.Data[CollectorField].(string)
+	messagesTotal.WithLabelValues(e.Level.String(), collector).Inc()
+	return nil
+}
+
+// MessagesCollector returns the collector exporting the number of log
+// messages of the standard logger.
+func MessagesCollector() prometheus.Collector {
+	return messagesTotal
+}
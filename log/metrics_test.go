@@ -0,0 +1,42 @@
+package log
+
+import (
+	"io/ioutil"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func messagesValue(t *testing.T, level, collector string) float64 {
+	var m dto.Metric
+	if err := messagesTotal.WithLabelValues(level, collector).Write(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+func TestMessagesCollector(t *testing.T) {
+	out := origLogger.Out
+	origLogger.SetOutput(ioutil.Discard)
+	defer origLogger.SetOutput(out)
+
+	warnings := messagesValue(t, "warning", "mssql")
+	debugs := messagesValue(t, "debug", "mssql")
+	errors := messagesValue(t, "error", "")
+
+	l := With(CollectorField, "mssql")
+	l.Warn("Instance not found")
+	l.Warnf("Instance %s not found", "SQLEXPRESS")
+	l.Debug("Filtered out by the level")
+	Error("Not logged by a collector")
+
+	if v := messagesValue(t, "warning", "mssql"); v != warnings+2 {
+		t.Errorf("Expected %v warnings of mssql, got %v", warnings+2, v)
+	}
+	if v := messagesValue(t, "debug", "mssql"); v != debugs {
+		t.Errorf("Expected debug messages filtered out by the level not to be counted, got %v", v)
+	}
+	if v := messagesValue(t, "error", ""); v != errors+1 {
+		t.Errorf("Expected %v errors without collector, got %v", errors+1, v)
+	}
+}
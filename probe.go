@@ -0,0 +1,85 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus-community/windows_exporter/collector"
+	"github.com/prometheus-community/windows_exporter/config"
+	"github.com/prometheus-community/windows_exporter/log"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type probeModule struct {
+	username   string
+	password   string
+	collectors map[string]collector.Collector
+}
+
+// probeHandler scrapes remote hosts over WMI, in the style of the blackbox
+// and snmp exporters: /probe?target=host&module=name
+type probeHandler struct {
+	timeoutMargin float64
+	modules       map[string]probeModule
+}
+
+func newProbeHandler(c *config.ProbeConfig, timeoutMargin float64) (*probeHandler, error) {
+	h := &probeHandler{
+		timeoutMargin: timeoutMargin,
+		modules:       make(map[string]probeModule, len(c.Modules)),
+	}
+	for name, m := range c.Modules {
+		collectors := make(map[string]collector.Collector, len(m.Collectors))
+		for _, cn := range m.Collectors {
+			if !collector.SupportsRemote(cn) {
+				return nil, fmt.Errorf("probe module %q: collector %s does not support remote targets", name, cn)
+			}
+			col, err := collector.Build(cn)
+			if err != nil {
+				return nil, fmt.Errorf("probe module %q: %v", name, err)
+			}
+			collectors[cn] = col
+		}
+		h.modules[name] = probeModule{
+			username:   m.Username,
+			password:   m.Password,
+			collectors: collectors,
+		}
+	}
+	return h, nil
+}
+
+func (ph *probeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		http.Error(w, "'target' parameter must be specified", http.StatusBadRequest)
+		return
+	}
+	moduleName := r.URL.Query().Get("module")
+	if moduleName == "" {
+		moduleName = "default"
+	}
+	module, ok := ph.modules[moduleName]
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown module %q", moduleName), http.StatusBadRequest)
+		return
+	}
+
+	wc, release, err := collector.NewRemotePrometheus(scrapeTimeout(r, ph.timeoutMargin), module.collectors, target, module.username, module.password)
+	if err != nil {
+		log.With(log.InstanceField, target).WithError(err).Warn("Couldn't create probe")
+		http.Error(w, fmt.Sprintf("Couldn't create probe: %s", err), http.StatusInternalServerError)
+		return
+	}
+	defer release()
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(wc.WithContext(r.Context()))
+	h := promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+}
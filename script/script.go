@@ -0,0 +1,232 @@
+// Package script runs the commands configured for the script collector and
+// parses the metrics they print.
+package script
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus-community/windows_exporter/config"
+	"github.com/prometheus-community/windows_exporter/log"
+	"github.com/prometheus-community/windows_exporter/textfile"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Result is the outcome of a run of a script.
+type Result struct {
+	Families []*dto.MetricFamily
+	// Exit code of the script, -1 if it couldn't be started or was killed.
+	ExitCode int
+	Duration time.Duration
+	Err      error
+}
+
+// A Runner runs scripts, limiting how many run concurrently.
+type Runner struct {
+	logger log.Logger
+
+	scripts []config.Script
+	// Default timeout of the scripts.
+	timeout time.Duration
+	// sem limits the number of scripts running concurrently.
+	sem chan struct{}
+
+	// results holds the last result of the scripts run on an interval.
+	resultsMu sync.Mutex
+	results   map[string]*Result
+
+	// stop stops the scripts run on an interval.
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewRunner returns a Runner for the scripts. Those not setting their own
+// timeout are killed after timeout.
+func NewRunner(scripts []config.Script, timeout time.Duration, maxConcurrency int, logger log.Logger) *Runner {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	return &Runner{
+		logger:  logger,
+		scripts: scripts,
+		timeout: timeout,
+		sem:     make(chan struct{}, maxConcurrency),
+		results: map[string]*Result{},
+		stop:    func() {},
+	}
+}
+
+// Scripts returns the scripts of the runner.
+func (r *Runner) Scripts() []config.Script {
+	return r.scripts
+}
+
+// Start runs the scripts having an interval in the background, until the
+// runner is closed.
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.stop = cancel
+	for _, s := range r.scripts {
+		if s.Interval > 0 {
+			r.wg.Add(1)
+			go r.schedule(ctx, s)
+		}
+	}
+}
+
+// Close stops the scripts run on an interval, killing those running.
+func (r *Runner) Close() error {
+	r.stop()
+	r.wg.Wait()
+	return nil
+}
+
+func (r *Runner) schedule(ctx context.Context, s config.Script) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		result := r.Run(ctx, s)
+		if ctx.Err() != nil {
+			return
+		}
+		r.resultsMu.Lock()
+		r.results[s.Name] = result
+		r.resultsMu.Unlock()
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// RunAll returns the results of the scripts, in the order of Scripts. The
+// scripts without interval are run concurrently, the others report their
+// last result, nil if they haven't run yet.
+func (r *Runner) RunAll(ctx context.Context) []*Result {
+	results := make([]*Result, len(r.scripts))
+	var wg sync.WaitGroup
+	for i, s := range r.scripts {
+		if s.Interval > 0 {
+			r.resultsMu.Lock()
+			results[i] = r.results[s.Name]
+			r.resultsMu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, s config.Script) {
+			defer wg.Done()
+			results[i] = r.Run(ctx, s)
+		}(i, s)
+	}
+	wg.Wait()
+	return results
+}
+
+// Run runs a script and parses its output, waiting for a free slot if the
+// maximum number of scripts are already running. The script is killed once
+// parent is cancelled.
+func (r *Runner) Run(parent context.Context, s config.Script) *Result {
+	select {
+	case r.sem <- struct{}{}:
+	case <-parent.Done():
+		return &Result{ExitCode: -1, Err: fmt.Errorf("script %q not run: %w", s.Name, parent.Err())}
+	}
+	defer func() { <-r.sem }()
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = r.timeout
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.Command, s.Args...)
+	cmd.Dir = s.WorkingDir
+	start := time.Now()
+	stdout, stderr, err := runCommand(ctx, cmd)
+	result := &Result{Duration: time.Since(start)}
+
+	var exitErr *exec.ExitError
+	switch {
+	case parent.Err() != nil:
+		result.ExitCode = -1
+		result.Err = fmt.Errorf("script %q killed: %w", s.Name, parent.Err())
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		result.Err = fmt.Errorf("script %q timed out after %s", s.Name, timeout)
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		result.Err = fmt.Errorf("script %q exited with code %d: %s", s.Name, result.ExitCode, strings.TrimSpace(stderr.String()))
+	case err != nil:
+		result.ExitCode = -1
+		result.Err = fmt.Errorf("failed to run script %q: %w", s.Name, err)
+	default:
+		if stderr.Len() > 0 {
+			r.logger.WithFields(log.Fields{"script": s.Name, "stderr": strings.TrimSpace(stderr.String())}).Debug("Script wrote to stderr")
+		}
+		result.Families, result.Err = textfile.Parse(stdout, fmt.Sprintf("output of script %q", s.Name), textfile.ExtProm, textfile.TimestampsReject, r.logger)
+	}
+	return result
+}
+
+// runCommand runs the command, returning its output. Unlike cmd.Run, it
+// doesn't wait for the output past the deadline of ctx, which is never
+// reached when a killed command leaves behind children holding it open.
+// No output is returned then.
+func runCommand(ctx context.Context, cmd *exec.Cmd) (*bytes.Buffer, *bytes.Buffer, error) {
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer outR.Close()
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		outW.Close()
+		return nil, nil, err
+	}
+	defer errR.Close()
+
+	cmd.Stdout, cmd.Stderr = outW, errW
+	err = cmd.Start()
+	// The command has its own copies of the write ends.
+	outW.Close()
+	errW.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			io.Copy(stdout, outR) //nolint:errcheck
+		}()
+		go func() {
+			defer wg.Done()
+			io.Copy(stderr, errR) //nolint:errcheck
+		}()
+		wg.Wait()
+	}()
+
+	err = cmd.Wait()
+	select {
+	case <-done:
+		return stdout, stderr, err
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
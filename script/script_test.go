@@ -0,0 +1,141 @@
+package script
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus-community/windows_exporter/config"
+	"github.com/prometheus-community/windows_exporter/log"
+)
+
+// shellScript returns a script running the given shell command.
+func shellScript(t *testing.T, name, command string) config.Script {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is required to run the test scripts")
+	}
+	return config.Script{Name: name, Command: "sh", Args: []string{"-c", command}}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "metrics.prom"), []byte("job_success{job=\"dir\"} 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	workingDir := shellScript(t, "working_dir", "cat metrics.prom")
+	workingDir.WorkingDir = dir
+	slow := shellScript(t, "slow", "sleep 5")
+	slow.Timeout = 100 * time.Millisecond
+
+	r := NewRunner(nil, time.Minute, 4, log.NewNopLogger())
+	for _, tc := range []struct {
+		script   config.Script
+		exitCode int
+		err      string
+		families []string
+	}{
+		{script: shellScript(t, "ok", `printf '# TYPE job_success gauge\njob_success{job="ok"} 1\n'`), families: []string{"job_success"}},
+		{script: shellScript(t, "failing", `echo 'job_success{job="failing"} 0'; echo oops >&2; exit 3`), exitCode: 3, err: "exited with code 3: oops"},
+		{script: shellScript(t, "invalid", `echo 'job_success{job="invalid" 0'`), err: "error parsing output"},
+		{script: shellScript(t, "timestamp", `echo 'job_success 1 1600000000000'`), err: "timestamp"},
+		{script: workingDir, families: []string{"job_success"}},
+		{script: slow, exitCode: -1, err: "timed out after 100ms"},
+		{script: config.Script{Name: "missing", Command: filepath.Join(dir, "missing")}, exitCode: -1, err: "failed to run"},
+	} {
+		result := r.Run(context.Background(), tc.script)
+		if result.ExitCode != tc.exitCode {
+			t.Errorf("%s: expected exit code %d, got %d", tc.script.Name, tc.exitCode, result.ExitCode)
+		}
+		switch {
+		case tc.err == "" && result.Err != nil:
+			t.Errorf("%s: unexpected error %v", tc.script.Name, result.Err)
+		case tc.err != "" && (result.Err == nil || !strings.Contains(result.Err.Error(), tc.err)):
+			t.Errorf("%s: expected an error containing %q, got %v", tc.script.Name, tc.err, result.Err)
+		}
+		var families []string
+		for _, mf := range result.Families {
+			families = append(families, mf.GetName())
+		}
+		if strings.Join(families, ",") != strings.Join(tc.families, ",") {
+			t.Errorf("%s: expected families %v, got %v", tc.script.Name, tc.families, families)
+		}
+	}
+
+	if result := r.Run(context.Background(), slow); result.Duration >= 5*time.Second {
+		t.Errorf("Expected the slow script to be killed, took %s", result.Duration)
+	}
+}
+
+func TestRunAllConcurrency(t *testing.T) {
+	r := NewRunner([]config.Script{
+		shellScript(t, "a", "sleep 0.2"),
+		shellScript(t, "b", "sleep 0.2"),
+	}, time.Minute, 1, log.NewNopLogger())
+
+	start := time.Now()
+	r.RunAll(context.Background())
+	if d := time.Since(start); d < 400*time.Millisecond {
+		t.Errorf("Expected the scripts to run one after the other, took %s", d)
+	}
+}
+
+func TestRunAllInterval(t *testing.T) {
+	script := shellScript(t, "interval", `echo "runs_total $(cat runs 2>/dev/null || echo 0)"; echo 1 > runs`)
+	script.WorkingDir = t.TempDir()
+	script.Interval = time.Hour
+	r := NewRunner([]config.Script{script}, time.Minute, 1, log.NewNopLogger())
+
+	// Interval scripts don't run on RunAll.
+	if results := r.RunAll(context.Background()); results[0] != nil {
+		t.Fatalf("Expected no result before the first run, got %+v", results[0])
+	}
+
+	r.Start()
+	defer r.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for r.RunAll(context.Background())[0] == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Interval script did not run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	result := r.RunAll(context.Background())[0]
+	if result.Err != nil || len(result.Families) != 1 || result.Families[0].GetMetric()[0].GetUntyped().GetValue() != 0 {
+		t.Errorf("Expected the script to run only once, got %+v", result)
+	}
+}
+
+func TestRunAllCancel(t *testing.T) {
+	r := NewRunner([]config.Script{shellScript(t, "slow", "sleep 5")}, time.Minute, 1, log.NewNopLogger())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	start := time.Now()
+	result := r.RunAll(ctx)[0]
+	if d := time.Since(start); d >= 5*time.Second {
+		t.Errorf("Expected the script to be killed once the context is cancelled, took %s", d)
+	}
+	if result.ExitCode != -1 || result.Err == nil {
+		t.Errorf("Expected the killed script to fail, got %+v", result)
+	}
+}
+
+func TestRunnerClose(t *testing.T) {
+	script := shellScript(t, "slow", "sleep 5")
+	script.Interval = time.Hour
+	r := NewRunner([]config.Script{script}, time.Minute, 1, log.NewNopLogger())
+	r.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= 5*time.Second {
+		t.Errorf("Expected the running script to be killed on close, took %s", d)
+	}
+}
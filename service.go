@@ -0,0 +1,337 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	defaultServiceName = "windows_exporter"
+	// serviceStateTimeout is the time waited for the service to start or stop,
+	// longer than the default --web.shutdown-grace-period (20s) and
+	// stopMargin the exporter is given to stop.
+	serviceStateTimeout = time.Minute
+	// serviceRestarts is the number of consecutive failures after which the
+	// service is restarted, as the MSI does.
+	serviceRestarts = 3
+)
+
+// Built-in accounts services may run as, by their short names.
+var serviceAccounts = map[string]string{
+	"localsystem":    "LocalSystem",
+	"localservice":   `NT AUTHORITY\LocalService`,
+	"networkservice": `NT AUTHORITY\NetworkService`,
+}
+
+var serviceStartTypes = map[string]uint32{
+	"auto":     mgr.StartAutomatic,
+	"manual":   mgr.StartManual,
+	"disabled": mgr.StartDisabled,
+}
+
+// serviceInstallOptions are the arguments of the service install command.
+type serviceInstallOptions struct {
+	name         string
+	displayName  string
+	description  string
+	configFile   string
+	account      string
+	password     string
+	startType    string
+	delayedStart bool
+	restartDelay time.Duration
+	resetPeriod  time.Duration
+	// args are passed to the exporter when the service starts.
+	args []string
+}
+
+// serviceCommands holds the service commands and their arguments.
+type serviceCommands struct {
+	install   *kingpin.CmdClause
+	uninstall *kingpin.CmdClause
+	start     *kingpin.CmdClause
+	stop      *kingpin.CmdClause
+
+	name    string
+	options serviceInstallOptions
+}
+
+// addServiceCommands adds the commands managing the windows_exporter service
+// to the Kingpin application.
+func addServiceCommands(app *kingpin.Application) *serviceCommands {
+	c := &serviceCommands{}
+	service := app.Command("service", "Manage the windows_exporter service.")
+	service.Flag("name", "Name of the service.").Default(defaultServiceName).StringVar(&c.name)
+
+	c.install = service.Command("install", "Install the service, running the exporter with the configuration file and arguments given.")
+	c.install.Flag("display-name", "Display name of the service. Defaults to its name.").StringVar(&c.options.displayName)
+	c.install.Flag("description", "Description of the service.").Default("Exports Prometheus metrics about the system").StringVar(&c.options.description)
+	c.install.Flag("account", "Account the service runs as: LocalSystem, LocalService, NetworkService, or a user such as DOMAIN\\user or a managed service account DOMAIN\\account$.").Default("LocalSystem").StringVar(&c.options.account)
+	c.install.Flag("password", "Password of the account.").StringVar(&c.options.password)
+	c.install.Flag("start-type", "Start type of the service.").Default("auto").EnumVar(&c.options.startType, "auto", "manual", "disabled")
+	c.install.Flag("delayed-start", "Start the service after the other automatic services, with the auto start type.").BoolVar(&c.options.delayedStart)
+	c.install.Flag("restart-delay", "Time after which the service is restarted on its first failures. 0 to disable restarts.").Default("60s").DurationVar(&c.options.restartDelay)
+	c.install.Flag("reset-period", "Time without failure after which the failure count is reset.").Default("24h").DurationVar(&c.options.resetPeriod)
+	c.install.Arg("args", "Arguments of the exporter, following --, e.g. -- --collectors.enabled=cpu,os").StringsVar(&c.options.args)
+
+	c.uninstall = service.Command("uninstall", "Stop and remove the service.")
+	c.start = service.Command("start", "Start the service.")
+	c.stop = service.Command("stop", "Stop the service.")
+	return c
+}
+
+// run runs the selected service command.
+func (c *serviceCommands) run(command, configFile string) error {
+	switch command {
+	case c.install.FullCommand():
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		opts := c.options
+		opts.name = c.name
+		opts.configFile = configFile
+		spec, err := opts.spec(exe)
+		if err != nil {
+			return err
+		}
+		return installService(spec)
+	case c.uninstall.FullCommand():
+		return uninstallService(c.name)
+	case c.start.FullCommand():
+		return startService(c.name)
+	case c.stop.FullCommand():
+		return stopService(c.name)
+	}
+	return fmt.Errorf("unknown command %q", command)
+}
+
+// serviceSpec describes the service to install.
+type serviceSpec struct {
+	name        string
+	exe         string
+	config      mgr.Config
+	args        []string
+	recovery    []mgr.RecoveryAction
+	resetPeriod uint32
+}
+
+// spec translates the options to the service to install, running exe.
+func (o serviceInstallOptions) spec(exe string) (serviceSpec, error) {
+	startType, ok := serviceStartTypes[o.startType]
+	if !ok {
+		return serviceSpec{}, fmt.Errorf("invalid start type %q", o.startType)
+	}
+	if o.delayedStart && startType != mgr.StartAutomatic {
+		return serviceSpec{}, errors.New("delayed start requires the auto start type")
+	}
+
+	account := o.account
+	if builtin, ok := serviceAccounts[strings.ToLower(account)]; ok {
+		account = builtin
+	}
+	if account == serviceAccounts["localsystem"] {
+		// Empty means LocalSystem to the service control manager.
+		account = ""
+	}
+	if o.password != "" && (account == "" || strings.HasPrefix(account, `NT AUTHORITY\`) || strings.HasSuffix(account, "$")) {
+		return serviceSpec{}, fmt.Errorf("account %s doesn't take a password", o.account)
+	}
+
+	displayName := o.displayName
+	if displayName == "" {
+		displayName = o.name
+	}
+
+	var args []string
+	if o.configFile != "" {
+		configFile, err := filepath.Abs(o.configFile)
+		if err != nil {
+			return serviceSpec{}, err
+		}
+		args = append(args, "--config.file="+configFile)
+	}
+	if !hasFlag(o.args, "log.format") {
+		args = append(args, "--log.format=logger:eventlog?name="+o.name)
+	}
+	args = append(args, o.args...)
+
+	var recovery []mgr.RecoveryAction
+	if o.restartDelay > 0 {
+		for i := 0; i < serviceRestarts; i++ {
+			recovery = append(recovery, mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: o.restartDelay})
+		}
+	}
+
+	return serviceSpec{
+		name: o.name,
+		exe:  exe,
+		config: mgr.Config{
+			ServiceType:      windows.SERVICE_WIN32_OWN_PROCESS,
+			StartType:        startType,
+			ErrorControl:     mgr.ErrorNormal,
+			DisplayName:      displayName,
+			Description:      o.description,
+			ServiceStartName: account,
+			Password:         o.password,
+			DelayedAutoStart: o.delayedStart,
+			Dependencies:     []string{"wmiApSrv"},
+		},
+		args:        args,
+		recovery:    recovery,
+		resetPeriod: uint32(o.resetPeriod / time.Second),
+	}, nil
+}
+
+// hasFlag returns whether the arguments set the flag.
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if arg == "--"+name || strings.HasPrefix(arg, "--"+name+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+func installService(spec serviceSpec) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect() //nolint:errcheck
+
+	if s, err := m.OpenService(spec.name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", spec.name)
+	}
+
+	s, err := m.CreateService(spec.name, spec.exe, spec.config, spec.args...)
+	if err != nil {
+		return fmt.Errorf("failed to create service %s: %w", spec.name, err)
+	}
+	defer s.Close()
+
+	if len(spec.recovery) > 0 {
+		if err := s.SetRecoveryActions(spec.recovery, spec.resetPeriod); err != nil {
+			s.Delete() //nolint:errcheck
+			return fmt.Errorf("failed to set recovery actions of service %s: %w", spec.name, err)
+		}
+	}
+	if err := eventlog.InstallAsEventCreate(spec.name, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		// The event source remains after a previous installation.
+		fmt.Fprintf(os.Stderr, "Couldn't register event source %s: %v\n", spec.name, err)
+	}
+	fmt.Printf("Service %s installed\n", spec.name)
+	return nil
+}
+
+func uninstallService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect() //nolint:errcheck
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", name, err)
+	}
+	defer s.Close()
+
+	// The service is still removed if it doesn't stop in time, the deletion
+	// being deferred until it stops.
+	if status, err := s.Query(); err == nil && status.State != svc.Stopped {
+		if err := controlService(s, svc.Stop, svc.Stopped); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't stop service %s, it will be removed once stopped: %v\n", name, err)
+		}
+	}
+	if err := s.Delete(); err != nil {
+		return fmt.Errorf("failed to remove service %s: %w", name, err)
+	}
+	if err := eventlog.Remove(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't remove event source %s: %v\n", name, err)
+	}
+	fmt.Printf("Service %s uninstalled\n", name)
+	return nil
+}
+
+func startService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect() //nolint:errcheck
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", name, err)
+	}
+	defer s.Close()
+
+	if err := s.Start(); err != nil {
+		return fmt.Errorf("failed to start service %s: %w", name, err)
+	}
+	if err := waitForState(s, svc.Running); err != nil {
+		return err
+	}
+	fmt.Printf("Service %s started\n", name)
+	return nil
+}
+
+func stopService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect() //nolint:errcheck
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed: %w", name, err)
+	}
+	defer s.Close()
+
+	if err := controlService(s, svc.Stop, svc.Stopped); err != nil {
+		return err
+	}
+	fmt.Printf("Service %s stopped\n", name)
+	return nil
+}
+
+// controlService sends the control to the service and waits for its state.
+func controlService(s *mgr.Service, c svc.Cmd, state svc.State) error {
+	if _, err := s.Control(c); err != nil {
+		return fmt.Errorf("failed to send control %d to service %s: %w", c, s.Name, err)
+	}
+	return waitForState(s, state)
+}
+
+func waitForState(s *mgr.Service, state svc.State) error {
+	deadline := time.Now().Add(serviceStateTimeout)
+	for {
+		status, err := s.Query()
+		if err != nil {
+			return fmt.Errorf("failed to query service %s: %w", s.Name, err)
+		}
+		if status.State == state {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for service %s to reach state %d, in state %d", s.Name, state, status.State)
+		}
+		time.Sleep(300 * time.Millisecond)
+	}
+}
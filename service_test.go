@@ -0,0 +1,95 @@
+//go:build windows
+// +build windows
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+func TestServiceSpec(t *testing.T) {
+	opts := serviceInstallOptions{
+		name:         "windows_exporter",
+		description:  "Exports Prometheus metrics about the system",
+		configFile:   `C:\windows_exporter\config.yml`,
+		account:      "NetworkService",
+		startType:    "auto",
+		delayedStart: true,
+		restartDelay: time.Minute,
+		resetPeriod:  24 * time.Hour,
+		args:         []string{"--collectors.enabled=cpu,os"},
+	}
+	spec, err := opts.spec(`C:\windows_exporter\windows_exporter.exe`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedArgs := []string{
+		`--config.file=C:\windows_exporter\config.yml`,
+		"--log.format=logger:eventlog?name=windows_exporter",
+		"--collectors.enabled=cpu,os",
+	}
+	if !reflect.DeepEqual(spec.args, expectedArgs) {
+		t.Errorf("expected args %q, got %q", expectedArgs, spec.args)
+	}
+	if spec.config.ServiceStartName != `NT AUTHORITY\NetworkService` {
+		t.Errorf("unexpected account %q", spec.config.ServiceStartName)
+	}
+	if spec.config.StartType != mgr.StartAutomatic || !spec.config.DelayedAutoStart {
+		t.Errorf("expected a delayed automatic start, got start type %d, delayed %v", spec.config.StartType, spec.config.DelayedAutoStart)
+	}
+	if spec.config.DisplayName != "windows_exporter" {
+		t.Errorf("expected the display name to default to the name, got %q", spec.config.DisplayName)
+	}
+	expectedRecovery := []mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}
+	if !reflect.DeepEqual(spec.recovery, expectedRecovery) {
+		t.Errorf("expected recovery actions %v, got %v", expectedRecovery, spec.recovery)
+	}
+	if spec.resetPeriod != 86400 {
+		t.Errorf("expected a reset period of 86400s, got %d", spec.resetPeriod)
+	}
+}
+
+func TestServiceSpecDefaults(t *testing.T) {
+	opts := serviceInstallOptions{
+		name:      "exporter",
+		account:   "LocalSystem",
+		startType: "manual",
+		args:      []string{"--log.format=logger:stderr"},
+	}
+	spec, err := opts.spec(`C:\windows_exporter.exe`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.config.ServiceStartName != "" {
+		t.Errorf("expected LocalSystem to be left empty, got %q", spec.config.ServiceStartName)
+	}
+	if !reflect.DeepEqual(spec.args, []string{"--log.format=logger:stderr"}) {
+		t.Errorf("expected the log format of the arguments to be kept, got %q", spec.args)
+	}
+	if len(spec.recovery) != 0 {
+		t.Errorf("expected no recovery actions, got %v", spec.recovery)
+	}
+}
+
+func TestServiceSpecInvalid(t *testing.T) {
+	cases := map[string]serviceInstallOptions{
+		"delayed manual start":     {startType: "manual", delayedStart: true, account: "LocalSystem"},
+		"unknown start type":       {startType: "boot", account: "LocalSystem"},
+		"password of LocalService": {startType: "auto", account: "LocalService", password: "secret"},
+		"password of gMSA":         {startType: "auto", account: `CORP\exporter$`, password: "secret"},
+	}
+	for name, opts := range cases {
+		if _, err := opts.spec(`C:\windows_exporter.exe`); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
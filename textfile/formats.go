@@ -0,0 +1,280 @@
+package textfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// Extensions of the supported formats.
+const (
+	ExtProm        = ".prom"
+	ExtOpenMetrics = ".om"
+	ExtJSON        = ".json"
+)
+
+// openMetricsEOF terminates every OpenMetrics exposition.
+const openMetricsEOF = "# EOF"
+
+// parseOpenMetrics parses the OpenMetrics text format. The input is translated
+// to the Prometheus text format, which is then parsed as usual:
+//   - counters get their _total suffix in TYPE and HELP lines, and info
+//     metrics their _info suffix,
+//   - info and stateset metrics become gauges,
+//   - unknown metrics become untyped,
+//   - _created samples, UNIT lines and exemplars are dropped,
+//   - timestamps are converted from seconds to milliseconds.
+//
+// A missing "# EOF" line is reported as an error, as it usually means the file
+// was read while being written.
+func parseOpenMetrics(r io.Reader) (map[string]*dto.MetricFamily, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if lines[len(lines)-1] != openMetricsEOF {
+		return nil, fmt.Errorf("missing %q line at the end of the file", openMetricsEOF)
+	}
+	lines = lines[:len(lines)-1]
+
+	types := map[string]string{}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "# TYPE ") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("line %d: invalid TYPE line %q", i+1, line)
+		}
+		types[fields[2]] = fields[3]
+	}
+
+	var buf bytes.Buffer
+	for i, line := range lines {
+		var (
+			converted string
+			err       error
+		)
+		switch {
+		case line == openMetricsEOF:
+			return nil, fmt.Errorf("line %d: unexpected %q line before the end of the file", i+1, openMetricsEOF)
+		case strings.HasPrefix(line, "# TYPE "):
+			converted, err = convertOpenMetricsType(line, types)
+		case strings.HasPrefix(line, "# HELP "):
+			converted = convertOpenMetricsHelp(line, types)
+		case line == "" || strings.HasPrefix(line, "#"):
+			// UNIT lines have no equivalent in the text format.
+			continue
+		default:
+			converted, err = convertOpenMetricsSample(line, types)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		if converted != "" {
+			buf.WriteString(converted)
+			buf.WriteByte('\n')
+		}
+	}
+
+	var parser expfmt.TextParser
+	return parser.TextToMetricFamilies(&buf)
+}
+
+// openMetricsFamilyName returns the name of the family in the text format.
+func openMetricsFamilyName(name, typ string) string {
+	switch typ {
+	case "counter":
+		return name + "_total"
+	case "info":
+		return name + "_info"
+	}
+	return name
+}
+
+func convertOpenMetricsType(line string, types map[string]string) (string, error) {
+	fields := strings.Fields(line)
+	name, typ := fields[2], fields[3]
+
+	textType := typ
+	switch typ {
+	case "counter", "gauge", "histogram", "summary":
+	case "unknown":
+		textType = "untyped"
+	case "info", "stateset":
+		textType = "gauge"
+	default:
+		return "", fmt.Errorf("unsupported metric type %q", typ)
+	}
+	return fmt.Sprintf("# TYPE %s %s", openMetricsFamilyName(name, types[name]), textType), nil
+}
+
+func convertOpenMetricsHelp(line string, types map[string]string) string {
+	rest := strings.TrimPrefix(line, "# HELP ")
+	name, help := rest, ""
+	if i := strings.IndexByte(rest, ' '); i >= 0 {
+		name, help = rest[:i], rest[i+1:]
+	}
+	// Double quotes are escaped in OpenMetrics, but not in the text format.
+	help = strings.ReplaceAll(help, `\"`, `"`)
+	return fmt.Sprintf("# HELP %s %s", openMetricsFamilyName(name, types[name]), help)
+}
+
+func convertOpenMetricsSample(line string, types map[string]string) (string, error) {
+	end := strings.IndexAny(line, "{ ")
+	if end < 0 {
+		return "", fmt.Errorf("invalid sample %q", line)
+	}
+	name := line[:end]
+	if base := strings.TrimSuffix(name, "_created"); base != name {
+		switch types[base] {
+		case "counter", "histogram", "summary":
+			return "", nil
+		}
+	}
+
+	if line[end] == '{' {
+		end = openMetricsLabelsEnd(line, end)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated label set in %q", line)
+		}
+	}
+
+	fields := strings.Fields(line[end:])
+	if len(fields) == 0 || fields[0] == "#" {
+		return "", fmt.Errorf("missing value in %q", line)
+	}
+	sample := line[:end] + " " + fields[0]
+	if len(fields) > 1 && fields[1] != "#" {
+		ts, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid timestamp in %q: %v", line, err)
+		}
+		sample += " " + strconv.FormatInt(int64(math.Round(ts*1000)), 10)
+	}
+	return sample, nil
+}
+
+// openMetricsLabelsEnd returns the index following the label set starting at
+// start, or -1 if it isn't terminated. Label values may contain any character.
+func openMetricsLabelsEnd(line string, start int) int {
+	inQuotes, escaped := false, false
+	for i := start + 1; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == '}' && !inQuotes:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// jsonMetric is a sample of a JSON textfile. A file holds either a single
+// sample or an array of them:
+//
+//	[
+//	  {"name": "job_success", "help": "Whether the job succeeded", "type": "gauge", "labels": {"job": "backup"}, "value": 1}
+//	]
+type jsonMetric struct {
+	Name   string            `json:"name"`
+	Help   string            `json:"help"`
+	Type   string            `json:"type"`
+	Labels map[string]string `json:"labels"`
+	Value  *float64          `json:"value"`
+}
+
+var jsonMetricTypes = map[string]dto.MetricType{
+	"":        dto.MetricType_UNTYPED,
+	"untyped": dto.MetricType_UNTYPED,
+	"counter": dto.MetricType_COUNTER,
+	"gauge":   dto.MetricType_GAUGE,
+}
+
+// parseJSONMetrics parses a JSON textfile into metric families.
+func parseJSONMetrics(r io.Reader) (map[string]*dto.MetricFamily, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// PowerShell's ConvertTo-Json turns single element arrays into objects.
+	var metrics []jsonMetric
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '{' {
+		var m jsonMetric
+		if err := json.Unmarshal(trimmed, &m); err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	} else if err := json.Unmarshal(b, &metrics); err != nil {
+		return nil, err
+	}
+
+	families := map[string]*dto.MetricFamily{}
+	for i, m := range metrics {
+		if !model.IsValidMetricName(model.LabelValue(m.Name)) {
+			return nil, fmt.Errorf("metric %d: invalid metric name %q", i, m.Name)
+		}
+		typ, ok := jsonMetricTypes[m.Type]
+		if !ok {
+			return nil, fmt.Errorf("metric %d: unsupported metric type %q", i, m.Type)
+		}
+		if m.Value == nil {
+			return nil, fmt.Errorf("metric %d: missing value", i)
+		}
+
+		mf, ok := families[m.Name]
+		if !ok {
+			mf = &dto.MetricFamily{Name: &metrics[i].Name, Type: typ.Enum()}
+			families[m.Name] = mf
+		} else if mf.GetType() != typ {
+			return nil, fmt.Errorf("metric %d: type of %s differs from a previous sample", i, m.Name)
+		}
+		if m.Help != "" {
+			if mf.Help != nil && mf.GetHelp() != m.Help {
+				return nil, fmt.Errorf("metric %d: help of %s differs from a previous sample", i, m.Name)
+			}
+			mf.Help = &metrics[i].Help
+		}
+
+		metric := &dto.Metric{}
+		labelNames := make([]string, 0, len(m.Labels))
+		for name := range m.Labels {
+			labelNames = append(labelNames, name)
+		}
+		sort.Strings(labelNames)
+		for _, name := range labelNames {
+			if !model.LabelName(name).IsValid() {
+				return nil, fmt.Errorf("metric %d: invalid label name %q", i, name)
+			}
+			name, value := name, m.Labels[name]
+			metric.Label = append(metric.Label, &dto.LabelPair{Name: &name, Value: &value})
+		}
+		switch typ {
+		case dto.MetricType_COUNTER:
+			metric.Counter = &dto.Counter{Value: m.Value}
+		case dto.MetricType_GAUGE:
+			metric.Gauge = &dto.Gauge{Value: m.Value}
+		default:
+			metric.Untyped = &dto.Untyped{Value: m.Value}
+		}
+		mf.Metric = append(mf.Metric, metric)
+	}
+	return families, nil
+}
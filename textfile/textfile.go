@@ -0,0 +1,182 @@
+// Copyright 2015 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package textfile parses metrics files and command outputs in the formats
+// supported by the textfile and script collectors.
+package textfile
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dimchansky/utfbom"
+	"github.com/prometheus-community/windows_exporter/log"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Handling of client-side timestamps.
+const (
+	TimestampsReject = "reject"
+	TimestampsHonor  = "honor"
+	TimestampsStrip  = "strip"
+)
+
+// Reasons for rejecting an input, as reported in the reason label of
+// windows_textfile_scrape_error.
+const (
+	ErrorRead      = "read"
+	ErrorParse     = "parse"
+	ErrorEncoding  = "encoding"
+	ErrorDuplicate = "duplicate"
+	ErrorTimestamp = "timestamp"
+	ErrorSize      = "size"
+	ErrorSeries    = "series"
+)
+
+// Error is the reason a file or source was rejected.
+type Error struct {
+	Reason string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// MetricKey identifies a metric by its name, label names and label values.
+func MetricKey(name string, metric *dto.Metric) string {
+	labels := make([]string, 0, len(metric.GetLabel()))
+	for _, label := range metric.GetLabel() {
+		labels = append(labels, label.GetName()+"\xff"+label.GetValue())
+	}
+	sort.Strings(labels)
+	return name + "\xfe" + strings.Join(labels, "\xfe")
+}
+
+// Given a slice of metric families, determine if any two entries are duplicates.
+// Duplicates will be detected where the metric name, labels and label values are identical.
+func duplicateMetricEntry(metricFamilies []*dto.MetricFamily) bool {
+	return len(DuplicateMetricKeys(metricFamilies, map[string]struct{}{})) > 0
+}
+
+// DuplicateMetricKeys returns the keys of the metrics which are either present
+// several times in metricFamilies, or already present in seen.
+func DuplicateMetricKeys(metricFamilies []*dto.MetricFamily, seen map[string]struct{}) []string {
+	var duplicates []string
+	keys := make(map[string]struct{})
+	for _, metricFamily := range metricFamilies {
+		for _, metric := range metricFamily.Metric {
+			key := MetricKey(metricFamily.GetName(), metric)
+			if _, ok := keys[key]; ok {
+				duplicates = append(duplicates, key)
+				continue
+			}
+			if _, ok := seen[key]; ok {
+				duplicates = append(duplicates, key)
+			}
+			keys[key] = struct{}{}
+		}
+	}
+	return duplicates
+}
+
+type carriageReturnFilteringReader struct {
+	r io.Reader
+}
+
+// Read returns data from the underlying io.Reader, but with \r filtered out
+func (cr carriageReturnFilteringReader) Read(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	n, err := cr.r.Read(buf)
+
+	if err != nil && err != io.EOF {
+		return n, err
+	}
+
+	pi := 0
+	for i := 0; i < n; i++ {
+		if buf[i] != '\r' {
+			p[pi] = buf[i]
+			pi++
+		}
+	}
+
+	return pi, err
+}
+
+// Parse parses the metrics of a file or command output in the format
+// of the given extension, handling client-side timestamps according to
+// timestamps. source describes the input in errors and in the messages logged
+// to logger.
+func Parse(input io.Reader, source, ext, timestamps string, logger log.Logger) ([]*dto.MetricFamily, error) {
+	r, encoding := utfbom.Skip(carriageReturnFilteringReader{r: input})
+	if err := checkBOM(encoding); err != nil {
+		return nil, &Error{Reason: ErrorEncoding, Err: fmt.Errorf("invalid encoding detected in %s: %s - input must be UTF8", source, err.Error())}
+	}
+	var (
+		parsedFamilies map[string]*dto.MetricFamily
+		err            error
+	)
+	switch ext {
+	case ExtOpenMetrics:
+		parsedFamilies, err = parseOpenMetrics(r)
+	case ExtJSON:
+		parsedFamilies, err = parseJSONMetrics(r)
+	default:
+		var parser expfmt.TextParser
+		parsedFamilies, err = parser.TextToMetricFamilies(r)
+	}
+	if err != nil {
+		return nil, &Error{Reason: ErrorParse, Err: fmt.Errorf("error parsing %s: %v", source, err)}
+	}
+
+	families := make([]*dto.MetricFamily, 0, len(parsedFamilies))
+	stripped := 0
+	for _, mf := range parsedFamilies {
+		for _, m := range mf.Metric {
+			if m.TimestampMs == nil {
+				continue
+			}
+			switch timestamps {
+			case TimestampsHonor:
+			case TimestampsStrip:
+				m.TimestampMs = nil
+				stripped++
+			default:
+				return nil, &Error{Reason: ErrorTimestamp, Err: fmt.Errorf("%s contains unsupported client-side timestamps, skipping it entirely", source)}
+			}
+		}
+		families = append(families, mf)
+	}
+	if stripped > 0 {
+		logger.Warnf("Stripped client-side timestamps from %d metrics of %s", stripped, source)
+	}
+
+	// If duplicate metrics are detected in a *single* input, skip processing of its metrics
+	if duplicateMetricEntry(families) {
+		return nil, &Error{Reason: ErrorDuplicate, Err: fmt.Errorf("duplicate metrics detected in %s, skipping its processing", source)}
+	}
+
+	return families, nil
+}
+
+func checkBOM(encoding utfbom.Encoding) error {
+	if encoding == utfbom.Unknown || encoding == utfbom.UTF8 {
+		return nil
+	}
+
+	return fmt.Errorf(encoding.String())
+}
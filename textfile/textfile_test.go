@@ -0,0 +1,248 @@
+package textfile
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/dimchansky/utfbom"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestCRFilter(t *testing.T) {
+	sr := strings.NewReader("line 1\r\nline 2")
+	cr := carriageReturnFilteringReader{r: sr}
+	b, err := ioutil.ReadAll(cr)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if string(b) != "line 1\nline 2" {
+		t.Errorf("Unexpected output %q", b)
+	}
+}
+
+func TestCheckBOM(t *testing.T) {
+	testdata := []struct {
+		encoding utfbom.Encoding
+		err      string
+	}{
+		{utfbom.Unknown, ""},
+		{utfbom.UTF8, ""},
+		{utfbom.UTF16BigEndian, "UTF16BigEndian"},
+		{utfbom.UTF16LittleEndian, "UTF16LittleEndian"},
+		{utfbom.UTF32BigEndian, "UTF32BigEndian"},
+		{utfbom.UTF32LittleEndian, "UTF32LittleEndian"},
+	}
+	for _, d := range testdata {
+		err := checkBOM(d.encoding)
+		if d.err == "" && err != nil {
+			t.Error(err)
+		}
+		if d.err != "" && err == nil {
+			t.Errorf("Missing expected error %s", d.err)
+		}
+		if err != nil && !strings.Contains(err.Error(), d.err) {
+			t.Error(err)
+		}
+	}
+}
+
+func TestDuplicateMetricEntry(t *testing.T) {
+	metric_name := "windows_sometest"
+	metric_help := "This is a Test."
+	metric_type := dto.MetricType_GAUGE
+
+	gauge_value := 1.0
+
+	gauge := dto.Gauge{
+		Value: &gauge_value,
+	}
+
+	label1_name := "display_name"
+	label1_value := "foobar"
+
+	label1 := dto.LabelPair{
+		Name:  &label1_name,
+		Value: &label1_value,
+	}
+
+	label2_name := "display_version"
+	label2_value := "13.4.0"
+
+	label2 := dto.LabelPair{
+		Name:  &label2_name,
+		Value: &label2_value,
+	}
+
+	metric1 := dto.Metric{
+		Label: []*dto.LabelPair{&label1, &label2},
+		Gauge: &gauge,
+	}
+
+	metric2 := dto.Metric{
+		Label: []*dto.LabelPair{&label1, &label2},
+		Gauge: &gauge,
+	}
+
+	duplicate := dto.MetricFamily{
+		Name:   &metric_name,
+		Help:   &metric_help,
+		Type:   &metric_type,
+		Metric: []*dto.Metric{&metric1, &metric2},
+	}
+
+	duplicateFamily := []*dto.MetricFamily{}
+	duplicateFamily = append(duplicateFamily, &duplicate)
+
+	// Ensure detection for duplicate metrics
+	if !duplicateMetricEntry(duplicateFamily) {
+		t.Errorf("Duplicate not found in duplicateFamily")
+	}
+
+	label3_name := "test"
+	label3_value := "1.0"
+
+	label3 := dto.LabelPair{
+		Name:  &label3_name,
+		Value: &label3_value,
+	}
+	metric3 := dto.Metric{
+		Label: []*dto.LabelPair{&label1, &label2, &label3},
+		Gauge: &gauge,
+	}
+
+	differentLabels := dto.MetricFamily{
+		Name:   &metric_name,
+		Help:   &metric_help,
+		Type:   &metric_type,
+		Metric: []*dto.Metric{&metric1, &metric3},
+	}
+
+	duplicateFamily = []*dto.MetricFamily{}
+	duplicateFamily = append(duplicateFamily, &differentLabels)
+
+	// Additional label on second metric should not be cause for duplicate detection
+	if duplicateMetricEntry(duplicateFamily) {
+		t.Errorf("Unexpected duplicate found in differentLabels")
+	}
+
+	label4_value := "2.0"
+
+	label4 := dto.LabelPair{
+		Name:  &label3_name,
+		Value: &label4_value,
+	}
+	metric4 := dto.Metric{
+		Label: []*dto.LabelPair{&label1, &label2, &label4},
+		Gauge: &gauge,
+	}
+
+	differentValues := dto.MetricFamily{
+		Name:   &metric_name,
+		Help:   &metric_help,
+		Type:   &metric_type,
+		Metric: []*dto.Metric{&metric3, &metric4},
+	}
+	duplicateFamily = []*dto.MetricFamily{}
+	duplicateFamily = append(duplicateFamily, &differentValues)
+
+	// Additional label with different values metric should not be cause for duplicate detection
+	if duplicateMetricEntry(duplicateFamily) {
+		t.Errorf("Unexpected duplicate found in differentValues")
+	}
+}
+
+func TestParseOpenMetrics(t *testing.T) {
+	input := `# HELP jobs Jobs \"run\".
+# TYPE jobs counter
+# UNIT jobs jobs
+jobs_total{job="a # {b}"} 3 1600000000.5 # {trace_id="1"} 1
+jobs_created{job="a # {b}"} 1500000000
+# TYPE build info
+build_info{version="1.0"} 1
+# TYPE temperature unknown
+temperature 21.5
+# EOF
+`
+	families, err := parseOpenMetrics(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, ok := families["jobs_total"]
+	if !ok || jobs.GetType() != dto.MetricType_COUNTER || jobs.GetHelp() != `Jobs "run".` {
+		t.Fatalf("unexpected jobs_total family %v", jobs)
+	}
+	if len(jobs.Metric) != 1 || jobs.Metric[0].GetCounter().GetValue() != 3 || jobs.Metric[0].GetTimestampMs() != 1600000000500 {
+		t.Errorf("unexpected jobs_total metrics %v", jobs.Metric)
+	}
+	if l := jobs.Metric[0].GetLabel(); len(l) != 1 || l[0].GetValue() != "a # {b}" {
+		t.Errorf("unexpected jobs_total labels %v", l)
+	}
+	if _, ok := families["jobs_created"]; ok {
+		t.Error("expected _created samples to be dropped")
+	}
+	if build, ok := families["build_info"]; !ok || build.GetType() != dto.MetricType_GAUGE {
+		t.Errorf("unexpected build_info family %v", build)
+	}
+	if temperature, ok := families["temperature"]; !ok || temperature.GetType() != dto.MetricType_UNTYPED {
+		t.Errorf("unexpected temperature family %v", temperature)
+	}
+
+	for name, input := range map[string]string{
+		"missing EOF":         "# TYPE a gauge\na 1\n",
+		"content after EOF":   "a 1\n# EOF\na 2\n",
+		"gaugehistogram":      "# TYPE a gaugehistogram\na_gcount 1\n# EOF\n",
+		"unterminated labels": "a{b=\"c\" 1\n# EOF\n",
+	} {
+		if _, err := parseOpenMetrics(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParseJSONMetrics(t *testing.T) {
+	input := `[
+  {"name": "job_success", "help": "Whether the job succeeded", "type": "gauge", "labels": {"job": "a"}, "value": 1},
+  {"name": "job_success", "type": "gauge", "labels": {"job": "b"}, "value": 0},
+  {"name": "job_runs_total", "type": "counter", "value": 12}
+]`
+	families, err := parseJSONMetrics(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	success := families["job_success"]
+	if success.GetType() != dto.MetricType_GAUGE || success.GetHelp() != "Whether the job succeeded" || len(success.Metric) != 2 {
+		t.Fatalf("unexpected job_success family %v", success)
+	}
+	if l := success.Metric[1].GetLabel(); len(l) != 1 || l[0].GetName() != "job" || l[0].GetValue() != "b" {
+		t.Errorf("unexpected job_success labels %v", l)
+	}
+	if runs := families["job_runs_total"]; runs.GetType() != dto.MetricType_COUNTER || runs.Metric[0].GetCounter().GetValue() != 12 {
+		t.Errorf("unexpected job_runs_total family %v", runs)
+	}
+
+	// A single metric, as written by PowerShell for single element arrays.
+	families, err = parseJSONMetrics(strings.NewReader(`{"name": "up", "value": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up := families["up"]; up.GetType() != dto.MetricType_UNTYPED || up.Metric[0].GetUntyped().GetValue() != 1 {
+		t.Errorf("unexpected up family %v", up)
+	}
+
+	for name, input := range map[string]string{
+		"invalid name":     `[{"name": "job-success", "value": 1}]`,
+		"invalid label":    `[{"name": "up", "labels": {"a-b": "c"}, "value": 1}]`,
+		"unsupported type": `[{"name": "up", "type": "histogram", "value": 1}]`,
+		"missing value":    `[{"name": "up"}]`,
+		"type conflict":    `[{"name": "up", "type": "gauge", "value": 1}, {"name": "up", "type": "counter", "value": 1}]`,
+		"invalid json":     `[{"name": "up", "value": 1}`,
+	} {
+		if _, err := parseJSONMetrics(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}